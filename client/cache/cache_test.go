@@ -16,6 +16,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"math"
 	"os"
 	"strings"
@@ -220,3 +221,18 @@ func testDecrOverFlow(t *testing.T, c Cache, timeout time.Duration) {
 		return
 	}
 }
+
+type unreachableCache struct {
+	Cache
+}
+
+func (c *unreachableCache) IsExist(context.Context, string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestHealthChecker(t *testing.T) {
+	bm, err := NewCache("memory", `{"interval":20}`)
+	assert.Nil(t, err)
+	assert.Nil(t, NewHealthChecker(bm).Check())
+	assert.EqualError(t, NewHealthChecker(&unreachableCache{Cache: bm}).Check(), "connection refused")
+}
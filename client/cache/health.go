@@ -0,0 +1,45 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import "context"
+
+// healthCheckKey is looked up by HealthChecker, it doesn't need to exist
+const healthCheckKey = "beego:healthcheck"
+
+// HealthChecker checks a cache by looking a key up, it fails when a remote cache is unreachable.
+// It works with the admin probes:
+//
+//	bm, _ := cache.NewCache("redis", `{"conn":"127.0.0.1:6379"}`)
+//	admin.AddProbe(admin.Readiness, "redis", cache.NewHealthChecker(bm), admin.WithCritical(false))
+type HealthChecker struct {
+	cache Cache
+}
+
+// NewHealthChecker returns the HealthChecker of the cache
+func NewHealthChecker(c Cache) *HealthChecker {
+	return &HealthChecker{cache: c}
+}
+
+// Check looks the key up
+func (h *HealthChecker) Check() error {
+	return h.CheckContext(context.Background())
+}
+
+// CheckContext looks the key up with ctx
+func (h *HealthChecker) CheckContext(ctx context.Context) error {
+	_, err := h.cache.IsExist(ctx, healthCheckKey)
+	return err
+}
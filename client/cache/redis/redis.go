@@ -151,6 +151,30 @@ func (rc *Cache) Incr(ctx context.Context, key string) error {
 	return err
 }
 
+// incrByScript increases a counter and sets its expiration if it has none, eg. when it's created
+var incrByScript = redis.NewScript(1, `
+local v = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return v`)
+
+// IncrBy increases a key's counter by n and returns its value.
+// The counter expires after timeout if it has no expiration yet, atomically,
+// so the counters created by concurrent increments always expire.
+func (rc *Cache) IncrBy(ctx context.Context, key string, n int64, timeout time.Duration) (int64, error) {
+	c := rc.p.Get()
+	defer func() {
+		_ = c.Close()
+	}()
+	v, err := redis.Int64(incrByScript.Do(c, rc.associate(key), n, timeout.Milliseconds()))
+	if err != nil {
+		return 0, berror.Wrapf(err, cache.RedisCacheCurdFailed,
+			"could not execute this command: INCRBY %s", key)
+	}
+	return v, nil
+}
+
 // Decr decreases a key's counter in redis.
 func (rc *Cache) Decr(ctx context.Context, key string) error {
 	_, err := redis.Bool(rc.do("INCRBY", key, -1))
@@ -231,6 +255,14 @@ func (rc *Cache) StartAndGC(config string) error {
 	return nil
 }
 
+// Close closes the connection pool, eg. when the application shuts down
+func (rc *Cache) Close() error {
+	if rc.p == nil {
+		return nil
+	}
+	return rc.p.Close()
+}
+
 func (rc *Cache) parseConf(config string) error {
 	var cf redisConfig
 	err := json.Unmarshal([]byte(config), &cf)
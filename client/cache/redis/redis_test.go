@@ -100,6 +100,18 @@ func TestRedisCache(t *testing.T) {
 	vs, _ = redis.String(vv[1], nil)
 	assert.Equal(t, "author1", vs)
 
+	// the counters created by IncrBy expire
+	rc := bm.(*Cache)
+	n, err := rc.IncrBy(context.Background(), "counter", 2, timeoutDuration)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	n, err = rc.IncrBy(context.Background(), "counter", 3, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), n)
+	ttl, err := redis.Int64(rc.do("TTL", "counter"))
+	assert.Nil(t, err)
+	assert.True(t, ttl > 0 && ttl <= 3)
+
 	// test clear all
 	assert.Nil(t, bm.ClearAll(context.Background()))
 }
@@ -108,6 +108,33 @@ func (rc *Cache) Incr(ctx context.Context, key string) error {
 	return berror.Wrapf(err, cache.SsdbCacheCurdFailed, "increase failed: %s", key)
 }
 
+// IncrBy increments a key's counter by n and returns its value.
+// The counter expires after timeout if it has no expiration yet, eg. when it's created.
+// SSDB has no script, so the expiration is set by another command.
+func (rc *Cache) IncrBy(ctx context.Context, key string, n int64, timeout time.Duration) (int64, error) {
+	resp, err := rc.conn.Do("incr", key, n)
+	if err != nil {
+		return 0, berror.Wrapf(err, cache.SsdbCacheCurdFailed, "increase failed: %s", key)
+	}
+	if len(resp) != 2 || resp[0] != "ok" {
+		return 0, berror.Errorf(cache.SsdbBadResponse, "the response from SSDB server is invalid: %v", resp)
+	}
+	v, err := strconv.ParseInt(resp[1], 10, 64)
+	if err != nil {
+		return 0, berror.Wrapf(err, cache.SsdbBadResponse, "the counter %s is not an integer", key)
+	}
+	resp, err = rc.conn.Do("ttl", key)
+	if err != nil {
+		return 0, berror.Wrapf(err, cache.SsdbCacheCurdFailed, "ttl failed: %s", key)
+	}
+	if len(resp) == 2 && resp[1] == "-1" {
+		if _, err = rc.conn.Do("expire", key, int64(timeout/time.Second)); err != nil {
+			return 0, berror.Wrapf(err, cache.SsdbCacheCurdFailed, "expire failed: %s", key)
+		}
+	}
+	return v, nil
+}
+
 // Decr decrements a key's counter.
 func (rc *Cache) Decr(ctx context.Context, key string) error {
 	_, err := rc.conn.Do("incr", key, -1)
@@ -177,6 +204,14 @@ func (rc *Cache) StartAndGC(config string) error {
 	return rc.connectInit()
 }
 
+// Close closes the connection, eg. when the application shuts down
+func (rc *Cache) Close() error {
+	if rc.conn == nil {
+		return nil
+	}
+	return rc.conn.Close()
+}
+
 // connect to memcache and keep the connection.
 func (rc *Cache) connectInit() error {
 	conninfoArray := strings.Split(rc.conninfo[0], ":")
@@ -28,6 +28,8 @@ import (
 type FilterChainBuilder struct {
 	printableContentTypes []string                              // only print the body of included mime types of request and response
 	log                   func(f interface{}, v ...interface{}) // custom log function
+	// log function receiving the context of the request, it's used when log is nil
+	logContext func(ctx context.Context, f interface{}, v ...interface{})
 }
 
 // BuilderOption option constructor
@@ -49,7 +51,7 @@ var defaultprintableContentTypes = []string{
 func NewFilterChainBuilder(opts ...BuilderOption) *FilterChainBuilder {
 	res := &FilterChainBuilder{
 		printableContentTypes: defaultprintableContentTypes,
-		log:                   logs.Debug,
+		logContext:            logs.DebugContext,
 	}
 	for _, o := range opts {
 		o(res)
@@ -65,6 +67,15 @@ func WithLog(f func(f interface{}, v ...interface{})) BuilderOption {
 	}
 }
 
+// WithContextLog return option constructor modify log function, f receives the context of the request
+// so that it can log its request id, see logs.WithRequestID. The default function is logs.DebugContext
+func WithContextLog(f func(ctx context.Context, f interface{}, v ...interface{})) BuilderOption {
+	return func(h *FilterChainBuilder) {
+		h.log = nil
+		h.logContext = f
+	}
+}
+
 // WithprintableContentTypes return option constructor modify printableContentTypes
 func WithprintableContentTypes(types []string) BuilderOption {
 	return func(h *FilterChainBuilder) {
@@ -76,7 +87,7 @@ func WithprintableContentTypes(types []string) BuilderOption {
 func (builder *FilterChainBuilder) FilterChain(next httplib.Filter) httplib.Filter {
 	return func(ctx context.Context, req *httplib.BeegoHTTPRequest) (*http.Response, error) {
 		info := &logInfo{}
-		defer info.print(builder.log)
+		defer info.print(builder.logger(ctx))
 		resp, err := next(ctx, req)
 		info.err = err
 		contentType := req.GetRequest().Header.Get("Content-Type")
@@ -99,6 +110,16 @@ func (builder *FilterChainBuilder) FilterChain(next httplib.Filter) httplib.Filt
 	}
 }
 
+// logger returns the log function, the messages include the request id of ctx if it's logged with the context
+func (builder *FilterChainBuilder) logger(ctx context.Context) func(f interface{}, v ...interface{}) {
+	if builder.log != nil {
+		return builder.log
+	}
+	return func(f interface{}, v ...interface{}) {
+		builder.logContext(ctx, f, v...)
+	}
+}
+
 func (builder *FilterChainBuilder) shouldPrintBody(contentType string, body io.ReadCloser) bool {
 	if contains(builder.printableContentTypes, contentType) {
 		return true
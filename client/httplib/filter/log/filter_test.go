@@ -23,6 +23,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/asish-tom/beego/v2/client/httplib"
+	"github.com/asish-tom/beego/v2/core/logs"
 )
 
 func TestFilterChain(t *testing.T) {
@@ -40,6 +41,31 @@ func TestFilterChain(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestFilterChainContextLog(t *testing.T) {
+	next := func(ctx context.Context, req *httplib.BeegoHTTPRequest) (*http.Response, error) {
+		return &http.Response{StatusCode: 200}, nil
+	}
+	var ids []string
+	builder := NewFilterChainBuilder(WithContextLog(func(ctx context.Context, f interface{}, v ...interface{}) {
+		ids = append(ids, logs.RequestID(ctx))
+	}))
+	ctx := logs.WithRequestID(context.Background(), "req-1")
+	_, err := builder.FilterChain(next)(ctx, httplib.Get("http://localhost/"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, ids)
+	for _, id := range ids {
+		assert.Equal(t, "req-1", id)
+	}
+
+	// the functions without context win
+	var n int
+	builder = NewFilterChainBuilder(WithLog(func(f interface{}, v ...interface{}) {
+		n++
+	}))
+	_, _ = builder.FilterChain(next)(ctx, httplib.Get("http://localhost/"))
+	assert.Equal(t, 4, n)
+}
+
 func TestContains(t *testing.T) {
 	jsonType := "application/json"
 	cases := []struct {
@@ -56,6 +56,10 @@ import (
 
 const contentTypeKey = "Content-Type"
 
+// RequestIDHeader is the header carrying the request id of the context to the outbound requests,
+// see logs.WithRequestID
+const RequestIDHeader = "X-Request-ID"
+
 // it will be the last filter and execute request.Do
 var doRequestFilter = func(ctx context.Context, req *BeegoHTTPRequest) (*http.Response, error) {
 	return req.doRequest(ctx)
@@ -463,7 +467,11 @@ func (b *BeegoHTTPRequest) DoRequestWithCtx(ctx context.Context) (resp *http.Res
 	return root(ctx, b)
 }
 
-func (b *BeegoHTTPRequest) doRequest(_ context.Context) (*http.Response, error) {
+func (b *BeegoHTTPRequest) doRequest(ctx context.Context) (*http.Response, error) {
+	// the deadline of ctx, eg. the one of the incoming request, bounds the request and its retries
+	if ctx != nil && ctx != b.req.Context() {
+		b.req = b.req.WithContext(ctx)
+	}
 	paramBody := b.buildParamBody()
 
 	b.buildURL(paramBody)
@@ -487,6 +495,11 @@ func (b *BeegoHTTPRequest) doRequest(_ context.Context) (*http.Response, error)
 		b.req.Header.Set("User-Agent", b.setting.UserAgent)
 	}
 
+	// propagate the id of the incoming request, see logs.WithRequestID
+	if requestID := logs.RequestID(b.req.Context()); requestID != "" && b.req.Header.Get(RequestIDHeader) == "" {
+		b.req.Header.Set(RequestIDHeader, requestID)
+	}
+
 	if b.setting.CheckRedirect != nil {
 		client.CheckRedirect = b.setting.CheckRedirect
 	}
@@ -504,6 +517,9 @@ func (b *BeegoHTTPRequest) sendRequest(client *http.Client) (resp *http.Response
 		if err == nil {
 			return
 		}
+		if b.req.Context().Err() != nil {
+			break
+		}
 		time.Sleep(b.setting.RetryDelay)
 		b.req.Body = b.copyBody()
 	}
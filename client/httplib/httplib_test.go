@@ -23,6 +23,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -32,6 +33,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/core/logs"
 )
 
 type HttplibTestSuite struct {
@@ -423,6 +426,50 @@ func TestNewBeegoRequestWithCtx(t *testing.T) {
 	assert.NotNil(t, req.copyBody)
 }
 
+func TestBeegoHTTPRequestDoRequestWithCtx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req := Get(server.URL).Retries(3).RetryDelay(time.Second)
+	start := time.Now()
+	_, err := req.DoRequestWithCtx(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	// no retry after the deadline
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestBeegoHTTPRequestRequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(RequestIDHeader)))
+	}))
+	defer server.Close()
+
+	ctx := logs.WithRequestID(context.Background(), "req-1")
+	body, err := NewBeegoRequestWithCtx(ctx, server.URL, http.MethodGet).String()
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", body)
+
+	// the context of DoRequestWithCtx
+	resp, err := Get(server.URL).DoRequestWithCtx(ctx)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, "req-1", string(data))
+
+	// the header set by the caller is kept
+	body, err = NewBeegoRequestWithCtx(ctx, server.URL, http.MethodGet).Header(RequestIDHeader, "other").String()
+	require.NoError(t, err)
+	assert.Equal(t, "other", body)
+
+	body, err = Get(server.URL).String()
+	require.NoError(t, err)
+	assert.Equal(t, "", body)
+}
+
 func TestBeegoHTTPRequestSetProtocolVersion(t *testing.T) {
 	req := NewBeegoRequest("http://beego.vip", "GET")
 	assert.Equal(t, 1, req.req.ProtoMajor)
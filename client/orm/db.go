@@ -298,7 +298,7 @@ func (d *dbBase) InsertStmt(ctx context.Context, stmt stmtQuerier, mi *models.Mo
 	}
 
 	if d.ins.HasReturningID(mi, nil) {
-		row := stmt.QueryRow(values...)
+		row := stmt.QueryRowContext(ctx, values...)
 		var id int64
 		err := row.Scan(&id)
 		return id, err
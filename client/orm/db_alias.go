@@ -17,6 +17,7 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -78,6 +79,8 @@ var (
 type _dbCache struct {
 	mux   sync.RWMutex
 	cache map[string]*alias
+	// names of the aliases in registered order
+	names []string
 }
 
 // add database alias with original name.
@@ -86,6 +89,7 @@ func (ac *_dbCache) add(name string, al *alias) (added bool) {
 	defer ac.mux.Unlock()
 	if _, ok := ac.cache[name]; !ok {
 		ac.cache[name] = al
+		ac.names = append(ac.names, name)
 		added = true
 	}
 	return
@@ -101,20 +105,41 @@ func (ac *_dbCache) get(name string) (al *alias, ok bool) {
 
 // get database alias if cached.
 func (ac *_dbCache) remove(name string) (al *alias, ok bool) {
-	ac.mux.RLock()
-	defer ac.mux.RUnlock()
+	ac.mux.Lock()
+	defer ac.mux.Unlock()
 	al, ok = ac.cache[name]
 	if ok {
 		al.DB.DB.Close()
 		fmt.Printf("Closed DB%s\n", name)
 
 		delete(ac.cache, name)
+		for i, n := range ac.names {
+			if n == name {
+				ac.names = append(ac.names[:i], ac.names[i+1:]...)
+				break
+			}
+		}
 		fmt.Printf("Removed DB %s\n", name)
 
 	}
 	return
 }
 
+// closeAll closes the databases of all the aliases in registered order and removes them.
+func (ac *_dbCache) closeAll() error {
+	ac.mux.Lock()
+	defer ac.mux.Unlock()
+	var errs []error
+	for _, name := range ac.names {
+		if err := ac.cache[name].DB.DB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close DataBase `%s`: %w", name, err))
+		}
+		delete(ac.cache, name)
+	}
+	ac.names = nil
+	return errors.Join(errs...)
+}
+
 // get default alias.
 func (ac *_dbCache) getDefault() (al *alias) {
 	al, _ = ac.get("default")
@@ -569,6 +594,17 @@ func RemoveDB(aliasNames ...string) {
 	dataBaseCache.remove(name)
 }
 
+// CloseDataBases closes the databases of all the registered aliases in registered order,
+// the aliases have to be registered again before being used.
+func CloseDataBases() error {
+	return dataBaseCache.closeAll()
+}
+
+// Shutdown closes the databases, it's the shutdown hook of the ORM: web.AddShutdownHook("orm", orm.Shutdown)
+func Shutdown(context.Context) error {
+	return CloseDataBases()
+}
+
 type stmtDecorator struct {
 	wg   sync.WaitGroup
 	stmt *sql.Stmt
@@ -15,10 +15,12 @@
 package orm
 
 import (
+	"database/sql"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRegisterDataBase(t *testing.T) {
@@ -86,3 +88,30 @@ func TestDBCache(t *testing.T) {
 	assert.NotNil(t, al)
 	assert.True(t, ok)
 }
+
+func TestDBCacheCloseAll(t *testing.T) {
+	ac := &_dbCache{cache: make(map[string]*alias)}
+	dbs := make([]*sql.DB, 0, 3)
+	for _, name := range []string{"b", "a", "c"} {
+		db, err := sql.Open("sqlite3", ":memory:")
+		require.NoError(t, err)
+		dbs = append(dbs, db)
+		ac.add(name, &alias{Name: name, DB: &DB{DB: db}})
+	}
+	assert.Equal(t, []string{"b", "a", "c"}, ac.names)
+
+	ac.remove("a")
+	assert.Equal(t, []string{"b", "c"}, ac.names)
+
+	require.NoError(t, ac.closeAll())
+	assert.Empty(t, ac.names)
+	assert.Empty(t, ac.cache)
+	for _, db := range dbs {
+		assert.Error(t, db.Ping())
+	}
+}
+
+func TestHealthChecker(t *testing.T) {
+	assert.NoError(t, NewHealthChecker("").Check())
+	assert.Error(t, NewHealthChecker("not-registered").Check())
+}
@@ -0,0 +1,47 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import "context"
+
+// HealthChecker checks the database of an alias with DB.PingContext,
+// it works with the admin probes:
+//
+//	admin.AddProbe(admin.Readiness, "database", orm.NewHealthChecker("default"), admin.WithTimeout(time.Second))
+type HealthChecker struct {
+	aliasName string
+}
+
+// NewHealthChecker returns the HealthChecker of the alias, "default" if aliasName is empty
+func NewHealthChecker(aliasName string) *HealthChecker {
+	if aliasName == "" {
+		aliasName = "default"
+	}
+	return &HealthChecker{aliasName: aliasName}
+}
+
+// Check pings the database
+func (h *HealthChecker) Check() error {
+	return h.CheckContext(context.Background())
+}
+
+// CheckContext pings the database until ctx is done
+func (h *HealthChecker) CheckContext(ctx context.Context) error {
+	db, err := GetDB(h.aliasName)
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
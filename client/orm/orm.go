@@ -590,7 +590,7 @@ func (o *orm) BeginWithCtxAndOpts(ctx context.Context, opts *sql.TxOptions) (TxO
 	}
 
 	if Debug {
-		_txOrm.db = newDbQueryLog(o.alias, _txOrm.db)
+		_txOrm.db = newTxQueryLog(ctx, o.alias, _txOrm.db)
 	}
 
 	var taskTxOrm TxOrmer = _txOrm
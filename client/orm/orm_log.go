@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/asish-tom/beego/v2/client/orm/internal/logs"
+	beelogs "github.com/asish-tom/beego/v2/core/logs"
 )
 
 type Log = logs.Log
@@ -35,7 +36,8 @@ func NewLog(out io.Writer) *logs.Log {
 // LogFunc costomer log func
 var LogFunc func(query map[string]interface{})
 
-func debugLogQueies(alias *alias, operation, query string, t time.Time, err error, args ...interface{}) {
+// debugLogQueies logs a query, with the request id of ctx if there's one, see logs.WithRequestID
+func debugLogQueies(ctx context.Context, alias *alias, operation, query string, t time.Time, err error, args ...interface{}) {
 	logMap := make(map[string]interface{})
 	sub := time.Since(t) / 1e5
 	elsp := float64(int(sub)) / 10.0
@@ -56,7 +58,12 @@ func debugLogQueies(alias *alias, operation, query string, t time.Time, err erro
 		actualQuery = query
 	}
 
-	con := fmt.Sprintf(" -[Queries/%s] - [  %s / %11s / %7.1fms] - [%s]", alias.Name, flag, operation, elsp, actualQuery)
+	con := fmt.Sprintf(" -[Queries/%s]", alias.Name)
+	if requestID := beelogs.RequestID(ctx); requestID != "" {
+		con += fmt.Sprintf(" - [%s]", requestID)
+		logMap["request_id"] = requestID
+	}
+	con += fmt.Sprintf(" - [  %s / %11s / %7.1fms] - [%s]", flag, operation, elsp, actualQuery)
 	if comments != "" {
 		con = fmt.Sprintf("%s\n    Comments: %s", con, comments)
 		logMap["sql_comments"] = comments
@@ -96,7 +103,7 @@ var _ stmtQuerier = new(stmtQueryLog)
 func (d *stmtQueryLog) Close() error {
 	a := time.Now()
 	err := d.stmt.Close()
-	debugLogQueies(d.alias, "st.Close", d.query, a, err)
+	debugLogQueies(context.Background(), d.alias, "st.Close", d.query, a, err)
 	return err
 }
 
@@ -107,7 +114,7 @@ func (d *stmtQueryLog) Exec(args ...interface{}) (sql.Result, error) {
 func (d *stmtQueryLog) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
 	a := time.Now()
 	res, err := d.stmt.ExecContext(ctx, args...)
-	debugLogQueies(d.alias, "st.Exec", d.query, a, err, args...)
+	debugLogQueies(ctx, d.alias, "st.Exec", d.query, a, err, args...)
 	return res, err
 }
 
@@ -118,7 +125,7 @@ func (d *stmtQueryLog) Query(args ...interface{}) (*sql.Rows, error) {
 func (d *stmtQueryLog) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
 	a := time.Now()
 	res, err := d.stmt.QueryContext(ctx, args...)
-	debugLogQueies(d.alias, "st.Query", d.query, a, err, args...)
+	debugLogQueies(ctx, d.alias, "st.Query", d.query, a, err, args...)
 	return res, err
 }
 
@@ -129,7 +136,7 @@ func (d *stmtQueryLog) QueryRow(args ...interface{}) *sql.Row {
 func (d *stmtQueryLog) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
 	a := time.Now()
 	res := d.stmt.QueryRow(args...)
-	debugLogQueies(d.alias, "st.QueryRow", d.query, a, nil, args...)
+	debugLogQueies(ctx, d.alias, "st.QueryRow", d.query, a, nil, args...)
 	return res
 }
 
@@ -148,6 +155,8 @@ type dbQueryLog struct {
 	db    dbQuerier
 	tx    txer
 	txe   txEnder
+	// the context of the transaction, its request id is logged with the commit and the rollback
+	ctx context.Context
 }
 
 var (
@@ -163,7 +172,7 @@ func (d *dbQueryLog) Prepare(query string) (*sql.Stmt, error) {
 func (d *dbQueryLog) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
 	a := time.Now()
 	stmt, err := d.db.PrepareContext(ctx, query)
-	debugLogQueies(d.alias, "db.Prepare", query, a, err)
+	debugLogQueies(ctx, d.alias, "db.Prepare", query, a, err)
 	return stmt, err
 }
 
@@ -174,7 +183,7 @@ func (d *dbQueryLog) Exec(query string, args ...interface{}) (sql.Result, error)
 func (d *dbQueryLog) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	a := time.Now()
 	res, err := d.db.ExecContext(ctx, query, args...)
-	debugLogQueies(d.alias, "db.Exec", query, a, err, args...)
+	debugLogQueies(ctx, d.alias, "db.Exec", query, a, err, args...)
 	return res, err
 }
 
@@ -185,7 +194,7 @@ func (d *dbQueryLog) Query(query string, args ...interface{}) (*sql.Rows, error)
 func (d *dbQueryLog) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	a := time.Now()
 	res, err := d.db.QueryContext(ctx, query, args...)
-	debugLogQueies(d.alias, "db.Query", query, a, err, args...)
+	debugLogQueies(ctx, d.alias, "db.Query", query, a, err, args...)
 	return res, err
 }
 
@@ -196,7 +205,7 @@ func (d *dbQueryLog) QueryRow(query string, args ...interface{}) *sql.Row {
 func (d *dbQueryLog) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	a := time.Now()
 	res := d.db.QueryRowContext(ctx, query, args...)
-	debugLogQueies(d.alias, "db.QueryRow", query, a, nil, args...)
+	debugLogQueies(ctx, d.alias, "db.QueryRow", query, a, nil, args...)
 	return res
 }
 
@@ -212,28 +221,28 @@ func (d *dbQueryLog) Begin() (*sql.Tx, error) {
 func (d *dbQueryLog) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
 	a := time.Now()
 	tx, err := d.db.(txer).BeginTx(ctx, opts)
-	debugLogQueies(d.alias, "db.BeginTx", "START TRANSACTION", a, err)
+	debugLogQueies(ctx, d.alias, "db.BeginTx", "START TRANSACTION", a, err)
 	return tx, err
 }
 
 func (d *dbQueryLog) Commit() error {
 	a := time.Now()
 	err := d.db.(txEnder).Commit()
-	debugLogQueies(d.alias, "tx.Commit", "COMMIT", a, err)
+	debugLogQueies(d.ctx, d.alias, "tx.Commit", "COMMIT", a, err)
 	return err
 }
 
 func (d *dbQueryLog) Rollback() error {
 	a := time.Now()
 	err := d.db.(txEnder).Rollback()
-	debugLogQueies(d.alias, "tx.Rollback", "ROLLBACK", a, err)
+	debugLogQueies(d.ctx, d.alias, "tx.Rollback", "ROLLBACK", a, err)
 	return err
 }
 
 func (d *dbQueryLog) RollbackUnlessCommit() error {
 	a := time.Now()
 	err := d.db.(txEnder).RollbackUnlessCommit()
-	debugLogQueies(d.alias, "tx.RollbackUnlessCommit", "ROLLBACK UNLESS COMMIT", a, err)
+	debugLogQueies(d.ctx, d.alias, "tx.RollbackUnlessCommit", "ROLLBACK UNLESS COMMIT", a, err)
 	return err
 }
 
@@ -245,5 +254,12 @@ func newDbQueryLog(alias *alias, db dbQuerier) dbQuerier {
 	d := new(dbQueryLog)
 	d.alias = alias
 	d.db = db
+	d.ctx = context.Background()
+	return d
+}
+
+func newTxQueryLog(ctx context.Context, alias *alias, db dbQuerier) dbQuerier {
+	d := newDbQueryLog(alias, db).(*dbQueryLog)
+	d.ctx = ctx
 	return d
 }
@@ -0,0 +1,50 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asish-tom/beego/v2/core/logs"
+)
+
+func TestDebugLogQueriesRequestID(t *testing.T) {
+	old, oldFunc := DebugLog, LogFunc
+	defer func() {
+		DebugLog, LogFunc = old, oldFunc
+	}()
+	buf := &bytes.Buffer{}
+	DebugLog = NewLog(buf)
+	var logMap map[string]interface{}
+	LogFunc = func(query map[string]interface{}) {
+		logMap = query
+	}
+
+	al := &alias{Name: "default"}
+	ctx := logs.WithRequestID(context.Background(), "req-1")
+	debugLogQueies(ctx, al, "db.Query", "SELECT 1", time.Now(), nil)
+	assert.Contains(t, buf.String(), "-[Queries/default] - [req-1] - [  OK /    db.Query /")
+	assert.Equal(t, "req-1", logMap["request_id"])
+
+	buf.Reset()
+	debugLogQueies(context.Background(), al, "db.Query", "SELECT 1", time.Now(), nil)
+	assert.Contains(t, buf.String(), "-[Queries/default] - [  OK /")
+	assert.NotContains(t, logMap, "request_id")
+}
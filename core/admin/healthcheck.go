@@ -28,6 +28,8 @@
 // AddHealthCheck("database",&DatabaseCheck{})
 package admin
 
+import "sync/atomic"
+
 // AdminCheckList holds health checker map
 var AdminCheckList map[string]HealthChecker
 
@@ -36,11 +38,26 @@ type HealthChecker interface {
 	Check() error
 }
 
-// AddHealthCheck add health checker with name string
+// AddHealthCheck add health checker with name string,
+// the checks are shown by the healthcheck page, see AddProbe for the liveness, readiness and startup probes
 func AddHealthCheck(name string, hc HealthChecker) {
 	AdminCheckList[name] = hc
 }
 
+// notReady is set while the application stops accepting requests, eg. when it shuts down
+var notReady atomic.Bool
+
+// SetReady marks whether the application accepts requests,
+// the healthcheck reports not ready when it doesn't so that the load balancers stop sending requests
+func SetReady(ready bool) {
+	notReady.Store(!ready)
+}
+
+// IsReady returns whether the application accepts requests
+func IsReady() bool {
+	return !notReady.Load()
+}
+
 func init() {
 	AdminCheckList = make(map[string]HealthChecker)
 }
@@ -0,0 +1,270 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProbeType is the type of a probe, they are the same as the Kubernetes probes
+type ProbeType string
+
+const (
+	// Liveness probe fails when the application has to be restarted
+	Liveness ProbeType = "liveness"
+	// Readiness probe fails when the application can't serve requests, eg. its database is down or it's shutting down
+	Readiness ProbeType = "readiness"
+	// Startup probe fails until the application is started
+	Startup ProbeType = "startup"
+)
+
+// ProbeStatus is the status of a probe or of a check
+type ProbeStatus string
+
+const (
+	// StatusUp means the probe or the check succeeded
+	StatusUp ProbeStatus = "up"
+	// StatusDown means the probe or the check failed
+	StatusDown ProbeStatus = "down"
+)
+
+// DefaultProbeTimeout is the timeout of the checks added without WithTimeout
+var DefaultProbeTimeout = 5 * time.Second
+
+// ContextHealthChecker is a HealthChecker which gives up when the context is done,
+// the probes pass the timeout of the check through the context.
+// The HealthCheckers which don't implement it keep running in background after the timeout,
+// and the next probes wait for them instead of starting another check
+type ContextHealthChecker interface {
+	CheckContext(ctx context.Context) error
+}
+
+// ProbeOption configures a check added by AddProbe
+type ProbeOption func(c *probeCheck)
+
+// WithTimeout sets how long the probe waits for the check, DefaultProbeTimeout by default
+func WithTimeout(timeout time.Duration) ProbeOption {
+	return func(c *probeCheck) {
+		c.timeout = timeout
+	}
+}
+
+// WithCacheTTL caches the result of the check during ttl, the expensive checks are run at most once per ttl
+func WithCacheTTL(ttl time.Duration) ProbeOption {
+	return func(c *probeCheck) {
+		c.ttl = ttl
+	}
+}
+
+// WithCritical sets whether the probe fails when the check fails, true by default.
+// The failures of the non-critical checks are reported only
+func WithCritical(critical bool) ProbeOption {
+	return func(c *probeCheck) {
+		c.critical = critical
+	}
+}
+
+// CheckResult is the result of a check
+type CheckResult struct {
+	Name     string      `json:"name"`
+	Status   ProbeStatus `json:"status"`
+	Critical bool        `json:"critical"`
+	Error    string      `json:"error,omitempty"`
+	// Duration is the time the check took in nanoseconds
+	Duration time.Duration `json:"duration"`
+	// Cached means the check didn't run, the result of a previous run is returned, see WithCacheTTL
+	Cached bool `json:"cached,omitempty"`
+}
+
+// ProbeResult is the result of a probe, its status is down when any critical check is down
+type ProbeResult struct {
+	Probe  ProbeType     `json:"probe"`
+	Status ProbeStatus   `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// IsUp returns whether the probe succeeded
+func (r *ProbeResult) IsUp() bool {
+	return r.Status == StatusUp
+}
+
+type probeCheck struct {
+	name     string
+	checker  HealthChecker
+	timeout  time.Duration
+	ttl      time.Duration
+	critical bool
+
+	// mu is held while the check runs, so the concurrent probes share the cached result
+	mu      sync.Mutex
+	last    CheckResult
+	expires time.Time
+	// running receives the result of the Check which was still running after the timeout
+	running chan error
+}
+
+var (
+	probesMu sync.RWMutex
+	probes   = map[ProbeType]map[string]*probeCheck{}
+)
+
+// AddProbe adds a check named name to the probe, the check replaces the one with the same name.
+// usage:
+//
+//	admin.AddProbe(admin.Readiness, "database", orm.NewHealthChecker("default"), admin.WithTimeout(time.Second))
+//	admin.AddProbe(admin.Readiness, "cache", cache.NewHealthChecker(bm), admin.WithCritical(false))
+func AddProbe(probe ProbeType, name string, hc HealthChecker, opts ...ProbeOption) {
+	c := &probeCheck{
+		name:     name,
+		checker:  hc,
+		timeout:  DefaultProbeTimeout,
+		critical: true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	probesMu.Lock()
+	defer probesMu.Unlock()
+	if probes[probe] == nil {
+		probes[probe] = make(map[string]*probeCheck)
+	}
+	probes[probe][name] = c
+}
+
+// RemoveProbe removes the check named name from the probe
+func RemoveProbe(probe ProbeType, name string) {
+	probesMu.Lock()
+	defer probesMu.Unlock()
+	delete(probes[probe], name)
+}
+
+// RunProbe runs the checks of the probe in parallel and returns their results sorted by name.
+// The readiness probe is down while the application isn't ready, see SetReady
+func RunProbe(ctx context.Context, probe ProbeType) *ProbeResult {
+	probesMu.RLock()
+	checks := make([]*probeCheck, 0, len(probes[probe]))
+	for _, c := range probes[probe] {
+		checks = append(checks, c)
+	}
+	probesMu.RUnlock()
+
+	res := &ProbeResult{
+		Probe:  probe,
+		Status: StatusUp,
+		Checks: make([]CheckResult, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *probeCheck) {
+			defer wg.Done()
+			res.Checks[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	if probe == Readiness && !IsReady() {
+		res.Checks = append(res.Checks, CheckResult{
+			Name:     "shutdown",
+			Status:   StatusDown,
+			Critical: true,
+			Error:    "the application is shutting down",
+		})
+	}
+	sort.Slice(res.Checks, func(i, j int) bool {
+		return res.Checks[i].Name < res.Checks[j].Name
+	})
+	for _, c := range res.Checks {
+		if c.Critical && c.Status == StatusDown {
+			res.Status = StatusDown
+		}
+	}
+	return res
+}
+
+// run returns the cached result or runs the check
+func (c *probeCheck) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.expires) {
+		res := c.last
+		res.Cached = true
+		return res
+	}
+
+	start := time.Now()
+	err := c.check(ctx)
+	res := CheckResult{
+		Name:     c.name,
+		Status:   StatusUp,
+		Critical: c.critical,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	// the probe was canceled by its caller, it says nothing about the check
+	if c.ttl > 0 && ctx.Err() == nil && !errors.Is(err, context.Canceled) {
+		c.last, c.expires = res, start.Add(c.ttl)
+	}
+	return res
+}
+
+// check runs the checker, giving up after the timeout
+func (c *probeCheck) check(ctx context.Context) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	var done chan error
+	if hc, ok := c.checker.(ContextHealthChecker); ok {
+		done = make(chan error, 1)
+		go func() {
+			done <- hc.CheckContext(ctx)
+		}()
+	} else {
+		if c.running != nil {
+			select {
+			case <-c.running:
+				// the check which timed out has finished since, its result is stale
+				c.running = nil
+			default:
+			}
+		}
+		if c.running == nil {
+			c.running = make(chan error, 1)
+			go func(running chan<- error) {
+				running <- c.checker.Check()
+			}(c.running)
+		}
+		done = c.running
+	}
+	select {
+	case err := <-done:
+		c.running = nil
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return errors.New("timeout after " + c.timeout.String())
+		}
+		return ctx.Err()
+	}
+}
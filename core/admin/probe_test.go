@@ -0,0 +1,181 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type funcChecker func() error
+
+func (f funcChecker) Check() error {
+	return f()
+}
+
+type contextChecker struct {
+	canceled chan struct{}
+}
+
+func (c *contextChecker) Check() error {
+	return nil
+}
+
+func (c *contextChecker) CheckContext(ctx context.Context) error {
+	<-ctx.Done()
+	close(c.canceled)
+	return ctx.Err()
+}
+
+func TestRunProbe(t *testing.T) {
+	const probe ProbeType = "test"
+	defer func() {
+		probesMu.Lock()
+		delete(probes, probe)
+		probesMu.Unlock()
+	}()
+
+	var calls int32
+	AddProbe(probe, "cached", funcChecker(func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), WithCacheTTL(time.Minute))
+	AddProbe(probe, "optional", funcChecker(func() error {
+		return errors.New("no cache")
+	}), WithCritical(false))
+	slow := &contextChecker{canceled: make(chan struct{})}
+	AddProbe(probe, "slow", slow, WithTimeout(50*time.Millisecond))
+
+	// the checks run in parallel
+	start := time.Now()
+	res := RunProbe(context.Background(), probe)
+	assert.Less(t, time.Since(start), time.Second)
+	<-slow.canceled
+
+	require.Len(t, res.Checks, 3)
+	assert.Equal(t, StatusDown, res.Status)
+	assert.False(t, res.IsUp())
+	assert.Equal(t, "cached", res.Checks[0].Name)
+	assert.Equal(t, StatusUp, res.Checks[0].Status)
+	assert.Equal(t, CheckResult{Name: "optional", Status: StatusDown, Critical: false, Error: "no cache",
+		Duration: res.Checks[1].Duration}, res.Checks[1])
+	assert.Equal(t, "timeout after 50ms", res.Checks[2].Error)
+
+	// the non-critical checks don't fail the probe
+	RemoveProbe(probe, "slow")
+	res = RunProbe(context.Background(), probe)
+	assert.Equal(t, StatusUp, res.Status)
+	assert.True(t, res.Checks[0].Cached)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRunProbeTimeoutWithoutContext(t *testing.T) {
+	const probe ProbeType = "test-timeout"
+	defer func() {
+		probesMu.Lock()
+		delete(probes, probe)
+		probesMu.Unlock()
+	}()
+
+	release := make(chan struct{})
+	defer close(release)
+	AddProbe(probe, "blocked", funcChecker(func() error {
+		<-release
+		return nil
+	}), WithTimeout(10*time.Millisecond))
+
+	res := RunProbe(context.Background(), probe)
+	assert.Equal(t, StatusDown, res.Status)
+	assert.Equal(t, "timeout after 10ms", res.Checks[0].Error)
+}
+
+func TestRunProbeInFlight(t *testing.T) {
+	const probe ProbeType = "test-in-flight"
+	defer func() {
+		probesMu.Lock()
+		delete(probes, probe)
+		probesMu.Unlock()
+	}()
+
+	var calls int32
+	release := make(chan struct{})
+	AddProbe(probe, "blocked", funcChecker(func() error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+		}
+		return nil
+	}), WithTimeout(10*time.Millisecond))
+
+	// the check still running is waited for instead of starting another one
+	res := RunProbe(context.Background(), probe)
+	assert.Equal(t, "timeout after 10ms", res.Checks[0].Error)
+	res = RunProbe(context.Background(), probe)
+	assert.Equal(t, "timeout after 10ms", res.Checks[0].Error)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	release <- struct{}{}
+	res = RunProbe(context.Background(), probe)
+	assert.Equal(t, StatusUp, res.Status)
+}
+
+func TestRunProbeCanceled(t *testing.T) {
+	const probe ProbeType = "test-canceled"
+	defer func() {
+		probesMu.Lock()
+		delete(probes, probe)
+		probesMu.Unlock()
+	}()
+
+	release := make(chan struct{})
+	AddProbe(probe, "blocked", funcChecker(func() error {
+		<-release
+		return nil
+	}), WithCacheTTL(time.Minute))
+
+	// the result of a canceled probe isn't cached
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := RunProbe(ctx, probe)
+	assert.Equal(t, context.Canceled.Error(), res.Checks[0].Error)
+
+	close(release)
+	res = RunProbe(context.Background(), probe)
+	assert.Equal(t, StatusUp, res.Status)
+	assert.False(t, res.Checks[0].Cached)
+	res = RunProbe(context.Background(), probe)
+	assert.True(t, res.Checks[0].Cached)
+}
+
+func TestRunProbeReadiness(t *testing.T) {
+	res := RunProbe(context.Background(), Readiness)
+	assert.Equal(t, StatusUp, res.Status)
+	assert.Empty(t, res.Checks)
+
+	SetReady(false)
+	defer SetReady(true)
+	res = RunProbe(context.Background(), Readiness)
+	assert.Equal(t, StatusDown, res.Status)
+	require.Len(t, res.Checks, 1)
+	assert.Equal(t, "shutdown", res.Checks[0].Name)
+
+	// the application is still alive
+	assert.Equal(t, StatusUp, RunProbe(context.Background(), Liveness).Status)
+}
@@ -42,6 +42,7 @@ type AccessLogRecord struct {
 	HTTPReferrer   string        `json:"http_referrer"`
 	HTTPUserAgent  string        `json:"http_user_agent"`
 	RemoteUser     string        `json:"remote_user"`
+	RequestID      string        `json:"request_id,omitempty"`
 }
 
 func (r *AccessLogRecord) json() ([]byte, error) {
@@ -69,6 +70,10 @@ func AccessLog(r *AccessLogRecord, format string) {
 		When:  time.Now(),
 		Level: levelLoggerImpl,
 	}
+	if format == apacheFormat {
+		// the JSON record holds it
+		lm.RequestID = r.RequestID
+	}
 	beeLogger.writeMsg(lm)
 }
 
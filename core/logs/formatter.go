@@ -62,7 +62,7 @@ func GetFormatter(name string) (LogFormatter, bool) {
 }
 
 // ToString 'w' when, 'm' msg,'f' filename，'F' full path，'n' line number
-// 'l' level number, 't' prefix of level type, 'T' full name of level type
+// 'l' level number, 't' prefix of level type, 'T' full name of level type, 'r' request id
 func (p *PatternLogFormatter) ToString(lm *LogMsg) string {
 	s := []rune(p.Pattern)
 	msg := fmt.Sprintf(lm.Msg, lm.Args...)
@@ -74,6 +74,7 @@ func (p *PatternLogFormatter) ToString(lm *LogMsg) string {
 		't': levelPrefix[lm.Level],
 		'T': levelNames[lm.Level],
 		'F': lm.FilePath,
+		'r': lm.RequestID,
 	}
 	_, m['f'] = path.Split(lm.FilePath)
 	res := ""
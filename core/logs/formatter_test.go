@@ -113,4 +113,7 @@ func TestPatternLogFormatter(t *testing.T) {
 		got := tes.ToString(tc.msg)
 		assert.Equal(t, tc.want, got)
 	}
+
+	tes.Pattern = "%t %r %m"
+	assert.Equal(t, "[I] req-1 hello", tes.ToString(&LogMsg{Msg: "hello", Level: LevelInfo, RequestID: "req-1"}))
 }
@@ -32,6 +32,7 @@
 package logs
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -324,6 +325,7 @@ func (bl *BeeLogger) writeMsg(lm *LogMsg) error {
 		logM.FilePath = lm.FilePath
 		logM.LineNumber = lm.LineNumber
 		logM.Prefix = lm.Prefix
+		logM.RequestID = lm.RequestID
 
 		if bl.outputs != nil {
 			if bl.logWithNonBlocking {
@@ -589,6 +591,70 @@ func (bl *BeeLogger) Trace(format string, v ...interface{}) {
 	bl.writeMsg(lm)
 }
 
+// ErrorContext logs an ERROR level message with the request id of ctx, see WithRequestID
+func (bl *BeeLogger) ErrorContext(ctx context.Context, format string, v ...interface{}) {
+	if LevelError > bl.level {
+		return
+	}
+	lm := &LogMsg{
+		Level:     LevelError,
+		Msg:       format,
+		When:      time.Now(),
+		Args:      v,
+		RequestID: RequestID(ctx),
+	}
+
+	bl.writeMsg(lm)
+}
+
+// WarnContext logs a WARNING level message with the request id of ctx, see WithRequestID
+func (bl *BeeLogger) WarnContext(ctx context.Context, format string, v ...interface{}) {
+	if LevelWarn > bl.level {
+		return
+	}
+	lm := &LogMsg{
+		Level:     LevelWarn,
+		Msg:       format,
+		When:      time.Now(),
+		Args:      v,
+		RequestID: RequestID(ctx),
+	}
+
+	bl.writeMsg(lm)
+}
+
+// InfoContext logs an INFORMATIONAL level message with the request id of ctx, see WithRequestID
+func (bl *BeeLogger) InfoContext(ctx context.Context, format string, v ...interface{}) {
+	if LevelInfo > bl.level {
+		return
+	}
+	lm := &LogMsg{
+		Level:     LevelInfo,
+		Msg:       format,
+		When:      time.Now(),
+		Args:      v,
+		RequestID: RequestID(ctx),
+	}
+
+	bl.writeMsg(lm)
+}
+
+// DebugContext logs a DEBUG level message with the request id of ctx, see WithRequestID
+func (bl *BeeLogger) DebugContext(ctx context.Context, format string, v ...interface{}) {
+	if LevelDebug > bl.level {
+		return
+	}
+	lm := &LogMsg{
+		Level:     LevelDebug,
+		Msg:       format,
+		When:      time.Now(),
+		Args:      v,
+		RequestID: RequestID(ctx),
+	}
+
+	bl.writeMsg(lm)
+}
+
 // Flush flush all chan data.
 func (bl *BeeLogger) Flush() {
 	if bl.asynchronous {
@@ -787,6 +853,26 @@ func Trace(f interface{}, v ...interface{}) {
 	beeLogger.Trace(formatPattern(f, v...), v...)
 }
 
+// ErrorContext logs a message at error level with the request id of ctx.
+func ErrorContext(ctx context.Context, f interface{}, v ...interface{}) {
+	beeLogger.ErrorContext(ctx, formatPattern(f, v...), v...)
+}
+
+// WarnContext logs a message at warning level with the request id of ctx.
+func WarnContext(ctx context.Context, f interface{}, v ...interface{}) {
+	beeLogger.WarnContext(ctx, formatPattern(f, v...), v...)
+}
+
+// InfoContext logs a message at info level with the request id of ctx.
+func InfoContext(ctx context.Context, f interface{}, v ...interface{}) {
+	beeLogger.InfoContext(ctx, formatPattern(f, v...), v...)
+}
+
+// DebugContext logs a message at debug level with the request id of ctx.
+func DebugContext(ctx context.Context, f interface{}, v ...interface{}) {
+	beeLogger.DebugContext(ctx, formatPattern(f, v...), v...)
+}
+
 func formatPattern(f interface{}, v ...interface{}) string {
 	var msg string
 	switch f.(type) {
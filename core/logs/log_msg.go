@@ -21,13 +21,15 @@ import (
 )
 
 type LogMsg struct {
-	Level               int
-	Msg                 string
-	When                time.Time
-	FilePath            string
-	LineNumber          int
-	Args                []interface{}
-	Prefix              string
+	Level      int
+	Msg        string
+	When       time.Time
+	FilePath   string
+	LineNumber int
+	Args       []interface{}
+	Prefix     string
+	// the id of the request the message is logged for, see WithRequestID
+	RequestID           string
 	enableFullFilePath  bool
 	enableFuncCallDepth bool
 }
@@ -40,6 +42,9 @@ func (lm *LogMsg) OldStyleFormat() string {
 		msg = fmt.Sprintf(lm.Msg, lm.Args...)
 	}
 
+	if lm.RequestID != "" {
+		msg = "[" + lm.RequestID + "] " + msg
+	}
 	msg = lm.Prefix + " " + msg
 
 	if lm.enableFuncCallDepth {
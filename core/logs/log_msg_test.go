@@ -45,4 +45,7 @@ func TestLogMsg_OldStyleFormat(t *testing.T) {
 	lg.Msg = "hello, %s"
 	lg.Args = []interface{}{"world"}
 	assert.Equal(t, "[D] [/user/home/main.go:13] Cus hello, world", lg.OldStyleFormat())
+
+	lg.RequestID = "req-1"
+	assert.Equal(t, "[D] [/user/home/main.go:13] Cus [req-1] hello, world", lg.OldStyleFormat())
 }
@@ -15,6 +15,7 @@
 package logs
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -126,3 +127,42 @@ func TestBeeLogger_AsyncNonBlockWrite(t *testing.T) {
 		})
 	}
 }
+
+// captureLogger keeps the messages
+type captureLogger struct {
+	msgs []*LogMsg
+}
+
+func (c *captureLogger) Init(string) error { return nil }
+
+func (c *captureLogger) WriteMsg(lm *LogMsg) error {
+	c.msgs = append(c.msgs, lm)
+	return nil
+}
+
+func (*captureLogger) Destroy()                    {}
+func (*captureLogger) Flush()                      {}
+func (*captureLogger) SetFormatter(_ LogFormatter) {}
+
+func TestBeeLoggerContext(t *testing.T) {
+	c := &captureLogger{}
+	bl := NewLogger()
+	bl.init = true
+	bl.outputs = []*nameLogger{{Logger: c, name: "capture"}}
+	bl.SetLevel(LevelInfo)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	bl.ErrorContext(ctx, "error %d", 1)
+	bl.WarnContext(ctx, "warn")
+	bl.InfoContext(context.Background(), "info")
+	bl.DebugContext(ctx, "debug")
+
+	assert.Equal(t, 3, len(c.msgs))
+	assert.Equal(t, "req-1", c.msgs[0].RequestID)
+	assert.Equal(t, "[E]  [req-1] error 1", c.msgs[0].OldStyleFormat())
+	assert.Equal(t, LevelWarn, c.msgs[1].Level)
+	assert.Equal(t, "req-1", c.msgs[1].RequestID)
+	assert.Equal(t, "", c.msgs[2].RequestID)
+
+	assert.Equal(t, "", RequestID(context.Background()))
+}
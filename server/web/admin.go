@@ -80,6 +80,8 @@ func writeJSON(rw http.ResponseWriter, jsonData []byte) {
 // adminApp is an http.HandlerFunc map used as beeAdminApp.
 type adminApp struct {
 	*HttpServer
+	// allowed are the networks of the clients allowed to access the admin service
+	allowed []*net.IPNet
 }
 
 // Run start Beego admin
@@ -90,8 +92,12 @@ func (admin *adminApp) Run() {
 	if BConfig.Listen.AdminPort != 0 {
 		addr = net.JoinHostPort(BConfig.Listen.AdminAddr, fmt.Sprintf("%d", BConfig.Listen.AdminPort))
 	}
+	if len(adminAuthenticators) == 0 && len(admin.allowed) == 0 {
+		logs.Warn("the admin server on %s accepts every client as operator, "+
+			"set the authenticators with SetAdminAuthenticators or Listen.AdminAllowCIDRs", addr)
+	}
 	logs.Info("Admin server Running on %s", addr)
-	admin.HttpServer.Run(addr)
+	admin.HttpServer.Run(addr, adminAuth(admin.allowed, adminAuthenticators))
 }
 
 func registerAdmin() error {
@@ -105,17 +111,42 @@ func registerAdmin() error {
 		adminCfg := *BConfig
 		adminCfg.Listen.EnableHTTPS = false
 		adminCfg.Listen.EnableMutualHTTPS = false
+		// the admin server keeps reporting the readiness while the application shuts down
+		adminCfg.Listen.ShutdownOnSignal = false
+		// fcgi serves the handlers without the authentication
+		adminCfg.Listen.EnableFcgi = false
+		if cfg := BConfig.Listen; cfg.AdminCertFile != "" {
+			adminCfg.Listen.EnableHTTP = false
+			adminCfg.Listen.EnableHTTPS = cfg.AdminTrustCaFile == ""
+			adminCfg.Listen.EnableMutualHTTPS = cfg.AdminTrustCaFile != ""
+			adminCfg.Listen.AutoTLS = false
+			adminCfg.Listen.HTTPSAddr, adminCfg.Listen.HTTPSPort = cfg.AdminAddr, cfg.AdminPort
+			adminCfg.Listen.HTTPSCertFile, adminCfg.Listen.HTTPSKeyFile = cfg.AdminCertFile, cfg.AdminKeyFile
+			adminCfg.Listen.TrustCaFile = cfg.AdminTrustCaFile
+		}
+		allowed, err := parseAdminCIDRs(BConfig.Listen.AdminAllowCIDRs)
+		if err != nil {
+			return err
+		}
 		beeAdminApp = &adminApp{
 			HttpServer: NewHttpServerWithCfg(&adminCfg),
+			allowed:    allowed,
 		}
 		// keep in mind that all data should be html escaped to avoid XSS attack
 		beeAdminApp.Router("/", c, "get:AdminIndex")
 		beeAdminApp.Router("/qps", c, "get:QpsIndex")
+		beeAdminApp.Router("/statistics", c, "get:Statistics")
 		beeAdminApp.Router("/prof", c, "get:ProfIndex")
 		beeAdminApp.Router("/healthcheck", c, "get:Healthcheck")
+		beeAdminApp.Router("/health/liveness", c, "get:Liveness")
+		beeAdminApp.Router("/health/readiness", c, "get:Readiness")
+		beeAdminApp.Router("/health/startup", c, "get:Startup")
 		beeAdminApp.Router("/task", c, "get:TaskStatus")
 		beeAdminApp.Router("/listconf", c, "get:ListConf")
 		beeAdminApp.Router("/metrics", c, "get:PrometheusMetrics")
+		if cfg := BConfig.WebConfig.OpenAPI; cfg.EnableOpenAPI && cfg.OpenAPIOnAdmin {
+			serveOpenAPI(beeAdminApp.HttpServer, BeeApp.Handlers, cfg)
+		}
 
 		go beeAdminApp.Run()
 	}
@@ -0,0 +1,326 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// AdminRole is the role of an admin user
+type AdminRole int
+
+const (
+	// AdminRoleReadOnly can view the admin pages
+	AdminRoleReadOnly AdminRole = iota + 1
+	// AdminRoleOperator can run the tasks and write the profiles too
+	AdminRoleOperator
+)
+
+// AdminAuthenticator authenticates the requests to the admin service, see SetAdminAuthenticators
+type AdminAuthenticator interface {
+	// Authenticate returns the role of the user sending r, ok is false when r isn't authenticated
+	Authenticate(r *http.Request) (role AdminRole, ok bool)
+}
+
+// adminChallenger is implemented by the AdminAuthenticators telling the clients how to authenticate
+type adminChallenger interface {
+	// Challenge returns the value of the WWW-Authenticate header
+	Challenge() string
+}
+
+// adminAuthenticators authenticate the requests to the admin service, nil means no authentication
+var adminAuthenticators []AdminAuthenticator
+
+// SetAdminAuthenticators makes the admin service authenticate the requests,
+// a request is authenticated by the first authenticator accepting it and rejected when none does.
+// Without authenticators, all the requests are authenticated as operator.
+// usage:
+//
+//	auth := web.NewAdminBasicAuth("beego")
+//	auth.AddUser("ops", "secret", web.AdminRoleOperator)
+//	web.SetAdminAuthenticators(auth, web.NewAdminBearerAuth().AddToken(os.Getenv("MONITORING_TOKEN"), web.AdminRoleReadOnly))
+func SetAdminAuthenticators(auths ...AdminAuthenticator) {
+	adminAuthenticators = auths
+}
+
+// AdminBasicAuth authenticates the admin users with the HTTP basic authentication
+type AdminBasicAuth struct {
+	realm string
+
+	mu    sync.RWMutex
+	users map[string]adminCredential
+}
+
+type adminCredential struct {
+	// digest is the sha256 of the secret, comparing the digests doesn't leak the length of the secret
+	digest [sha256.Size]byte
+	role   AdminRole
+}
+
+func (c adminCredential) match(secret string) bool {
+	digest := sha256.Sum256([]byte(secret))
+	return subtle.ConstantTimeCompare(digest[:], c.digest[:]) == 1
+}
+
+// NewAdminBasicAuth returns an AdminBasicAuth without user, realm is shown by the browsers
+func NewAdminBasicAuth(realm string) *AdminBasicAuth {
+	return &AdminBasicAuth{
+		realm: realm,
+		users: make(map[string]adminCredential),
+	}
+}
+
+// AddUser adds a user, it replaces the user with the same name
+func (a *AdminBasicAuth) AddUser(name, password string, role AdminRole) *AdminBasicAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users[name] = adminCredential{digest: sha256.Sum256([]byte(password)), role: role}
+	return a
+}
+
+// Authenticate checks the user and the password of the Authorization header
+func (a *AdminBasicAuth) Authenticate(r *http.Request) (AdminRole, bool) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return 0, false
+	}
+	a.mu.RLock()
+	user, ok := a.users[name]
+	a.mu.RUnlock()
+	if !ok || !user.match(password) {
+		return 0, false
+	}
+	return user.role, true
+}
+
+// Challenge asks the browsers for the user and the password
+func (a *AdminBasicAuth) Challenge() string {
+	return fmt.Sprintf("Basic realm=%q", a.realm)
+}
+
+// AdminBearerAuth authenticates the admin users with the bearer tokens of the Authorization header
+type AdminBearerAuth struct {
+	mu     sync.RWMutex
+	tokens []adminCredential
+}
+
+// NewAdminBearerAuth returns an AdminBearerAuth without token
+func NewAdminBearerAuth() *AdminBearerAuth {
+	return &AdminBearerAuth{}
+}
+
+// AddToken adds a token authenticating the users with role
+func (a *AdminBearerAuth) AddToken(token string, role AdminRole) *AdminBearerAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens = append(a.tokens, adminCredential{digest: sha256.Sum256([]byte(token)), role: role})
+	return a
+}
+
+// Authenticate checks the token of the Authorization header
+func (a *AdminBearerAuth) Authenticate(r *http.Request) (AdminRole, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return 0, false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, t := range a.tokens {
+		if t.match(token) {
+			return t.role, true
+		}
+	}
+	return 0, false
+}
+
+// Challenge asks the clients for a bearer token
+func (a *AdminBearerAuth) Challenge() string {
+	return "Bearer"
+}
+
+// AdminMTLSAuth authenticates the admin users with their client certificate,
+// the admin service verifies them when Listen.AdminTrustCaFile is set
+type AdminMTLSAuth struct {
+	mu    sync.RWMutex
+	roles map[string]AdminRole
+}
+
+// NewAdminMTLSAuth returns an AdminMTLSAuth without user
+func NewAdminMTLSAuth() *AdminMTLSAuth {
+	return &AdminMTLSAuth{roles: make(map[string]AdminRole)}
+}
+
+// AddSubject authenticates the certificates whose subject common name is commonName with role
+func (a *AdminMTLSAuth) AddSubject(commonName string, role AdminRole) *AdminMTLSAuth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.roles[commonName] = role
+	return a
+}
+
+// Authenticate checks the verified client certificate of the connection
+func (a *AdminMTLSAuth) Authenticate(r *http.Request) (AdminRole, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return 0, false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	role, ok := a.roles[r.TLS.VerifiedChains[0][0].Subject.CommonName]
+	return role, ok
+}
+
+type adminRoleKey struct{}
+
+// adminRole returns the role of the user sending r, it's set by adminAuth
+func adminRole(r *http.Request) AdminRole {
+	if role, ok := r.Context().Value(adminRoleKey{}).(AdminRole); ok {
+		return role
+	}
+	return 0
+}
+
+// adminAuth rejects the requests from the clients out of allowed,
+// then authenticates the requests with the AdminAuthenticators
+func adminAuth(allowed []*net.IPNet, auths []AdminAuthenticator) MiddleWare {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if len(allowed) > 0 && !containsIP(allowed, r.RemoteAddr) {
+				http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			role, ok := AdminRoleOperator, len(auths) == 0
+			for _, auth := range auths {
+				if role, ok = auth.Authenticate(r); ok {
+					break
+				}
+			}
+			if !ok {
+				for _, auth := range auths {
+					if c, isChallenger := auth.(adminChallenger); isChallenger {
+						rw.Header().Add("WWW-Authenticate", c.Challenge())
+					}
+				}
+				http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), adminRoleKey{}, role)))
+		})
+	}
+}
+
+// requireOperator writes 403 and returns false when the user isn't an operator
+func requireOperator(rw http.ResponseWriter, r *http.Request) bool {
+	if adminRole(r) == AdminRoleOperator {
+		return true
+	}
+	http.Error(rw, "only the operators can do it", http.StatusForbidden)
+	return false
+}
+
+// parseAdminCIDRs parses the CIDRs or IP addresses of Listen.AdminAllowCIDRs
+func parseAdminCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid admin allowed address %q", c)
+			}
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin allowed CIDR %q: %w", c, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminRedactedConfig are the config keys whose value is hidden by the admin service,
+// add the keys of your secrets if they are in BConfig
+var AdminRedactedConfig = []string{
+	"BConfig.WebConfig.XSRFKey",
+	"BConfig.WebConfig.Session.SessionProviderConfig",
+	"BConfig.Listen.HTTPSKeyFile",
+	"BConfig.Listen.AdminKeyFile",
+}
+
+// AdminRedactPatterns hide the values of the config keys they match, in addition to AdminRedactedConfig
+var AdminRedactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)password|passwd|secret|token|credential`),
+}
+
+// redacted is shown instead of the hidden values
+const redacted = "******"
+
+// redactConfig hides the values of the keys of AdminRedactedConfig and of the keys matching AdminRedactPatterns,
+// the empty values are kept
+func redactConfig(m M) {
+	for k, v := range m {
+		if fmt.Sprint(v) != "" && redactedKey(k) {
+			m[k] = redacted
+		}
+	}
+}
+
+func redactedKey(key string) bool {
+	for _, k := range AdminRedactedConfig {
+		if k == key {
+			return true
+		}
+	}
+	for _, p := range AdminRedactPatterns {
+		if p.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
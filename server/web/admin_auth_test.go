@@ -0,0 +1,174 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAuth(t *testing.T) {
+	basic := NewAdminBasicAuth("beego").AddUser("ops", "pass", AdminRoleOperator)
+	bearer := NewAdminBearerAuth().AddToken("viewer-token", AdminRoleReadOnly)
+	allowed, err := parseAdminCIDRs([]string{"127.0.0.1", "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	var role AdminRole
+	handler := adminAuth(allowed, []AdminAuthenticator{basic, bearer})(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		role = adminRole(r)
+	}))
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		setAuth    func(r *http.Request)
+		status     int
+		role       AdminRole
+	}{
+		{name: "operator", remoteAddr: "127.0.0.1:1234", setAuth: func(r *http.Request) { r.SetBasicAuth("ops", "pass") },
+			status: http.StatusOK, role: AdminRoleOperator},
+		{name: "read only", remoteAddr: "10.1.2.3:1234", setAuth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer viewer-token") },
+			status: http.StatusOK, role: AdminRoleReadOnly},
+		{name: "wrong password", remoteAddr: "127.0.0.1:1234", setAuth: func(r *http.Request) { r.SetBasicAuth("ops", "wrong") },
+			status: http.StatusUnauthorized},
+		{name: "wrong token", remoteAddr: "127.0.0.1:1234", setAuth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") },
+			status: http.StatusUnauthorized},
+		{name: "anonymous", remoteAddr: "127.0.0.1:1234", setAuth: func(r *http.Request) {}, status: http.StatusUnauthorized},
+		{name: "not allowed", remoteAddr: "192.168.1.1:1234", setAuth: func(r *http.Request) { r.SetBasicAuth("ops", "pass") },
+			status: http.StatusForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			role = 0
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = c.remoteAddr
+			c.setAuth(r)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, c.status, w.Code)
+			assert.Equal(t, c.role, role)
+			if c.status == http.StatusUnauthorized {
+				assert.Equal(t, []string{`Basic realm="beego"`, "Bearer"}, w.Header().Values("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// without authenticators, everybody is operator
+	handler = adminAuth(nil, nil)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		role = adminRole(r)
+	}))
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, AdminRoleOperator, role)
+
+	_, err = parseAdminCIDRs([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
+
+func TestAdminMTLSAuth(t *testing.T) {
+	auth := NewAdminMTLSAuth().AddSubject("ops", AdminRoleOperator)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	_, ok := auth.Authenticate(r)
+	assert.False(t, ok)
+
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "ops"}}}}}
+	role, ok := auth.Authenticate(r)
+	assert.True(t, ok)
+	assert.Equal(t, AdminRoleOperator, role)
+
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "other"}}}}}
+	_, ok = auth.Authenticate(r)
+	assert.False(t, ok)
+}
+
+func TestAdminOperatorOnly(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/task", &adminController{}, WithRouterMethods(&adminController{}, "get:TaskStatus"))
+	handler.Add("/prof", &adminController{}, WithRouterMethods(&adminController{}, "get:ProfIndex"))
+	auth := NewAdminBearerAuth().AddToken("viewer", AdminRoleReadOnly).AddToken("ops", AdminRoleOperator)
+	app := adminAuth(nil, []AdminAuthenticator{auth})(handler)
+
+	do := func(url, token string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	assert.Equal(t, http.StatusForbidden, do("/task?taskname=missing", "viewer").Code)
+	// the task commands are registered when the tasks start, the operator passes the check only
+	assert.NotEqual(t, http.StatusForbidden, do("/task?taskname=missing", "ops").Code)
+	assert.Equal(t, http.StatusForbidden, do("/prof?command=get+memprof", "viewer").Code)
+	assert.Equal(t, http.StatusOK, do("/prof?command=lookup+goroutine", "viewer").Code)
+}
+
+func TestRedactConfig(t *testing.T) {
+	m := M{
+		"BConfig.WebConfig.Session.SessionProviderConfig":   "redis://:password@127.0.0.1:6379/0",
+		"BConfig.WebConfig.XSRFKey":                         "",
+		"BConfig.AppName":                                   "beego",
+		"BConfig.Listen.HTTPSCertFile":                      "cert.pem",
+		"BConfig.Listen.HTTPSKeyFile":                       "key.pem",
+		"BConfig.WebConfig.Session.SessionNameInHTTPHeader": "Beegosessionid",
+		"BConfig.Listen.HTTPPort":                           8080,
+	}
+	redactConfig(m)
+	assert.Equal(t, M{
+		"BConfig.WebConfig.Session.SessionProviderConfig":   redacted,
+		"BConfig.WebConfig.XSRFKey":                         "",
+		"BConfig.AppName":                                   "beego",
+		"BConfig.Listen.HTTPSCertFile":                      "cert.pem",
+		"BConfig.Listen.HTTPSKeyFile":                       redacted,
+		"BConfig.WebConfig.Session.SessionNameInHTTPHeader": "Beegosessionid",
+		"BConfig.Listen.HTTPPort":                           8080,
+	}, m)
+
+	// the keys matching the patterns are redacted too
+	m = M{
+		"BConfig.Custom.DBPassword":  "root",
+		"BConfig.Custom.APIToken":    "abc",
+		"BConfig.Custom.OAuthSecret": "",
+		"BConfig.Custom.Host":        "db.local",
+	}
+	redactConfig(m)
+	assert.Equal(t, M{
+		"BConfig.Custom.DBPassword":  redacted,
+		"BConfig.Custom.APIToken":    redacted,
+		"BConfig.Custom.OAuthSecret": "",
+		"BConfig.Custom.Host":        "db.local",
+	}, m)
+
+	patterns := AdminRedactPatterns
+	defer func() { AdminRedactPatterns = patterns }()
+	AdminRedactPatterns = append(AdminRedactPatterns, regexp.MustCompile(`Host$`))
+	m = M{"BConfig.Custom.Host": "db.local", "BConfig.AppName": "beego"}
+	redactConfig(m)
+	assert.Equal(t, M{"BConfig.Custom.Host": redacted, "BConfig.AppName": "beego"}, m)
+
+	// the keys are those listed by the admin service
+	m = M{}
+	list("BConfig", BConfig, m)
+	for _, k := range AdminRedactedConfig {
+		assert.Contains(t, m, k)
+	}
+}
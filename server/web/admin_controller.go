@@ -45,6 +45,10 @@ func (a *adminController) ProfIndex() {
 	if command == "" {
 		return
 	}
+	// the profiles are written in files of the server
+	if (command == "get cpuprof" || command == "get memprof") && !requireOperator(rw, r) {
+		return
+	}
 
 	var (
 		format = r.Form.Get("format")
@@ -87,6 +91,9 @@ func (a *adminController) TaskStatus() {
 	req.ParseForm()
 	taskname := req.Form.Get("taskname")
 	if taskname != "" {
+		if !requireOperator(rw, req) {
+			return
+		}
 		cmd := admin.GetCommand("task", "run")
 		res := cmd.Execute(taskname)
 		if res.IsSuccess() {
@@ -157,6 +164,13 @@ func heathCheck(rw http.ResponseWriter, r *http.Request) {
 		*resultList = append(*resultList, result)
 	}
 
+	// the load balancers stop sending requests when the application is shutting down
+	status := http.StatusOK
+	if !admin.IsReady() {
+		status = http.StatusServiceUnavailable
+		*resultList = append(*resultList, []string{"error", "readiness", "not ready"})
+	}
+
 	queryParams := r.URL.Query()
 	jsonFlag := queryParams.Get("json")
 	shouldReturnJSON, _ := strconv.ParseBool(jsonFlag)
@@ -168,7 +182,9 @@ func heathCheck(rw http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 		} else {
-			writeJSON(rw, jsonResponse)
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(status)
+			rw.Write(jsonResponse)
 		}
 		return
 	}
@@ -177,9 +193,41 @@ func heathCheck(rw http.ResponseWriter, r *http.Request) {
 	data["Content"] = content
 	data["Title"] = "Health Check"
 
+	rw.WriteHeader(status)
+
 	writeTemplate(rw, data, healthCheckTpl, defaultScriptsTpl)
 }
 
+// Liveness runs the liveness probe, it's in "/health/liveness" pattern in admin module.
+func (a *adminController) Liveness() {
+	writeProbe(a.Ctx.ResponseWriter, a.Ctx.Request, admin.Liveness)
+}
+
+// Readiness runs the readiness probe, it's in "/health/readiness" pattern in admin module.
+func (a *adminController) Readiness() {
+	writeProbe(a.Ctx.ResponseWriter, a.Ctx.Request, admin.Readiness)
+}
+
+// Startup runs the startup probe, it's in "/health/startup" pattern in admin module.
+func (a *adminController) Startup() {
+	writeProbe(a.Ctx.ResponseWriter, a.Ctx.Request, admin.Startup)
+}
+
+// writeProbe writes the result of the probe in JSON, the status code is 503 when the probe is down
+func writeProbe(rw http.ResponseWriter, r *http.Request, probe admin.ProbeType) {
+	res := admin.RunProbe(r.Context(), probe)
+	data, err := json.Marshal(res)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if !res.IsUp() {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+	rw.Write(data)
+}
+
 // QpsIndex is the http.Handler for writing qps statistics map result info in http.ResponseWriter.
 // it's registered with url pattern "/qps" in admin module.
 func (a *adminController) QpsIndex() {
@@ -196,9 +244,26 @@ func (a *adminController) QpsIndex() {
 			}
 		}
 	}
+	data["Routes"] = StatisticsMap.RouteStatistics()
+	data["Window"] = StatisticsWindow
 	writeTemplate(a.Ctx.ResponseWriter, data, qpsTpl, defaultScriptsTpl)
 }
 
+// Statistics writes the statistics of the routes in JSON, the durations are in nanoseconds.
+// it's registered with url pattern "/statistics" in admin module.
+func (a *adminController) Statistics() {
+	data, err := json.Marshal(map[string]interface{}{
+		"window":  StatisticsWindow,
+		"buckets": LatencyBuckets,
+		"routes":  StatisticsMap.RouteStatistics(),
+	})
+	if err != nil {
+		http.Error(a.Ctx.ResponseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(a.Ctx.ResponseWriter, data)
+}
+
 // ListConf is the http.Handler of displaying all beego configuration values as key/value pair.
 // it's registered with url pattern "/listconf" in admin module.
 func (a *adminController) ListConf() {
@@ -216,6 +281,7 @@ func (a *adminController) ListConf() {
 	case "conf":
 		m := make(M)
 		list("BConfig", BConfig, m)
+		redactConfig(m)
 		m["appConfigPath"] = template.HTMLEscapeString(appConfigPath)
 		m["appConfigProvider"] = template.HTMLEscapeString(appConfigProvider)
 		tmpl := template.Must(template.New("dashboard").Parse(dashboardTpl))
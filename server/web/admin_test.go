@@ -236,3 +236,40 @@ func TestHealthCheckHandlerReturnsJSON(t *testing.T) {
 	assert.Equal(t, expectedResponseBody[0], database)
 	assert.Equal(t, expectedResponseBody[1], cache)
 }
+
+func TestHealthCheckHandlerNotReady(t *testing.T) {
+	admin.SetReady(false)
+	defer admin.SetReady(true)
+
+	for _, url := range []string{"/healthcheck", "/healthcheck?json=true"} {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		heathCheck(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "readiness")
+	}
+}
+
+func TestWriteProbe(t *testing.T) {
+	admin.AddProbe(admin.Startup, "config", &SampleDatabaseCheck{})
+	admin.AddProbe(admin.Startup, "cache", &SampleCacheCheck{}, admin.WithCritical(false))
+	defer admin.RemoveProbe(admin.Startup, "config")
+	defer admin.RemoveProbe(admin.Startup, "cache")
+
+	req, _ := http.NewRequest(http.MethodGet, "/health/startup", nil)
+	w := httptest.NewRecorder()
+	writeProbe(w, req, admin.Startup)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	res := &admin.ProbeResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+	assert.Equal(t, admin.StatusUp, res.Status)
+	assert.Len(t, res.Checks, 2)
+	assert.Equal(t, "no cache detected", res.Checks[0].Error)
+
+	admin.AddProbe(admin.Startup, "cache", &SampleCacheCheck{})
+	w = httptest.NewRecorder()
+	writeProbe(w, req, admin.Startup)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"down"`)
+}
@@ -85,6 +85,49 @@ var qpsTpl = `{{define "content"}}
 	    <td data-order="{{index $elem 5}}">{{index $elem 6}}</td>
 	    <td data-order="{{index $elem 7}}">{{index $elem 8}}</td>
 	    <td data-order="{{index $elem 9}}">{{index $elem 10}}</td>
+	    <td data-order="{{index $elem 11}}">{{index $elem 12}}</td>
+	    <td data-order="{{index $elem 13}}">{{index $elem 14}}</td>
+	    <td data-order="{{index $elem 15}}">{{index $elem 16}}</td>
+	</tr>
+	{{end}}
+	</tbody>
+
+</table>
+
+<h1>Routes statistics of the last {{.Window}}</h1>
+<table class="table table-striped table-hover ">
+	<thead>
+	<tr>
+		<th>route</th>
+		<th>method</th>
+		<th>in flight</th>
+		<th>times</th>
+		<th>2xx</th>
+		<th>3xx</th>
+		<th>4xx</th>
+		<th>5xx</th>
+		<th>p50</th>
+		<th>p90</th>
+		<th>p99</th>
+		<th>max used</th>
+	</tr>
+	</thead>
+
+	<tbody>
+	{{range .Routes}}
+	<tr>
+	    <td>{{html .Pattern}}</td>
+	    <td>{{.Method}}</td>
+	    <td>{{.InFlight}}</td>
+	    <td>{{.Window.Count}}</td>
+	    <td>{{index .Window.Status "2xx"}}</td>
+	    <td>{{index .Window.Status "3xx"}}</td>
+	    <td>{{index .Window.Status "4xx"}}</td>
+	    <td>{{index .Window.Status "5xx"}}</td>
+	    <td data-order="{{.Window.P50.Nanoseconds}}">{{.Window.P50}}</td>
+	    <td data-order="{{.Window.P90.Nanoseconds}}">{{.Window.P90}}</td>
+	    <td data-order="{{.Window.P99.Nanoseconds}}">{{.Window.P99}}</td>
+	    <td data-order="{{.Window.Max.Nanoseconds}}">{{.Window.Max}}</td>
 	</tr>
 	{{end}}
 	</tbody>
@@ -71,8 +71,10 @@ func initBeforeHTTPRun() {
 			registerDefaultErrorHandler,
 			registerSession,
 			registerTemplate,
+			registerOpenAPI,
 			registerAdmin,
 			registerGzip,
+			registerTrustedProxies,
 			// registerCommentRouter,
 		)
 
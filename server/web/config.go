@@ -91,6 +91,12 @@ type Config struct {
 	// And this configure item only work in dev run mode (see RunMode)
 	// @Default true
 	EnableErrorsRender bool
+	// EnableProblemJSON
+	// @Description If it's true, the errors of the requests accepting JSON are rendered as
+	// application/problem+json (RFC 7807) instead of the error pages.
+	// It covers Abort, Exception, the panics handled by RecoverPanic and the errors of the generic wrappers
+	// @Default false
+	EnableProblemJSON bool
 	// ServerName
 	// @Description server name. For example, in large scale system,
 	// you may want to deploy your application to several machines, so that each of them has a server name
@@ -98,6 +104,15 @@ type Config struct {
 	// or integrated with other tools such as tracing, metrics
 	// @Default
 	ServerName string
+	// TrustedProxies
+	// @Description the CIDRs or IP addresses of the proxies in front of the application.
+	// The Forwarded, X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers are only trusted
+	// when they are received from these proxies, they are used by Input.IP, Input.Scheme and Input.Host.
+	// The client IP is the first address of X-Forwarded-For, walked from the right, which is not a trusted proxy.
+	// The default value trusts the loopback and the private networks, see context.DefaultTrustedProxies,
+	// set it to the networks of your proxies, or to an empty list if the application is not behind a proxy.
+	// @Default ["127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7"]
+	TrustedProxies []string
 
 	// RecoverFunc
 	// @Description when Beego want to recover from panic, it will use this func as callback
@@ -148,6 +163,12 @@ type Listen struct {
 	// see EnableHTTPS
 	// @Default true
 	EnableHTTP bool
+	// EnableH2C
+	// @Description if it's true, Beego will accept HTTP/2 requests without TLS on the HTTP address,
+	// either with prior knowledge or upgraded from HTTP/1.1.
+	// It's useful when Beego runs behind a proxy speaking h2c to backends
+	// @Default false
+	EnableH2C bool
 	// AutoTLS
 	// @Description If it's true, Beego will use default value to initialize the TLS configure
 	// But those values could be override if you have custom value.
@@ -181,6 +202,22 @@ type Listen struct {
 	// @Description EnableStdIo works with EnableFcgi Use FCGI via standard I/O
 	// @Default false
 	EnableStdIo bool
+	// ShutdownOnSignal
+	// @Description if it's true, Beego shuts down gracefully when it receives SIGINT or SIGTERM
+	// see HttpServer.Shutdown
+	// @Default false
+	ShutdownOnSignal bool
+	// ShutdownDrainTime
+	// @Description Beego keeps serving requests during this period after the healthcheck reports not ready on shutdown,
+	// so that the load balancers have time to stop sending requests.
+	// The unit is second.
+	// @Default 0
+	ShutdownDrainTime int64
+	// ShutdownTimeout
+	// @Description Beego waits for the active requests and the running tasks during this period on shutdown.
+	// The unit is second, 0 means waiting until they are done.
+	// @Default 30
+	ShutdownTimeout int64
 	// ServerTimeOut
 	// @Description Beego use this as ReadTimeout and WriteTimeout
 	// The unit is second.
@@ -238,6 +275,28 @@ type Listen struct {
 	// @Description  Beego will listen to this port to provide admin service
 	// @Default 8088
 	AdminPort int
+	// AdminAllowCIDRs
+	// @Description only the clients whose IP is in those ranges can access the admin service,
+	// eg. []string{"127.0.0.1/32", "10.0.0.0/8"}. All the clients can access it when it's empty
+	// see SetAdminAuthenticators
+	// @Default []
+	AdminAllowCIDRs []string
+	// AdminCertFile
+	// @Description Beego serves the admin service over HTTPS with this cert file when it's not empty
+	// see AdminKeyFile
+	// @Default ""
+	AdminCertFile string
+	// AdminKeyFile
+	// @Description Beego read this file as the key file of the admin service
+	// see AdminCertFile
+	// @Default ""
+	AdminKeyFile string
+	// AdminTrustCaFile
+	// @Description Beego verifies the client certificates of the admin service with this CA file when it's not empty,
+	// the verified certificates are authenticated by AdminMTLSAuth.
+	// see ClientAuth
+	// @Default ""
+	AdminTrustCaFile string
 	// @Description Beego use this tls.ClientAuthType to initialize TLS connection
 	// The default value is tls.RequireAndVerifyClientCert
 	// @Default 4
@@ -293,6 +352,20 @@ type WebConfig struct {
 	// @Description The static resources with those extension will be compressed if EnableGzip is true
 	// @Default [".css", ".js" ]
 	StaticExtensionsToGzip []string
+	// StaticPrecompressed
+	// @Description If it's true, Beego serves the precompressed files next to the static resources,
+	// eg. app.js.br, app.js.zst or app.js.gz for app.js, to the clients accepting their encoding.
+	// It doesn't depend on EnableGzip
+	// @Default false
+	StaticPrecompressed bool
+	// StaticCacheImmutable
+	// @Description If it's true, the fingerprinted static resources are sent with
+	// Cache-Control: public, max-age=31536000, immutable, so the browsers never revalidate them.
+	// The fingerprinted resources have a name containing a hex hash, eg. app.3f2a9c1b.js,
+	// or are in the asset manifest, see SetAssetManifest.
+	// Enable it only if the names of all your static files with a hash change with their content
+	// @Default false
+	StaticCacheImmutable bool
 	// StaticCacheFileSize
 	// @Description If the size of static resource < StaticCacheFileSize, Beego will try to handle it by itself,
 	// it means that Beego will compressed the file data (if enable) and cache this file.
@@ -340,6 +413,38 @@ type WebConfig struct {
 	XSRFExpire int
 	// @Description session related config
 	Session SessionConfig
+	// @Description OpenAPI document related config
+	OpenAPI OpenAPIConfig
+}
+
+// OpenAPIConfig holds the config of the OpenAPI document generated from the routers
+type OpenAPIConfig struct {
+	// EnableOpenAPI
+	// @Description if it's true, Beego serves the OpenAPI 3.1 document of the application's routers
+	// @Default false
+	EnableOpenAPI bool
+	// OpenAPIPath
+	// @Description the document is served as JSON at this path and at the path + ".json",
+	// and as YAML at the path + ".yaml"
+	// @Default /openapi
+	OpenAPIPath string
+	// OpenAPIOnAdmin
+	// @Description if it's true, the document is served by the admin server instead of the application
+	// see EnableAdmin
+	// @Default false
+	OpenAPIOnAdmin bool
+	// OpenAPITitle
+	// @Description the title of the API, AppName is used if it's empty
+	// @Default ""
+	OpenAPITitle string
+	// OpenAPIVersion
+	// @Description the version of the API
+	// @Default 1.0.0
+	OpenAPIVersion string
+	// OpenAPIDescription
+	// @Description the description of the API
+	// @Default ""
+	OpenAPIDescription string
 }
 
 // SessionConfig holds session related config
@@ -508,6 +613,11 @@ func defaultRecoverPanic(ctx *context.Context, cfg *Config) {
 			stack += fmt.Sprintf("%s:%d\n", file, line)
 		}
 
+		if acceptsProblem(cfg, ctx) {
+			writePanicProblem(err, ctx, cfg)
+			return
+		}
+
 		if ctx.Output.Status != 0 {
 			ctx.ResponseWriter.WriteHeader(ctx.Output.Status)
 		} else {
@@ -534,27 +644,32 @@ func newBConfig() *Config {
 		MaxUploadSize:      1 << 30, // 1GB
 		EnableErrorsShow:   true,
 		EnableErrorsRender: true,
+		EnableProblemJSON:  false,
+		TrustedProxies:     append([]string(nil), context.DefaultTrustedProxies...),
 		Listen: Listen{
-			Graceful:      false,
-			ServerTimeOut: 0,
-			ListenTCP4:    false,
-			EnableHTTP:    true,
-			AutoTLS:       false,
-			Domains:       []string{},
-			TLSCacheDir:   ".",
-			HTTPAddr:      "",
-			HTTPPort:      8080,
-			EnableHTTPS:   false,
-			HTTPSAddr:     "",
-			HTTPSPort:     10443,
-			HTTPSCertFile: "",
-			HTTPSKeyFile:  "",
-			EnableAdmin:   false,
-			AdminAddr:     "",
-			AdminPort:     8088,
-			EnableFcgi:    false,
-			EnableStdIo:   false,
-			ClientAuth:    int(tls.RequireAndVerifyClientCert),
+			Graceful:        false,
+			ServerTimeOut:   0,
+			ShutdownTimeout: 30,
+			ListenTCP4:      false,
+			EnableHTTP:      true,
+			EnableH2C:       false,
+			AutoTLS:         false,
+			Domains:         []string{},
+			TLSCacheDir:     ".",
+			HTTPAddr:        "",
+			HTTPPort:        8080,
+			EnableHTTPS:     false,
+			HTTPSAddr:       "",
+			HTTPSPort:       10443,
+			HTTPSCertFile:   "",
+			HTTPSKeyFile:    "",
+			EnableAdmin:     false,
+			AdminAddr:       "",
+			AdminPort:       8088,
+			AdminAllowCIDRs: []string{},
+			EnableFcgi:      false,
+			EnableStdIo:     false,
+			ClientAuth:      int(tls.RequireAndVerifyClientCert),
 		},
 		WebConfig: WebConfig{
 			AutoRender:             true,
@@ -566,6 +681,7 @@ func newBConfig() *Config {
 			StaticExtensionsToGzip: []string{".css", ".js"},
 			StaticCacheFileSize:    1024 * 100,
 			StaticCacheFileNum:     1000,
+			StaticCacheImmutable:   false,
 			TemplateLeft:           "{{",
 			TemplateRight:          "}}",
 			ViewsPath:              "views",
@@ -588,6 +704,12 @@ func newBConfig() *Config {
 				SessionEnableSidInURLQuery:   false, // enable get the sessionId from Url Query params
 				SessionCookieSameSite:        http.SameSiteDefaultMode,
 			},
+			OpenAPI: OpenAPIConfig{
+				EnableOpenAPI:  false,
+				OpenAPIPath:    "/openapi",
+				OpenAPIOnAdmin: false,
+				OpenAPIVersion: "1.0.0",
+			},
 		},
 		Log: LogConfig{
 			AccessLogs:       false,
@@ -638,7 +760,7 @@ func assignConfig(ac config.Configer) error {
 }
 
 func parseConfigForV1(ac config.Configer) {
-	for _, i := range []interface{}{BConfig, &BConfig.Listen, &BConfig.WebConfig, &BConfig.Log, &BConfig.WebConfig.Session} {
+	for _, i := range []interface{}{BConfig, &BConfig.Listen, &BConfig.WebConfig, &BConfig.Log, &BConfig.WebConfig.Session, &BConfig.WebConfig.OpenAPI} {
 		assignSingleConfig(i, ac)
 	}
 
@@ -679,6 +801,19 @@ func parseConfigForV1(ac config.Configer) {
 		}
 	}
 
+	// an empty value trusts no proxy, the defaults are kept only when the key is missing
+	if tp, err := ac.String("TrustedProxies"); err == nil && (tp != "" || hasConfigKey(ac, "TrustedProxies")) {
+		BConfig.TrustedProxies = strings.FieldsFunc(tp, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' '
+		})
+	}
+
+	if cidrs, err := ac.String("AdminAllowCIDRs"); cidrs != "" && err == nil {
+		BConfig.Listen.AdminAllowCIDRs = strings.FieldsFunc(cidrs, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' '
+		})
+	}
+
 	if sfs, err := ac.Int("StaticCacheFileSize"); err == nil {
 		BConfig.WebConfig.StaticCacheFileSize = sfs
 	}
@@ -703,6 +838,22 @@ func parseConfigForV1(ac config.Configer) {
 	}
 }
 
+// hasConfigKey reports whether key is set in ac, even to an empty value.
+// The ini files are looked up in the section of the run mode and in the default section,
+// the other providers return the value of the key from DIY.
+func hasConfigKey(ac config.Configer, key string) bool {
+	for _, section := range []string{BConfig.RunMode, "default"} {
+		if m, err := ac.GetSection(section); err == nil {
+			if _, ok := m[strings.ToLower(key)]; ok {
+				return true
+			}
+		}
+	}
+	v, err := ac.DIY(key)
+	_, isSection := v.(map[string]string)
+	return err == nil && !isSection
+}
+
 func assignSingleConfig(p interface{}, ac config.Configer) {
 	pt := reflect.TypeOf(p)
 	if pt.Kind() != reflect.Ptr {
@@ -19,7 +19,12 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/core/config"
 	beeJson "github.com/asish-tom/beego/v2/core/config/json"
+	"github.com/asish-tom/beego/v2/server/web/context"
 )
 
 func TestDefaults(t *testing.T) {
@@ -147,3 +152,30 @@ func TestAssignConfig_03(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestAssignConfigTrustedProxies(t *testing.T) {
+	defer func(c *Config) { BConfig = c }(BConfig)
+
+	cases := []struct {
+		name     string
+		provider string
+		data     string
+		expected []string
+	}{
+		{name: "ini missing", provider: "ini", data: "appname = beego", expected: context.DefaultTrustedProxies},
+		{name: "ini empty", provider: "ini", data: "TrustedProxies =", expected: []string{}},
+		{name: "ini list", provider: "ini", data: "TrustedProxies = 10.0.0.1, 10.0.0.2", expected: []string{"10.0.0.1", "10.0.0.2"}},
+		{name: "ini empty in run mode section", provider: "ini", data: "TrustedProxies = 10.0.0.1\n[dev]\nTrustedProxies =", expected: []string{"10.0.0.1"}},
+		{name: "json missing", provider: "json", data: `{"AppName": "beego"}`, expected: context.DefaultTrustedProxies},
+		{name: "json empty", provider: "json", data: `{"TrustedProxies": []}`, expected: []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			BConfig = newBConfig()
+			ac, err := config.NewConfigData(c.provider, []byte(c.data))
+			require.NoError(t, err)
+			require.NoError(t, assignConfig(ac))
+			assert.Equal(t, c.expected, BConfig.TrustedProxies)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RouteConstraint restricts the values accepted by a typed route parameter, eg. /:id<uuid>
+type RouteConstraint struct {
+	// Name is the constraint type used in route patterns
+	Name string
+	// Regexp is the expression spliced into the route regexp,
+	// it must not contain parentheses
+	Regexp string
+	// Match is an additional check applied to the captured value, it may be nil
+	Match func(value string) bool
+
+	re *regexp.Regexp
+}
+
+// Accept reports whether value satisfies the constraint
+func (c *RouteConstraint) Accept(value string) bool {
+	if !c.re.MatchString(value) {
+		return false
+	}
+	return c.Match == nil || c.Match(value)
+}
+
+var routeConstraints = map[string]*RouteConstraint{}
+
+// constraintParam matches typed parameters such as :id<uuid>
+var constraintParam = regexp.MustCompile(`:([a-zA-Z0-9_]+)<([a-zA-Z0-9_]+)>`)
+
+func init() {
+	AddRouteConstraintRegexp("int", `[0-9]+`)
+	AddRouteConstraintRegexp("alpha", `[a-zA-Z]+`)
+	AddRouteConstraintRegexp("slug", `[a-z0-9][a-z0-9-]*`)
+	AddRouteConstraintRegexp("uuid", `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	AddRouteConstraint(&RouteConstraint{
+		Name:   "date",
+		Regexp: `[0-9]{4}-[0-9]{2}-[0-9]{2}`,
+		Match: func(value string) bool {
+			_, err := time.Parse("2006-01-02", value)
+			return err == nil
+		},
+	})
+}
+
+// AddRouteConstraint registers a named constraint type which can be used in
+// route patterns as :param<name>. An empty Regexp matches one path segment.
+// Constraints must be registered before the routes using them.
+func AddRouteConstraint(c *RouteConstraint) {
+	if c.Name == "" {
+		panic("route constraint name can't be empty")
+	}
+	if c.Regexp == "" {
+		c.Regexp = `[^/]+`
+	}
+	if strings.ContainsAny(c.Regexp, "()") {
+		panic(fmt.Sprintf("route constraint %s: regexp %q must not contain parentheses", c.Name, c.Regexp))
+	}
+	c.re = regexp.MustCompile("^(?:" + c.Regexp + ")$")
+	routeConstraints[strings.ToLower(c.Name)] = c
+}
+
+// AddRouteConstraintRegexp registers a constraint type backed by a regexp
+func AddRouteConstraintRegexp(name, expr string) {
+	AddRouteConstraint(&RouteConstraint{Name: name, Regexp: expr})
+}
+
+// AddRouteConstraintFunc registers a constraint type backed by a function
+func AddRouteConstraintFunc(name string, match func(value string) bool) {
+	AddRouteConstraint(&RouteConstraint{Name: name, Match: match})
+}
+
+func getRouteConstraint(name string) *RouteConstraint {
+	c, ok := routeConstraints[strings.ToLower(name)]
+	if !ok {
+		panic("unknown route constraint: " + name)
+	}
+	return c
+}
+
+// parseRouteConstraints returns the constraints of the typed params in pattern, keyed by param name
+// "/users/:id<uuid>/posts/:day<date>" -> {":id": uuid, ":day": date}
+func parseRouteConstraints(pattern string) map[string]*RouteConstraint {
+	matches := constraintParam.FindAllStringSubmatch(pattern, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	constraints := make(map[string]*RouteConstraint, len(matches))
+	for _, m := range matches {
+		constraints[":"+m[1]] = getRouteConstraint(m[2])
+	}
+	return constraints
+}
@@ -21,7 +21,6 @@ import (
 	"compress/zlib"
 	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -60,6 +59,12 @@ type resetWriter interface {
 	Reset(w io.Writer)
 }
 
+// ResetWriteCloser is a compressing writer which can be reused for another response
+type ResetWriteCloser interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
 type nopResetWriter struct {
 	io.Writer
 }
@@ -69,81 +74,142 @@ func (n nopResetWriter) Reset(w io.Writer) {
 }
 
 type acceptEncoder struct {
-	name                    string
-	levelEncode             func(int) resetWriter
-	customCompressLevelPool *sync.Pool
-	bestCompressionPool     *sync.Pool
+	name        string
+	levelEncode func(int) resetWriter
+	// the client accepting several encodings with the same q-value gets the one with the highest priority
+	priority int
+	// level => *sync.Pool of the writers
+	pools *sync.Map
+}
+
+func newAcceptEncoder(name string, priority int, levelEncode func(int) resetWriter) acceptEncoder {
+	return acceptEncoder{name: name, levelEncode: levelEncode, priority: priority, pools: &sync.Map{}}
 }
 
 func (ac acceptEncoder) encode(wr io.Writer, level int) resetWriter {
-	if ac.customCompressLevelPool == nil || ac.bestCompressionPool == nil {
+	if ac.levelEncode == nil {
 		return nopResetWriter{wr}
 	}
-	var rwr resetWriter
-	switch level {
-	case flate.BestSpeed:
-		rwr = ac.customCompressLevelPool.Get().(resetWriter)
-	case flate.BestCompression:
-		rwr = ac.bestCompressionPool.Get().(resetWriter)
-	default:
-		rwr = ac.levelEncode(level)
-	}
+	rwr := ac.pool(level).Get().(resetWriter)
 	rwr.Reset(wr)
 	return rwr
 }
 
 func (ac acceptEncoder) put(wr resetWriter, level int) {
-	if ac.customCompressLevelPool == nil || ac.bestCompressionPool == nil {
+	if ac.levelEncode == nil {
 		return
 	}
 	wr.Reset(nil)
+	ac.pool(level).Put(wr)
+}
 
-	// notice
-	// compressionLevel==BestCompression DOES NOT MATTER
-	// sync.Pool will not memory leak
-
-	switch level {
-	case gzipCompressLevel:
-		ac.customCompressLevelPool.Put(wr)
-	case flate.BestCompression:
-		ac.bestCompressionPool.Put(wr)
+// pool returns the writers of level, only a few levels are used so the pools are never released
+func (ac acceptEncoder) pool(level int) *sync.Pool {
+	if p, ok := ac.pools.Load(level); ok {
+		return p.(*sync.Pool)
 	}
+	p, _ := ac.pools.LoadOrStore(level, &sync.Pool{New: func() interface{} { return ac.levelEncode(level) }})
+	return p.(*sync.Pool)
 }
 
 var (
-	noneCompressEncoder = acceptEncoder{"", nil, nil, nil}
-	gzipCompressEncoder = acceptEncoder{
-		name:                    "gzip",
-		levelEncode:             func(level int) resetWriter { wr, _ := gzip.NewWriterLevel(nil, level); return wr },
-		customCompressLevelPool: &sync.Pool{New: func() interface{} { wr, _ := gzip.NewWriterLevel(nil, gzipCompressLevel); return wr }},
-		bestCompressionPool:     &sync.Pool{New: func() interface{} { wr, _ := gzip.NewWriterLevel(nil, flate.BestCompression); return wr }},
-	}
+	noneCompressEncoder = acceptEncoder{}
+	gzipCompressEncoder = newAcceptEncoder("gzip", 0,
+		func(level int) resetWriter { wr, _ := gzip.NewWriterLevel(nil, level); return wr })
 
 	// According to: http://tools.ietf.org/html/rfc2616#section-3.5 the deflate compress in http is zlib indeed
 	// deflate
 	// The "zlib" format defined in RFC 1950 [31] in combination with
 	// the "deflate" compression mechanism described in RFC 1951 [29].
-	deflateCompressEncoder = acceptEncoder{
-		name:                    "deflate",
-		levelEncode:             func(level int) resetWriter { wr, _ := zlib.NewWriterLevel(nil, level); return wr },
-		customCompressLevelPool: &sync.Pool{New: func() interface{} { wr, _ := zlib.NewWriterLevel(nil, gzipCompressLevel); return wr }},
-		bestCompressionPool:     &sync.Pool{New: func() interface{} { wr, _ := zlib.NewWriterLevel(nil, flate.BestCompression); return wr }},
-	}
+	deflateCompressEncoder = newAcceptEncoder("deflate", 0,
+		func(level int) resetWriter { wr, _ := zlib.NewWriterLevel(nil, level); return wr })
 )
 
 var encoderMap = map[string]acceptEncoder{ // all the other compress methods will ignore
 	"gzip":     gzipCompressEncoder,
 	"deflate":  deflateCompressEncoder,
-	"*":        gzipCompressEncoder, // * means any compress will accept,we prefer gzip
 	"identity": noneCompressEncoder, // identity means none-compress
 }
 
-// WriteFile reads from file and writes to writer by the specific encoding(gzip/deflate)
-func WriteFile(encoding string, writer io.Writer, file *os.File) (bool, string, error) {
+// encoderNames are the registered encodings in their registration order, * means any of them
+var encoderNames = []string{"gzip", "deflate"}
+
+// RegisterEncoder registers the content coding name, eg. "br" or "zstd",
+// newWriter returns a writer compressing at level, it's called once per pooled writer.
+// level is the gzipCompressLevel of InitGzip, or flate.BestCompression for the static files,
+// the encoders with other levels should map it to their own scale.
+// When the client accepts several encodings with the same q-value, the one with the highest priority is used,
+// gzip and deflate have the priority 0.
+// It's not safe to register encoders while serving the requests.
+//
+//	context.RegisterEncoder("br", 10, func(level int) context.ResetWriteCloser {
+//		return brotli.NewWriterLevel(nil, level)
+//	})
+func RegisterEncoder(name string, priority int, newWriter func(level int) ResetWriteCloser) {
+	if _, ok := encoderMap[name]; !ok {
+		encoderNames = append(encoderNames, name)
+	}
+	encoderMap[name] = newAcceptEncoder(name, priority, func(level int) resetWriter { return newWriter(level) })
+}
+
+var (
+	// compressIncludeTypes and compressExcludeTypes are the patterns of the content types
+	compressIncludeTypes []string
+	compressExcludeTypes []string
+)
+
+// InitCompressTypes sets the content types of the responses which are compressed.
+// When include is empty every content type which is not excluded is compressed.
+// The patterns are either a media type, eg. "application/json", or a type followed by /*, eg. "text/*"
+func InitCompressTypes(include, exclude []string) {
+	compressIncludeTypes = normalizeTypes(include)
+	compressExcludeTypes = normalizeTypes(exclude)
+}
+
+func normalizeTypes(patterns []string) []string {
+	res := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Compressible reports whether the responses of contentType are compressed, see InitCompressTypes
+func Compressible(contentType string) bool {
+	mediaType := contentType
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if matchType(compressExcludeTypes, mediaType) {
+		return false
+	}
+	return len(compressIncludeTypes) == 0 || matchType(compressIncludeTypes, mediaType)
+}
+
+func matchType(patterns []string, mediaType string) bool {
+	if mediaType == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if p == mediaType || p == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(p, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteFile reads from file and writes to writer by the specific encoding, see RegisterEncoder
+func WriteFile(encoding string, writer io.Writer, file io.Reader) (bool, string, error) {
 	return writeLevel(encoding, writer, file, flate.BestCompression)
 }
 
-// WriteBody reads writes content to writer by the specific encoding(gzip/deflate)
+// WriteBody reads writes content to writer by the specific encoding, see RegisterEncoder
 func WriteBody(encoding string, writer io.Writer, content []byte) (bool, string, error) {
 	if encoding == "" || len(content) < gzipMinLength {
 		_, err := writer.Write(content)
@@ -185,6 +251,10 @@ func ParseEncoding(r *http.Request) string {
 	if r == nil {
 		return ""
 	}
+	// event streams are flushed per event, compression would buffer them
+	if strings.Contains(r.Header.Get("Accept"), TextEventStream) {
+		return ""
+	}
 	if (getMethodOnly && r.Method == "GET") || includedMethods[r.Method] {
 		return parseEncoding(r)
 	}
@@ -192,39 +262,97 @@ func ParseEncoding(r *http.Request) string {
 }
 
 type q struct {
-	name  string
-	value float64
+	name     string
+	value    float64
+	priority int
 }
 
 func parseEncoding(r *http.Request) string {
-	acceptEncoding := r.Header.Get("Accept-Encoding")
+	name := negotiate(r.Header.Get("Accept-Encoding"), encoderNames, func(name string) (int, bool) {
+		ce, ok := encoderMap[name]
+		return ce.priority, ok
+	})
+	return encoderMap[name].name
+}
+
+// NegotiateEncoding returns the encoding of codings preferred by the client, or "" if it accepts none of them.
+// When the client accepts several of them with the same q-value, the first one of codings is used.
+func NegotiateEncoding(r *http.Request, codings ...string) string {
+	if r == nil {
+		return ""
+	}
+	return negotiate(r.Header.Get("Accept-Encoding"), codings, func(name string) (int, bool) {
+		for i, c := range codings {
+			if c == name {
+				return len(codings) - i, true
+			}
+		}
+		return 0, name == "identity"
+	})
+}
+
+// negotiate returns the coding with the highest q-value in the Accept-Encoding header,
+// then the one with the highest priority, then the first one in the header.
+// * applies to the codings which are not in the header.
+func negotiate(acceptEncoding string, codings []string, priority func(name string) (int, bool)) string {
 	if acceptEncoding == "" {
 		return ""
 	}
-	var lastQ q
+	var best q
+	consider := func(name string, value float64) {
+		p, ok := priority(name)
+		if !ok || value <= 0 {
+			return
+		}
+		if value > best.value || (value == best.value && p > best.priority) {
+			best = q{name: name, value: value, priority: p}
+		}
+	}
+	mentioned := make(map[string]bool)
+	wildcard := 0.0
 	for _, v := range strings.Split(acceptEncoding, ",") {
-		v = strings.TrimSpace(v)
-		if v == "" {
+		name, value, ok := parseCoding(v)
+		if !ok {
 			continue
 		}
-		vs := strings.Split(v, ";")
-		var cf acceptEncoder
-		var ok bool
-		if cf, ok = encoderMap[vs[0]]; !ok {
+		if name == "*" {
+			wildcard = value
 			continue
 		}
-		if len(vs) == 1 {
-			return cf.name
-		}
-		if len(vs) == 2 {
-			f, _ := strconv.ParseFloat(strings.Replace(vs[1], "q=", "", -1), 64)
-			if f == 0 {
-				continue
-			}
-			if f > lastQ.value {
-				lastQ = q{cf.name, f}
+		mentioned[name] = true
+		consider(name, value)
+	}
+	if wildcard > 0 {
+		for _, name := range codings {
+			if !mentioned[name] {
+				consider(name, wildcard)
 			}
 		}
 	}
-	return lastQ.name
+	if best.name == "identity" {
+		return ""
+	}
+	return best.name
+}
+
+// parseCoding parses an element of Accept-Encoding, eg. "gzip;q=0.8"
+func parseCoding(v string) (string, float64, bool) {
+	vs := strings.Split(v, ";")
+	name := strings.ToLower(strings.TrimSpace(vs[0]))
+	if name == "" {
+		return "", 0, false
+	}
+	value := 1.0
+	for _, param := range vs[1:] {
+		k, qv, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.TrimSpace(k) != "q" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(qv), 64)
+		if err != nil {
+			return "", 0, false
+		}
+		value = f
+	}
+	return name, value, true
 }
@@ -15,8 +15,12 @@
 package context
 
 import (
+	"bytes"
+	"io"
 	"net/http"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_ExtractEncoding(t *testing.T) {
@@ -57,3 +61,85 @@ func Test_ExtractEncoding(t *testing.T) {
 		t.Fail()
 	}
 }
+
+// upperWriter "compresses" by upper casing the content
+type upperWriter struct {
+	w io.Writer
+}
+
+func (u *upperWriter) Write(p []byte) (int, error) {
+	return u.w.Write(bytes.ToUpper(p))
+}
+
+func (u *upperWriter) Close() error {
+	return nil
+}
+
+func (u *upperWriter) Reset(w io.Writer) {
+	u.w = w
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("upper", 10, func(level int) ResetWriteCloser {
+		return &upperWriter{}
+	})
+	defer func() {
+		delete(encoderMap, "upper")
+		encoderNames = encoderNames[:len(encoderNames)-1]
+	}()
+
+	cases := []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{acceptEncoding: "gzip, deflate, upper", encoding: "upper"},
+		{acceptEncoding: "gzip, upper;q=0.9", encoding: "gzip"},
+		{acceptEncoding: "gzip;q=0.5, *", encoding: "upper"},
+		{acceptEncoding: "upper;q=0, *", encoding: "gzip"},
+		{acceptEncoding: "UPPER ; q=1", encoding: "upper"},
+		{acceptEncoding: "identity, upper;q=0.5", encoding: ""},
+	}
+	for _, c := range cases {
+		r := &http.Request{Header: http.Header{"Accept-Encoding": {c.acceptEncoding}}}
+		assert.Equal(t, c.encoding, parseEncoding(r), c.acceptEncoding)
+	}
+
+	buf := &bytes.Buffer{}
+	ok, encoding, err := WriteBody("upper", buf, []byte("hello world, hello beego"))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "upper", encoding)
+	assert.Equal(t, "HELLO WORLD, HELLO BEEGO", buf.String())
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	r := &http.Request{Header: http.Header{"Accept-Encoding": {"gzip, br"}}}
+	assert.Equal(t, "br", NegotiateEncoding(r, "br", "gzip"))
+	assert.Equal(t, "gzip", NegotiateEncoding(r, "gzip", "br"))
+	assert.Equal(t, "", NegotiateEncoding(r, "zstd"))
+	assert.Equal(t, "", NegotiateEncoding(nil, "gzip"))
+
+	r.Header.Set("Accept-Encoding", "br;q=0.2, *;q=0.5")
+	assert.Equal(t, "zstd", NegotiateEncoding(r, "br", "zstd"))
+}
+
+func TestCompressible(t *testing.T) {
+	defer InitCompressTypes(nil, nil)
+
+	assert.True(t, Compressible("image/png"))
+	assert.True(t, Compressible(""))
+
+	InitCompressTypes(nil, []string{"image/*", "application/zip"})
+	assert.False(t, Compressible("image/png"))
+	assert.False(t, Compressible("Application/Zip"))
+	assert.True(t, Compressible("image-png"))
+	assert.True(t, Compressible("text/html; charset=utf-8"))
+	assert.True(t, Compressible(""))
+
+	InitCompressTypes([]string{"text/*", "application/json"}, []string{"text/event-stream"})
+	assert.True(t, Compressible("text/html; charset=utf-8"))
+	assert.True(t, Compressible("application/json"))
+	assert.False(t, Compressible("text/event-stream"))
+	assert.False(t, Compressible("application/octet-stream"))
+	assert.False(t, Compressible(""))
+}
@@ -102,20 +102,18 @@ func (ctx *Context) Bind(obj interface{}) error {
 	}
 }
 
-// Resp sends response based on the Accept Header
-// By default response will be in JSON
+// Resp sends response in the registered format preferred by the Accept Header, see RegisterFormat.
+// By default response will be in JSON.
+// It responds 406 and returns ErrNotAcceptable if no format is acceptable.
 func (ctx *Context) Resp(data interface{}) error {
-	accept := ctx.Input.Header("Accept")
-	switch accept {
-	case ApplicationYAML:
-		return ctx.YamlResp(data)
-	case ApplicationXML, TextXML:
-		return ctx.XMLResp(data)
-	case ApplicationProto:
-		return ctx.ProtoResp(data.(proto.Message))
-	default:
-		return ctx.JSONResp(data)
+	f := ctx.Input.NegotiateFormat()
+	if f == nil {
+		ctx.ResponseWriter.Header().Add("Vary", "Accept")
+		ctx.Output.SetStatus(http.StatusNotAcceptable)
+		_ = ctx.Output.Body([]byte(http.StatusText(http.StatusNotAcceptable)))
+		return ErrNotAcceptable
 	}
+	return ctx.Output.serveFormat(f, data, false, false)
 }
 
 func (ctx *Context) JSONResp(data interface{}) error {
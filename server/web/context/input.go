@@ -19,6 +19,7 @@ import (
 	"compress/gzip"
 	"errors"
 	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
@@ -36,7 +37,7 @@ import (
 var (
 	acceptsHTMLRegex = regexp.MustCompile(`(text/html|application/xhtml\+xml)(?:,|$)`)
 	acceptsXMLRegex  = regexp.MustCompile(`(application/xml|text/xml)(?:,|$)`)
-	acceptsJSONRegex = regexp.MustCompile(`(application/json)(?:,|$)`)
+	acceptsJSONRegex = regexp.MustCompile(`(application/(?:problem\+)?json)(?:,|$)`)
 	acceptsYAMLRegex = regexp.MustCompile(`(application/x-yaml)(?:,|$)`)
 	maxParam         = 50
 )
@@ -53,6 +54,7 @@ type BeegoInput struct {
 	RequestBody   []byte
 	RunMethod     string
 	RunController reflect.Type
+	fwd           *forwarded
 }
 
 // NewInput returns the BeegoInput generated by context.
@@ -74,6 +76,7 @@ func (input *BeegoInput) Reset(ctx *Context) {
 	input.data = nil
 	input.dataLock.Unlock()
 	input.RequestBody = []byte{}
+	input.fwd = nil
 }
 
 // Protocol returns the request protocol name, such as HTTP/1.1 .
@@ -97,17 +100,9 @@ func (input *BeegoInput) Site() string {
 }
 
 // Scheme returns the request scheme as "http" or "https".
+// The scheme forwarded by the trusted proxies is used, see SetTrustedProxies.
 func (input *BeegoInput) Scheme() string {
-	if scheme := input.Header("X-Forwarded-Proto"); scheme != "" {
-		return scheme
-	}
-	if input.Context.Request.URL.Scheme != "" {
-		return input.Context.Request.URL.Scheme
-	}
-	if input.Context.Request.TLS == nil {
-		return "http"
-	}
-	return "https"
+	return input.forwarded().scheme
 }
 
 // Domain returns the host name (alias of host method)
@@ -116,13 +111,14 @@ func (input *BeegoInput) Domain() string {
 }
 
 // Host returns the host name.
+// The host forwarded by the trusted proxies is used, see SetTrustedProxies.
 // If no host info in request, return localhost.
 func (input *BeegoInput) Host() string {
-	if input.Context.Request.Host != "" {
-		if hostPart, _, err := net.SplitHostPort(input.Context.Request.Host); err == nil {
+	if host := input.forwarded().host; host != "" {
+		if hostPart, _, err := net.SplitHostPort(host); err == nil {
 			return hostPart
 		}
-		return input.Context.Request.Host
+		return host
 	}
 	return "localhost"
 }
@@ -213,27 +209,18 @@ func (input *BeegoInput) AcceptsYAML() bool {
 }
 
 // IP returns request client ip.
-// if in proxy, return first proxy id.
-// if error, return RemoteAddr.
+// The forwarding headers of the trusted proxies are walked from the right,
+// the client is the first address which is not a trusted proxy, see SetTrustedProxies.
+// If not in proxy, return the address of the peer.
 func (input *BeegoInput) IP() string {
-	ips := input.Proxy()
-	if len(ips) > 0 && ips[0] != "" {
-		rip, _, err := net.SplitHostPort(ips[0])
-		if err != nil {
-			rip = ips[0]
-		}
-		return rip
-	}
-	if ip, _, err := net.SplitHostPort(input.Context.Request.RemoteAddr); err == nil {
-		return ip
-	}
-	return input.Context.Request.RemoteAddr
+	return input.forwarded().ip
 }
 
-// Proxy returns proxy client ips slice.
+// Proxy returns the addresses forwarded by the trusted proxies,
+// the client ip first, followed by the proxies in front of the nearest one.
 func (input *BeegoInput) Proxy() []string {
-	if ips := input.Header("X-Forwarded-For"); ips != "" {
-		return strings.Split(ips, ",")
+	if proxies := input.forwarded().proxies; proxies != nil {
+		return proxies
 	}
 	return []string{}
 }
@@ -261,7 +248,7 @@ func (input *BeegoInput) SubDomains() string {
 // Port returns request client port.
 // when error or empty, return 80.
 func (input *BeegoInput) Port() int {
-	if _, portPart, err := net.SplitHostPort(input.Context.Request.Host); err == nil {
+	if _, portPart, err := net.SplitHostPort(input.forwarded().host); err == nil {
 		port, _ := strconv.Atoi(portPart)
 		return port
 	}
@@ -432,6 +419,38 @@ func (input *BeegoInput) ParseFormOrMultiForm(maxMemory int64) error {
 	return nil
 }
 
+// MultipartReader returns a reader iterating over the parts of a multipart/form-data body.
+// The body must not have been parsed, see web.WithRouterStreamBody
+func (input *BeegoInput) MultipartReader() (*multipart.Reader, error) {
+	return input.Context.Request.MultipartReader()
+}
+
+// ForEachPart calls fn with the parts of the multipart/form-data body in their order,
+// nothing is buffered in memory or written to temp files.
+// fn must consume the part before returning, eg. io.Copy it to a file, an object storage or a hash,
+// and the iteration stops at the first error returned by fn.
+// A body larger than the max upload size fails with *http.MaxBytesError.
+func (input *BeegoInput) ForEachPart(fn func(part *multipart.Part) error) error {
+	mr, err := input.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
 // Bind data from request.Form[key] to dest
 // like /?id=123&isok=true&ft=1.2&ol[0]=1&ol[1]=2&ul[]=str&ul[]=array&user.Name=astaxie
 // var id int  beegoInput.Bind(&id, "id")  id ==123
@@ -0,0 +1,274 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotAcceptable is returned by Resp when no registered format satisfies the Accept header,
+// the 406 response has been written
+var ErrNotAcceptable = errors.New("no acceptable response format")
+
+// Format is a response format which can be negotiated through the Accept header
+type Format struct {
+	// MediaType identifies the format, eg. application/msgpack
+	MediaType string
+	// Aliases are other media types served by the format, eg. text/xml for XML
+	Aliases []string
+	// ContentType is written in the Content-Type header, MediaType is used if it's empty
+	ContentType string
+	// Marshal encodes the response data
+	Marshal func(data interface{}) ([]byte, error)
+}
+
+func (f *Format) contentType() string {
+	if f.ContentType != "" {
+		return f.ContentType
+	}
+	return f.MediaType
+}
+
+var formats = struct {
+	sync.RWMutex
+	list []*Format
+}{}
+
+func init() {
+	RegisterFormat(&Format{
+		MediaType:   ApplicationJSON,
+		ContentType: "application/json; charset=utf-8",
+		Marshal:     json.Marshal,
+	})
+	RegisterFormat(&Format{
+		MediaType:   ApplicationXML,
+		Aliases:     []string{TextXML},
+		ContentType: "application/xml; charset=utf-8",
+		Marshal:     xml.Marshal,
+	})
+	RegisterFormat(&Format{
+		MediaType:   ApplicationYAML,
+		Aliases:     []string{"application/yaml", "text/yaml"},
+		ContentType: "application/x-yaml; charset=utf-8",
+		Marshal:     yaml.Marshal,
+	})
+	RegisterFormat(&Format{
+		MediaType:   ApplicationProto,
+		ContentType: "application/x-protobuf; charset=utf-8",
+		Marshal: func(data interface{}) ([]byte, error) {
+			msg, ok := data.(proto.Message)
+			if !ok {
+				return nil, fmt.Errorf("%T is not a proto.Message", data)
+			}
+			return proto.Marshal(msg)
+		},
+	})
+}
+
+// RegisterFormat adds a response format, or replaces the one with the same MediaType.
+// When several formats are equally acceptable, the one registered first wins,
+// so JSON is used when the Accept header is missing or is */*.
+// usage:
+//
+//	context.RegisterFormat(&context.Format{
+//		MediaType: "application/msgpack",
+//		Aliases:   []string{"application/x-msgpack"},
+//		Marshal:   msgpack.Marshal,
+//	})
+func RegisterFormat(f *Format) {
+	if f.MediaType == "" || f.Marshal == nil {
+		panic("context: a format needs a media type and a Marshal function")
+	}
+	formats.Lock()
+	defer formats.Unlock()
+	for i, old := range formats.list {
+		if strings.EqualFold(old.MediaType, f.MediaType) {
+			formats.list[i] = f
+			return
+		}
+	}
+	formats.list = append(formats.list, f)
+}
+
+// GetFormat returns the registered format serving mediaType
+func GetFormat(mediaType string) (*Format, bool) {
+	formats.RLock()
+	defer formats.RUnlock()
+	for _, f := range formats.list {
+		if strings.EqualFold(f.MediaType, mediaType) {
+			return f, true
+		}
+		for _, alias := range f.Aliases {
+			if strings.EqualFold(alias, mediaType) {
+				return f, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// AcceptRange is a media range of the Accept header with its quality factor
+type AcceptRange struct {
+	// MediaType may contain wildcards, eg. */* or text/*
+	MediaType string
+	Q         float64
+}
+
+// specificity is 0 for */*, 1 for type/* and 2 for type/subtype
+func (a AcceptRange) specificity() int {
+	switch {
+	case a.MediaType == "*/*":
+		return 0
+	case strings.HasSuffix(a.MediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+func (a AcceptRange) match(mediaType string) bool {
+	switch a.specificity() {
+	case 0:
+		return true
+	case 1:
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(a.MediaType, "*"))
+	}
+	return a.MediaType == mediaType
+}
+
+// ParseAccept parses an Accept header.
+// The ranges are sorted by preference: higher quality first,
+// then the more specific ranges, then in the order of the header.
+// Ranges with an invalid quality factor are ignored.
+func ParseAccept(header string) []AcceptRange {
+	var ranges []AcceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		if !strings.Contains(mediaType, "/") {
+			// some clients send * for */*
+			if mediaType != "*" {
+				continue
+			}
+			mediaType = "*/*"
+		}
+		r := AcceptRange{MediaType: mediaType, Q: 1}
+		valid := true
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.ToLower(strings.TrimSpace(k)) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			r.Q = q
+		}
+		if valid {
+			ranges = append(ranges, r)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].Q != ranges[j].Q {
+			return ranges[i].Q > ranges[j].Q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// NegotiateMediaType returns the offer preferred by the Accept header, "" if none is acceptable.
+// The quality of an offer is given by the most specific range matching it,
+// offers with the same quality are preferred in their order.
+// The first offer is returned when the header is empty or holds no valid media range, like the old clients sending "OTHER".
+func NegotiateMediaType(header string, offers ...string) string {
+	ranges := ParseAccept(header)
+	if len(ranges) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		mt := strings.ToLower(offer)
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := r.specificity(); s > specificity && r.match(mt) {
+				q, specificity = r.Q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// NegotiateFormat returns the registered format preferred by the Accept header, nil if none is acceptable
+func (input *BeegoInput) NegotiateFormat() *Format {
+	formats.RLock()
+	offers := make([]string, 0, len(formats.list))
+	for _, f := range formats.list {
+		offers = append(offers, f.MediaType)
+		offers = append(offers, f.Aliases...)
+	}
+	formats.RUnlock()
+
+	mediaType := NegotiateMediaType(input.Header("Accept"), offers...)
+	if mediaType == "" {
+		return nil
+	}
+	f, _ := GetFormat(mediaType)
+	return f
+}
+
+// Format writes data encoded by f
+func (output *BeegoOutput) Format(f *Format, data interface{}) error {
+	content, err := f.Marshal(data)
+	if err != nil {
+		http.Error(output.Context.ResponseWriter, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	output.Header("Content-Type", f.contentType())
+	return output.Body(content)
+}
+
+// serveFormat writes data encoded by f, the options only apply to the built-in JSON and XML formats
+func (output *BeegoOutput) serveFormat(f *Format, data interface{}, hasIndent bool, hasEncode bool) error {
+	output.Context.ResponseWriter.Header().Add("Vary", "Accept")
+	switch f.MediaType {
+	case ApplicationJSON:
+		return output.JSON(data, hasIndent, hasEncode)
+	case ApplicationXML:
+		return output.XML(data, hasIndent)
+	}
+	return output.Format(f, data)
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccept(t *testing.T) {
+	ranges := ParseAccept("text/*;q=0.5, */*;q=0.1, application/json, text/html;level=1;q=0.5, image/png;q=2, *")
+	assert.Equal(t, []AcceptRange{
+		{MediaType: "application/json", Q: 1},
+		{MediaType: "*/*", Q: 1},
+		{MediaType: "text/html", Q: 0.5},
+		{MediaType: "text/*", Q: 0.5},
+		{MediaType: "*/*", Q: 0.1},
+	}, ranges)
+}
+
+func TestNegotiateMediaType(t *testing.T) {
+	offers := []string{ApplicationJSON, ApplicationXML, TextXML}
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"", ApplicationJSON},
+		{"*/*", ApplicationJSON},
+		{"application/xml", ApplicationXML},
+		{"application/json;q=0.5, application/xml", ApplicationXML},
+		{"text/*", TextXML},
+		{"application/*;q=0.2, text/xml;q=0.9", TextXML},
+		// the specific range wins over the wildcard
+		{"*/*;q=0.9, application/json;q=0", ApplicationXML},
+		{"text/html", ""},
+		{"application/json;q=0", ""},
+		// no valid range, same as no header
+		{"OTHER", ApplicationJSON},
+		{"text/html;q=2", ApplicationJSON},
+	}
+	for _, c := range cases {
+		t.Run(c.accept, func(t *testing.T) {
+			assert.Equal(t, c.want, NegotiateMediaType(c.accept, offers...))
+		})
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat(&Format{
+		MediaType: "text/csv",
+		Aliases:   []string{"application/csv"},
+		Marshal: func(data interface{}) ([]byte, error) {
+			return []byte(fmt.Sprintf("%v\n", data)), nil
+		},
+	})
+	defer func() {
+		formats.Lock()
+		formats.list = formats.list[:len(formats.list)-1]
+		formats.Unlock()
+	}()
+
+	f, ok := GetFormat("application/csv")
+	assert.True(t, ok)
+	assert.Equal(t, "text/csv", f.MediaType)
+
+	cases := []struct {
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"text/csv", http.StatusOK, "text/csv", "a,b\n"},
+		{"application/csv;q=0.9, application/json;q=0.8", http.StatusOK, "text/csv", "a,b\n"},
+		{"application/x-yaml", http.StatusOK, "application/x-yaml; charset=utf-8", "a,b\n"},
+		{"image/png", http.StatusNotAcceptable, "", "Not Acceptable"},
+	}
+	for _, c := range cases {
+		t.Run(c.accept, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", c.accept)
+			w := httptest.NewRecorder()
+			ctx := NewContext()
+			ctx.Reset(w, r)
+
+			err := ctx.Resp("a,b")
+			if c.status == http.StatusNotAcceptable {
+				assert.ErrorIs(t, err, ErrNotAcceptable)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, c.contentType, w.Header().Get("Content-Type"))
+			}
+			assert.Equal(t, c.status, w.Code)
+			assert.Equal(t, c.body, w.Body.String())
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		})
+	}
+
+	// ServeFormatted falls back to JSON
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+	ctx := NewContext()
+	ctx.Reset(w, r)
+	assert.NoError(t, ctx.Output.ServeFormatted("a,b", false))
+	assert.Equal(t, `"a,b"`, w.Body.String())
+}
@@ -60,12 +60,12 @@ func (output *BeegoOutput) Header(key, val string) {
 }
 
 // Body sets the response body content.
-// if EnableGzip, content is compressed.
+// if EnableGzip, content is compressed unless its content type is excluded, see InitCompressTypes.
 // Sends out response body directly.
 func (output *BeegoOutput) Body(content []byte) error {
 	var encoding string
 	buf := &bytes.Buffer{}
-	if output.EnableGzip {
+	if output.EnableGzip && Compressible(output.Context.ResponseWriter.Header().Get("Content-Type")) {
 		encoding = ParseEncoding(output.Context.Request)
 	}
 	if b, n, _ := WriteBody(encoding, buf, content); b {
@@ -281,17 +281,14 @@ func (output *BeegoOutput) XML(data interface{}, hasIndent bool) error {
 	return output.Body(content)
 }
 
-// ServeFormatted serves YAML, XML or JSON, depending on the value of the Accept header
+// ServeFormatted serves the registered format preferred by the Accept header, see RegisterFormat.
+// JSON is served when no format is acceptable.
 func (output *BeegoOutput) ServeFormatted(data interface{}, hasIndent bool, hasEncode ...bool) error {
-	accept := output.Context.Input.Header("Accept")
-	switch accept {
-	case ApplicationYAML:
-		return output.YAML(data)
-	case ApplicationXML, TextXML:
-		return output.XML(data, hasIndent)
-	default:
-		return output.JSON(data, hasIndent, len(hasEncode) > 0 && hasEncode[0])
+	f := output.Context.Input.NegotiateFormat()
+	if f == nil {
+		f, _ = GetFormat(ApplicationJSON)
 	}
+	return output.serveFormat(f, data, hasIndent, len(hasEncode) > 0 && hasEncode[0])
 }
 
 // Download forces response for download file.
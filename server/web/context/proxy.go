@@ -0,0 +1,256 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultTrustedProxies are the loopback and the private networks, where the proxies usually run
+var DefaultTrustedProxies = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+	"::1/128", "fc00::/7",
+}
+
+// trustedProxies are the networks of the proxies whose forwarding headers are trusted
+var trustedProxies = struct {
+	sync.RWMutex
+	nets []*net.IPNet
+}{nets: mustParseCIDRs(DefaultTrustedProxies...)}
+
+// SetTrustedProxies sets the proxies whose Forwarded, X-Forwarded-For, X-Forwarded-Proto
+// and X-Forwarded-Host headers are trusted, as CIDRs or IP addresses.
+// By default the peers of the loopback and the private networks are trusted, see DefaultTrustedProxies,
+// an empty list trusts none of them.
+// The peers without an IP address, eg. on a unix socket, are trusted like the loopback address.
+func SetTrustedProxies(proxies []string) error {
+	nets, err := parseCIDRs(proxies...)
+	if err != nil {
+		return err
+	}
+	trustedProxies.Lock()
+	trustedProxies.nets = nets
+	trustedProxies.Unlock()
+	return nil
+}
+
+func parseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", c)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", c, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets, err := parseCIDRs(cidrs...)
+	if err != nil {
+		panic(err)
+	}
+	return nets
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	trustedProxies.RLock()
+	defer trustedProxies.RUnlock()
+	for _, n := range trustedProxies.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hop is a proxy hop described by a forwarding header
+type hop struct {
+	// addr is the address of the client of the proxy, without port
+	addr  string
+	proto string
+	host  string
+}
+
+// forwarded is the client address, scheme and host resolved through the trusted proxies
+type forwarded struct {
+	// the request resolved, the request of the context may be replaced
+	req     *http.Request
+	ip      string
+	scheme  string
+	host    string
+	proxies []string
+}
+
+// forwarded resolves the request through the forwarding headers of the trusted proxies.
+// The hops are walked from the right, the client is the first address which is not a trusted proxy.
+func (input *BeegoInput) forwarded() *forwarded {
+	r := input.Context.Request
+	if input.fwd != nil && input.fwd.req == r {
+		return input.fwd
+	}
+	f := &forwarded{req: r, ip: stripPort(r.RemoteAddr), scheme: r.URL.Scheme, host: r.Host}
+	if f.scheme == "" {
+		f.scheme = "http"
+		if r.TLS != nil {
+			f.scheme = "https"
+		}
+	}
+	input.fwd = f
+	peer := f.ip
+	if net.ParseIP(peer) == nil {
+		peer = "127.0.0.1"
+	}
+	if !isTrustedProxy(peer) {
+		return f
+	}
+
+	hops := forwardedHops(r.Header)
+	if len(hops) == 0 {
+		return f
+	}
+	i := len(hops) - 1
+	for i > 0 && isTrustedProxy(hops[i].addr) {
+		i--
+	}
+	if hops[i].addr != "" {
+		f.ip = hops[i].addr
+	}
+	if hops[i].proto != "" {
+		f.scheme = strings.ToLower(hops[i].proto)
+	}
+	if hops[i].host != "" {
+		f.host = hops[i].host
+	}
+	for _, h := range hops[i:] {
+		if h.addr != "" {
+			f.proxies = append(f.proxies, h.addr)
+		}
+	}
+	return f
+}
+
+// forwardedHops parses the Forwarded header of RFC 7239, or the X-Forwarded-* headers,
+// X-Real-Ip is used when X-Forwarded-For is missing
+func forwardedHops(header http.Header) []hop {
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		return parseForwarded(values)
+	}
+	fors := splitList(header.Values("X-Forwarded-For"))
+	if len(fors) == 0 {
+		fors = splitList(header.Values("X-Real-Ip"))
+	}
+	protos := splitList(header.Values("X-Forwarded-Proto"))
+	hosts := splitList(header.Values("X-Forwarded-Host"))
+	if len(fors) == 0 {
+		if len(protos) == 0 && len(hosts) == 0 {
+			return nil
+		}
+		// the proxy doesn't forward the address of the client
+		fors = []string{""}
+	}
+	hops := make([]hop, len(fors))
+	for i, f := range fors {
+		hops[i].addr = stripPort(f)
+	}
+	// the proxies usually set X-Forwarded-Proto and X-Forwarded-Host once,
+	// the values are matched to the hops when each proxy appends its own
+	assign := func(values []string, set func(h *hop, v string)) {
+		if len(values) == len(hops) {
+			for i, v := range values {
+				set(&hops[i], v)
+			}
+		} else if len(values) > 0 {
+			for i := range hops {
+				set(&hops[i], values[len(values)-1])
+			}
+		}
+	}
+	assign(protos, func(h *hop, v string) { h.proto = v })
+	assign(hosts, func(h *hop, v string) { h.host = v })
+	return hops
+}
+
+// parseForwarded parses the elements of the Forwarded headers, eg. for=192.0.2.60;proto=https, for="[2001:db8::1]:4711"
+func parseForwarded(values []string) []hop {
+	var hops []hop
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			var h hop
+			for _, pair := range strings.Split(element, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				v = strings.Trim(strings.TrimSpace(v), `"`)
+				switch strings.ToLower(strings.TrimSpace(k)) {
+				case "for":
+					h.addr = stripPort(v)
+				case "proto":
+					h.proto = v
+				case "host":
+					h.host = v
+				}
+			}
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+func splitList(values []string) []string {
+	var res []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				res = append(res, s)
+			}
+		}
+	}
+	return res
+}
+
+// stripPort removes the port and the brackets of an IPv6 address
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
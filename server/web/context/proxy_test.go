@@ -0,0 +1,107 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProxyTestInput(remoteAddr string, header ...string) *BeegoInput {
+	r, _ := http.NewRequest("GET", "http://example.com/", nil)
+	r.RemoteAddr = remoteAddr
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Add(header[i], header[i+1])
+	}
+	ctx := NewContext()
+	ctx.Reset(httptest.NewRecorder(), r)
+	return ctx.Input
+}
+
+func TestTrustedProxies(t *testing.T) {
+	defer func() {
+		require.NoError(t, SetTrustedProxies(DefaultTrustedProxies))
+	}()
+
+	// the loopback and the private networks are trusted by default
+	input := newProxyTestInput("203.0.113.1:1234", "X-Forwarded-For", "1.1.1.1", "X-Forwarded-Proto", "https")
+	assert.Equal(t, "203.0.113.1", input.IP())
+	assert.Equal(t, "http", input.Scheme())
+	input = newProxyTestInput("10.0.0.1:1234", "X-Forwarded-For", "1.1.1.1, 2.2.2.2", "X-Forwarded-Proto", "https")
+	assert.Equal(t, "2.2.2.2", input.IP())
+	assert.Equal(t, []string{"2.2.2.2"}, input.Proxy())
+	assert.Equal(t, "https", input.Scheme())
+	input = newProxyTestInput("[::1]:1234", "X-Forwarded-For", "1.1.1.1, 192.168.0.2")
+	assert.Equal(t, "1.1.1.1", input.IP())
+	// a peer without IP address, eg. on a unix socket, is trusted like the loopback address
+	input = newProxyTestInput("@", "X-Real-Ip", "1.1.1.1")
+	assert.Equal(t, "1.1.1.1", input.IP())
+
+	require.NoError(t, SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"}))
+	assert.Error(t, SetTrustedProxies([]string{"10.0.0.0/33"}))
+	assert.Error(t, SetTrustedProxies([]string{"proxy"}))
+
+	// the headers of the other peers are ignored
+	input = newProxyTestInput("203.0.113.1:1234", "X-Forwarded-For", "1.1.1.1", "X-Forwarded-Proto", "https",
+		"X-Forwarded-Host", "evil.com")
+	assert.Equal(t, "203.0.113.1", input.IP())
+	assert.Empty(t, input.Proxy())
+	assert.Equal(t, "http", input.Scheme())
+	assert.Equal(t, "example.com", input.Host())
+
+	// the chain is walked from the right, the spoofed addresses on the left are ignored
+	input = newProxyTestInput("10.0.0.2:1234", "X-Forwarded-For", "6.6.6.6, 1.1.1.1, 192.168.1.1",
+		"X-Forwarded-Proto", "https", "X-Forwarded-Host", "www.example.com:8443")
+	assert.Equal(t, "1.1.1.1", input.IP())
+	assert.Equal(t, []string{"1.1.1.1", "192.168.1.1"}, input.Proxy())
+	assert.Equal(t, "https", input.Scheme())
+	assert.Equal(t, "www.example.com", input.Host())
+	assert.Equal(t, 8443, input.Port())
+	assert.Equal(t, "https://www.example.com", input.Site())
+
+	// only trusted proxies
+	input = newProxyTestInput("10.0.0.2:1234", "X-Forwarded-For", "10.0.0.3")
+	assert.Equal(t, "10.0.0.3", input.IP())
+
+	input = newProxyTestInput("10.0.0.2:1234", "X-Real-Ip", "1.1.1.1")
+	assert.Equal(t, "1.1.1.1", input.IP())
+	input = newProxyTestInput("10.0.0.2:1234", "X-Forwarded-Proto", "https")
+	assert.Equal(t, "10.0.0.2", input.IP())
+	assert.Equal(t, "https", input.Scheme())
+	assert.Empty(t, input.Proxy())
+}
+
+func TestForwardedHeader(t *testing.T) {
+	defer func() {
+		require.NoError(t, SetTrustedProxies(DefaultTrustedProxies))
+	}()
+	require.NoError(t, SetTrustedProxies([]string{"10.0.0.0/8", "2001:db8:cafe::/48"}))
+
+	input := newProxyTestInput("[2001:db8:cafe::1]:443",
+		"Forwarded", `for=6.6.6.6;proto=http, for="[2001:db8::17]:4711";proto=https;host=api.example.com`,
+		"Forwarded", `for=10.0.0.5;proto=http;by=10.0.0.6`,
+		// ignored when Forwarded is present
+		"X-Forwarded-For", "7.7.7.7")
+	assert.Equal(t, "2001:db8::17", input.IP())
+	assert.Equal(t, []string{"2001:db8::17", "10.0.0.5"}, input.Proxy())
+	assert.Equal(t, "https", input.Scheme())
+	assert.Equal(t, "api.example.com", input.Host())
+
+	assert.Equal(t, []hop{{addr: "unknown"}, {proto: "https"}}, parseForwarded([]string{"for=unknown", "proto=https"}))
+}
@@ -0,0 +1,235 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TextEventStream is the mime-type of server-sent events
+const TextEventStream = "text/event-stream"
+
+const defaultSSEHeartbeat = 15 * time.Second
+
+// ErrSSEClosed is returned when writing to a closed event stream
+var ErrSSEClosed = errors.New("sse: stream closed")
+
+// SSEEvent is a single server-sent event.
+// Empty fields are not sent.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// String formats the event in the text/event-stream wire format
+func (e *SSEEvent) String() string {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + sseField(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + sseField(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	if e.Data != "" {
+		data := strings.ReplaceAll(e.Data, "\r\n", "\n")
+		for _, line := range strings.Split(data, "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// sseField strips line breaks which would end the field early
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// SSEOption configures the SSEWriter
+type SSEOption func(w *SSEWriter)
+
+// WithSSEHeartbeat sets the interval of the keep-alive comments,
+// a non-positive value disables them
+func WithSSEHeartbeat(interval time.Duration) SSEOption {
+	return func(w *SSEWriter) {
+		w.heartbeat = interval
+	}
+}
+
+// SSEWriter writes server-sent events to the client.
+// It is safe for concurrent use.
+type SSEWriter struct {
+	ctx       *Context
+	heartbeat time.Duration
+
+	mu      sync.Mutex
+	err     error
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// SSE starts a server-sent events stream on the response.
+// Compression is disabled for the stream, and the caller must Close it before
+// the handler returns.
+func (ctx *Context) SSE(opts ...SSEOption) *SSEWriter {
+	w := &SSEWriter{
+		ctx:       ctx,
+		heartbeat: defaultSSEHeartbeat,
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	ctx.Output.EnableGzip = false
+	header := ctx.ResponseWriter.Header()
+	header.Set("Content-Type", TextEventStream)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// disable response buffering in nginx
+	header.Set("X-Accel-Buffering", "no")
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+
+	status := ctx.Output.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ctx.ResponseWriter.WriteHeader(status)
+	ctx.Output.Status = 0
+	ctx.ResponseWriter.Flush()
+
+	go w.keepAlive()
+	return w
+}
+
+func (w *SSEWriter) keepAlive() {
+	defer close(w.stopped)
+	var tick <-chan time.Time
+	if w.heartbeat > 0 {
+		ticker := time.NewTicker(w.heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.Done():
+			w.mu.Lock()
+			if w.err == nil {
+				w.err = ErrSSEClosed
+			}
+			w.mu.Unlock()
+			return
+		case <-tick:
+			if w.write(": heartbeat\n\n") != nil {
+				return
+			}
+		}
+	}
+}
+
+func (w *SSEWriter) write(s string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	if w.ctx.Request.Context().Err() != nil {
+		w.err = ErrSSEClosed
+		return w.err
+	}
+	if _, err := io.WriteString(w.ctx.ResponseWriter, s); err != nil {
+		w.err = err
+		return err
+	}
+	w.ctx.ResponseWriter.Flush()
+	return nil
+}
+
+// Send writes the event to the client and flushes it
+func (w *SSEWriter) Send(e *SSEEvent) error {
+	return w.write(e.String())
+}
+
+// Event sends data as the named event
+func (w *SSEWriter) Event(event string, data string) error {
+	return w.Send(&SSEEvent{Event: event, Data: data})
+}
+
+// Data sends an unnamed event, which triggers the client's onmessage
+func (w *SSEWriter) Data(data string) error {
+	return w.Send(&SSEEvent{Data: data})
+}
+
+// JSON sends v encoded as JSON as the named event
+func (w *SSEWriter) JSON(event string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.Send(&SSEEvent{Event: event, Data: string(content)})
+}
+
+// ID sets the client's last event id without dispatching an event
+func (w *SSEWriter) ID(id string) error {
+	return w.write("id: " + sseField(id) + "\n\n")
+}
+
+// Retry tells the client how long to wait before reconnecting
+func (w *SSEWriter) Retry(d time.Duration) error {
+	return w.write("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n")
+}
+
+// Done returns a channel which is closed when the client disconnects
+func (w *SSEWriter) Done() <-chan struct{} {
+	return w.ctx.Request.Context().Done()
+}
+
+// Err returns the error which stopped the stream, if any
+func (w *SSEWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Close stops the heartbeats, later writes return ErrSSEClosed.
+// It does not close the underlying connection.
+func (w *SSEWriter) Close() {
+	w.once.Do(func() {
+		close(w.stop)
+		<-w.stopped
+		w.mu.Lock()
+		if w.err == nil {
+			w.err = ErrSSEClosed
+		}
+		w.mu.Unlock()
+	})
+}
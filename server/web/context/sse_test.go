@@ -0,0 +1,97 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"bufio"
+	context2 "context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEEvent_String(t *testing.T) {
+	e := &SSEEvent{ID: "1\n", Event: "progress", Data: "line1\r\nline2", Retry: 3 * time.Second}
+	assert.Equal(t, "id: 1\nevent: progress\nretry: 3000\ndata: line1\ndata: line2\n\n", e.String())
+	assert.Equal(t, "data: ok\n\n", (&SSEEvent{Data: "ok"}).String())
+}
+
+func TestContext_SSE(t *testing.T) {
+	rw := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	c := NewContext()
+	c.Reset(rw, r)
+	c.Output.EnableGzip = true
+
+	sse := c.SSE(WithSSEHeartbeat(0))
+	assert.Nil(t, sse.Event("progress", "50"))
+	assert.Nil(t, sse.ID("42"))
+	assert.Nil(t, sse.Retry(time.Second))
+	assert.Nil(t, sse.JSON("done", map[string]int{"progress": 100}))
+	sse.Close()
+	assert.Equal(t, ErrSSEClosed, sse.Data("late"))
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, TextEventStream, rw.Header().Get("Content-Type"))
+	assert.Equal(t, "", rw.Header().Get("Content-Encoding"))
+	assert.False(t, c.Output.EnableGzip)
+	assert.Equal(t, "event: progress\ndata: 50\n\nid: 42\n\nretry: 1000\n\nevent: done\ndata: {\"progress\":100}\n\n", rw.Body.String())
+}
+
+func TestContext_SSEDisconnect(t *testing.T) {
+	connected := make(chan struct{})
+	finished := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		c := NewContext()
+		c.Reset(rw, r)
+		sse := c.SSE(WithSSEHeartbeat(10 * time.Millisecond))
+		defer sse.Close()
+		close(connected)
+		<-sse.Done()
+		finished <- sse.Data("gone")
+	}))
+	defer server.Close()
+
+	reqCtx, cancel := context2.WithCancel(context2.Background())
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept", TextEventStream)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	<-connected
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(line, ": heartbeat"))
+
+	cancel()
+	resp.Body.Close()
+	select {
+	case err = <-finished:
+		assert.Equal(t, ErrSSEClosed, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not notified of the disconnect")
+	}
+}
+
+func TestParseEncodingEventStream(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Accept", TextEventStream)
+	assert.Equal(t, "", ParseEncoding(r))
+}
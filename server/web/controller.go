@@ -401,6 +401,10 @@ func (c *Controller) CustomAbort(status int, body string) {
 	if _, ok := ErrorMaps[body]; ok {
 		panic(body)
 	}
+	if acceptsProblem(BConfig, c.Ctx) {
+		WriteProblem(c.Ctx, NewProblem(status, body))
+		panic(ErrAbort)
+	}
 	// last panic user string
 	c.Ctx.ResponseWriter.WriteHeader(status)
 	c.Ctx.ResponseWriter.Write([]byte(body))
@@ -436,8 +440,8 @@ func (c *Controller) YamlResp(data interface{}) error {
 	return c.Ctx.YamlResp(data)
 }
 
-// Resp sends response based on the Accept Header
-// By default response will be in JSON
+// Resp sends response based on the Accept Header, see context.RegisterFormat
+// By default response will be in JSON, 406 is sent if no format is acceptable
 // it's different from ServeXXX methods
 // because we don't store the data to Data field
 func (c *Controller) Resp(data interface{}) error {
@@ -471,13 +475,21 @@ func (c *Controller) ServeYAML() error {
 	return c.Ctx.Output.YAML(c.Data["yaml"])
 }
 
-// ServeFormatted serve YAML, XML OR JSON, depending on the value of the Accept header
+// ServeFormatted serve the registered format preferred by the Accept header, JSON by default
 func (c *Controller) ServeFormatted(encoding ...bool) error {
 	hasIndent := BConfig.RunMode != PROD
 	hasEncoding := len(encoding) > 0 && encoding[0]
 	return c.Ctx.Output.ServeFormatted(c.Data, hasIndent, hasEncoding)
 }
 
+// ServeSSE starts a server-sent events stream and runs handler on it.
+// The stream is closed when handler returns.
+func (c *Controller) ServeSSE(handler func(sse *context.SSEWriter) error, opts ...context.SSEOption) error {
+	sse := c.Ctx.SSE(opts...)
+	defer sse.Close()
+	return handler(sse)
+}
+
 // Input returns the input data map from POST or PUT request body and query string.
 func (c *Controller) Input() (url.Values, error) {
 	if c.Ctx.Request.Form == nil {
@@ -280,10 +280,22 @@ func (t *TestRespController) TestSaveToFile() {
 	t.Ctx.WriteString("save file success")
 }
 
+func (t *TestRespController) TestServeSSE() {
+	_ = t.ServeSSE(func(sse *context.SSEWriter) error {
+		for i := 1; i <= 2; i++ {
+			if err := sse.Event("progress", strconv.Itoa(i*50)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, context.WithSSEHeartbeat(0))
+}
+
 type respTestCase struct {
 	Accept                string
 	ExpectedContentLength int64
 	ExpectedResponse      string
+	ExpectedStatus        int
 }
 
 func TestControllerResp(t *testing.T) {
@@ -293,6 +305,9 @@ func TestControllerResp(t *testing.T) {
 		{Accept: context.ApplicationXML, ExpectedContentLength: 21, ExpectedResponse: `<S><foo>bar</foo></S>`},
 		{Accept: context.ApplicationYAML, ExpectedContentLength: 9, ExpectedResponse: "foo: bar\n"},
 		{Accept: "OTHER", ExpectedContentLength: 13, ExpectedResponse: `{"foo":"bar"}`},
+		{Accept: "text/html, application/*;q=0.5", ExpectedContentLength: 13, ExpectedResponse: `{"foo":"bar"}`},
+		{Accept: "application/json;q=0.2, text/xml;q=0.8", ExpectedContentLength: 21, ExpectedResponse: `<S><foo>bar</foo></S>`},
+		{Accept: "text/html", ExpectedContentLength: 14, ExpectedResponse: "Not Acceptable", ExpectedStatus: http.StatusNotAcceptable},
 	}
 
 	for _, tc := range tcs {
@@ -316,7 +331,10 @@ func testControllerRespTestCases(t *testing.T, tc respTestCase) {
 		t.Errorf("TestResponse() unable to validate content length %d for %s", response.ContentLength, tc.Accept)
 	}
 
-	if response.StatusCode != http.StatusOK {
+	if tc.ExpectedStatus == 0 {
+		tc.ExpectedStatus = http.StatusOK
+	}
+	if response.StatusCode != tc.ExpectedStatus {
 		t.Errorf("TestResponse() failed to validate response code for %s", tc.Accept)
 	}
 
@@ -398,3 +416,19 @@ func TestControllerSaveFile(t *testing.T) {
 		t.Errorf("TestSaveToFile() failed to validate response code for %s", context.ApplicationJSON)
 	}
 }
+
+func TestControllerServeSSE(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/events", nil)
+	r.Header.Set("Accept", context.TextEventStream)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	handler := NewControllerRegister()
+	handler.Add("/events", &TestRespController{}, WithRouterMethods(&TestRespController{}, "get:TestServeSSE"))
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, context.TextEventStream, w.Header().Get("Content-Type"))
+	assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "event: progress\ndata: 50\n\nevent: progress\ndata: 100\n\n", w.Body.String())
+}
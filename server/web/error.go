@@ -427,6 +427,7 @@ func Exception(errCode uint64, ctx *context.Context) {
 
 // show error string as simple text message.
 // if error string is empty, show 503 or 500 error as default.
+// If EnableProblemJSON is true and the request accepts JSON, a problem+json is written instead.
 func exception(errCode string, ctx *context.Context) {
 	atoi := func(code string) int {
 		v, err := strconv.Atoi(code)
@@ -439,6 +440,18 @@ func exception(errCode string, ctx *context.Context) {
 		return ctx.Output.Status
 	}
 
+	if acceptsProblem(BConfig, ctx) {
+		status := atoi(errCode)
+		p := NewProblem(status, "")
+		if strconv.Itoa(status) != errCode {
+			// the name of a custom error, eg. Abort("dbError")
+			p.Detail = errCode
+		}
+		LogAccess(ctx, nil, status)
+		WriteProblem(ctx, p)
+		return
+	}
+
 	for _, ec := range []string{errCode, "503", "500"} {
 		if h, ok := ErrorMaps[ec]; ok {
 			executeError(h, ctx, atoi(ec))
@@ -37,6 +37,11 @@
 //		beego.InsertFilter("*", beego.BeforeRouter, authz.NewAuthorizer(e))
 //		beego.Run()
 //	}
+//
+// The subject can be taken from another authentication, eg. the sub claim of a JSON Web Token:
+//
+//	beego.InsertFilter("*", beego.BeforeRouter, jwt.NewAuthenticator(keys))
+//	beego.InsertFilter("*", beego.BeforeRouter, authz.NewAuthorizer(e, authz.WithSubject(jwt.Subject)))
 package authz
 
 import (
@@ -48,13 +53,31 @@ import (
 	"github.com/asish-tom/beego/v2/server/web/context"
 )
 
+// Option configures the authorizer
+type Option func(a *BasicAuthorizer)
+
+// WithSubject sets the function returning the subject checked against the policies,
+// the username of the HTTP basic authentication is used by default.
+// For example, jwt.Subject returns the sub claim of the token verified by the jwt filter.
+func WithSubject(f func(ctx *context.Context) string) Option {
+	return func(a *BasicAuthorizer) {
+		a.subject = f
+	}
+}
+
 // NewAuthorizer returns the authorizer.
 // Use a casbin enforcer as input
-func NewAuthorizer(e *casbin.Enforcer) web.FilterFunc {
+func NewAuthorizer(e *casbin.Enforcer, opts ...Option) web.FilterFunc {
+	a := &BasicAuthorizer{enforcer: e}
+	for _, opt := range opts {
+		opt(a)
+	}
 	return func(ctx *context.Context) {
-		a := &BasicAuthorizer{enforcer: e}
-
-		if !a.CheckPermission(ctx.Request) {
+		user := a.GetUserName(ctx.Request)
+		if a.subject != nil {
+			user = a.subject(ctx)
+		}
+		if !a.enforcer.Enforce(user, ctx.Request.URL.Path, ctx.Request.Method) {
 			a.RequirePermission(ctx.ResponseWriter)
 		}
 	}
@@ -63,6 +86,7 @@ func NewAuthorizer(e *casbin.Enforcer) web.FilterFunc {
 // BasicAuthorizer stores the casbin handler
 type BasicAuthorizer struct {
 	enforcer *casbin.Enforcer
+	subject  func(ctx *context.Context) string
 }
 
 // GetUserName gets the user name from the request.
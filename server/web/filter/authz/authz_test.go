@@ -15,6 +15,9 @@
 package authz
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -24,6 +27,7 @@ import (
 	"github.com/asish-tom/beego/v2/server/web"
 	"github.com/asish-tom/beego/v2/server/web/context"
 	"github.com/asish-tom/beego/v2/server/web/filter/auth"
+	"github.com/asish-tom/beego/v2/server/web/filter/jwt"
 )
 
 func testRequest(t *testing.T, handler *web.ControllerRegister, user string, path string, method string, code int) {
@@ -107,3 +111,34 @@ func TestRBAC(t *testing.T) {
 	testRequest(t, handler, "cathy", "/dataset2/item", "POST", 403)
 	testRequest(t, handler, "cathy", "/dataset2/item", "DELETE", 403)
 }
+
+func TestJWTSubject(t *testing.T) {
+	secret := []byte("secret")
+	handler := web.NewControllerRegister()
+	handler.InsertFilter("*", web.BeforeRouter, jwt.NewAuthenticator(jwt.StaticKeys{"": secret}))
+	handler.InsertFilter("*", web.BeforeRouter, NewAuthorizer(casbin.NewEnforcer("authz_model.conf", "authz_policy.csv"), WithSubject(jwt.Subject)))
+	handler.Any("*", func(ctx *context.Context) {
+		ctx.Output.SetStatus(200)
+	})
+
+	sign := func(sub string) string {
+		enc := base64.RawURLEncoding
+		signed := enc.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." + enc.EncodeToString([]byte(`{"sub":"`+sub+`"}`))
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		return signed + "." + enc.EncodeToString(mac.Sum(nil))
+	}
+	request := func(sub, path, method string, code int) {
+		r, _ := http.NewRequest(method, path, nil)
+		r.Header.Set("Authorization", "Bearer "+sign(sub))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Errorf("%s, %s, %s: %d, supposed to be %d", sub, path, method, w.Code, code)
+		}
+	}
+	request("alice", "/dataset1/resource1", "GET", 200)
+	request("alice", "/dataset1/resource2", "POST", 403)
+	request("bob", "/dataset2/resource1", "DELETE", 200)
+	request("bob", "/dataset1/resource1", "GET", 403)
+}
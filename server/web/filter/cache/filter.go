@@ -0,0 +1,603 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache provides a FilterChain caching the responses of GET and HEAD requests
+// in any client/cache adapter.
+//
+// Usage:
+//
+//	import(
+//		"github.com/asish-tom/beego/v2/client/cache"
+//		"github.com/asish-tom/beego/v2/server/web"
+//		respcache "github.com/asish-tom/beego/v2/server/web/filter/cache"
+//	)
+//
+//	func main(){
+//		bm, _ := cache.NewCache("memory", `{"interval":60}`)
+//		builder := respcache.NewFilterChainBuilder(bm,
+//			respcache.WithTTL(5*time.Minute),
+//			respcache.WithKey(respcache.KeyMethod, respcache.KeyPath, respcache.KeyQuery, respcache.KeyHeader("Accept")))
+//		web.InsertFilterChain("/api/products/*", builder.FilterChain)
+//
+//		// the handlers tag their responses
+//		respcache.Tag(ctx, "product:"+id)
+//		// and the tagged responses are dropped when the data change
+//		builder.Invalidate(ctx.Request.Context(), "product:"+id)
+//		web.Run()
+//	}
+//
+// The requests carrying an Authorization header or a session id get their own entries,
+// so the personalized responses are never served to other users.
+// The Cache-Control directives of the request and of the response are honoured,
+// the responses varying on request headers are stored once per variant,
+// and ETag and Last-Modified are added so that conditional requests get 304.
+// The per-request headers, eg. X-Request-ID or Date, and the hop-by-hop headers aren't stored, see WithExcludedHeaders.
+package cache
+
+import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asish-tom/beego/v2/client/cache"
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+// tagsDataKey is the key of the tags added by Tag in the context data
+const tagsDataKey = "filter_cache_tags"
+
+// KeyPart computes a part of the cache key from the request
+type KeyPart func(ctx *context.Context) string
+
+// KeyMethod is the method of the request, HEAD requests share the entries of GET requests
+func KeyMethod(ctx *context.Context) string {
+	if ctx.Request.Method == http.MethodHead {
+		return http.MethodGet
+	}
+	return ctx.Request.Method
+}
+
+// KeyPath is the path of the request
+func KeyPath(ctx *context.Context) string {
+	return ctx.Request.URL.Path
+}
+
+// KeyQuery is the query of the request, the parameters are sorted
+func KeyQuery(ctx *context.Context) string {
+	return ctx.Request.URL.Query().Encode()
+}
+
+// KeyHeader returns the KeyPart of the values of the request headers
+func KeyHeader(names ...string) KeyPart {
+	return func(ctx *context.Context) string {
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			values = append(values, name+"="+strings.Join(ctx.Request.Header.Values(name), ","))
+		}
+		return strings.Join(values, "&")
+	}
+}
+
+// KeySession returns the KeyPart of a session value, eg. the user id,
+// so that every user gets its own entries.
+// The filter runs before the router starts the session,
+// so the session must be started by a previous FilterChain, see filter/session
+func KeySession(name interface{}) KeyPart {
+	return func(ctx *context.Context) string {
+		if ctx.Input.CruSession == nil {
+			return ""
+		}
+		v := ctx.Input.Session(name)
+		if v == nil {
+			return ""
+		}
+		return cache.GetString(v)
+	}
+}
+
+// Tag adds tags to the response of the request, see FilterChainBuilder.Invalidate
+func Tag(ctx *context.Context, tags ...string) {
+	old, _ := ctx.Input.GetData(tagsDataKey).([]string)
+	ctx.Input.SetData(tagsDataKey, append(old, tags...))
+}
+
+// Option configures FilterChainBuilder
+type Option func(b *FilterChainBuilder)
+
+// WithTTL sets how long the responses without max-age are cached
+func WithTTL(ttl time.Duration) Option {
+	return func(b *FilterChainBuilder) {
+		b.ttl = ttl
+	}
+}
+
+// WithKey sets the parts of the cache key, the default parts are KeyMethod, KeyPath and KeyQuery.
+// The credentials of the request are always added, see FilterChain
+func WithKey(parts ...KeyPart) Option {
+	return func(b *FilterChainBuilder) {
+		b.keyParts = parts
+	}
+}
+
+// WithPrefix sets the prefix of the keys stored in the cache
+func WithPrefix(prefix string) Option {
+	return func(b *FilterChainBuilder) {
+		b.prefix = prefix
+	}
+}
+
+// WithTags adds tags to every response cached by the filter, see Tag
+func WithTags(tags func(ctx *context.Context) []string) Option {
+	return func(b *FilterChainBuilder) {
+		b.tags = tags
+	}
+}
+
+// WithStatusCodes sets the status codes of the responses which can be cached,
+// the default codes are 200, 203, 204, 300, 301, 404 and 410
+func WithStatusCodes(codes ...int) Option {
+	return func(b *FilterChainBuilder) {
+		b.statusCodes = make(map[int]bool, len(codes))
+		for _, code := range codes {
+			b.statusCodes[code] = true
+		}
+	}
+}
+
+// WithMaxBodySize sets the size of the largest body which can be cached,
+// larger responses are streamed to the client
+func WithMaxBodySize(size int) Option {
+	return func(b *FilterChainBuilder) {
+		b.maxBodySize = size
+	}
+}
+
+// WithExcludedHeaders adds response headers which aren't stored, eg. the header of the request id
+// when it isn't X-Request-ID. The headers of the current request are sent with the cached responses.
+func WithExcludedHeaders(names ...string) Option {
+	return func(b *FilterChainBuilder) {
+		for _, name := range names {
+			b.excludedHeaders[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// FilterChainBuilder builds the FilterChain caching the responses
+type FilterChainBuilder struct {
+	cache       cache.Cache
+	ttl         time.Duration
+	tagTTL      time.Duration
+	prefix      string
+	keyParts    []KeyPart
+	tags        func(ctx *context.Context) []string
+	statusCodes map[int]bool
+	maxBodySize int
+	// excludedHeaders are the canonical names of the headers which aren't stored
+	excludedHeaders map[string]bool
+}
+
+// defaultExcludedHeaders are the per-request and the hop-by-hop headers
+var defaultExcludedHeaders = []string{
+	"Age", "Connection", "Date", "Keep-Alive", "Proxy-Authenticate", "Proxy-Connection",
+	"Set-Cookie", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "X-Request-Id",
+}
+
+// NewFilterChainBuilder returns a FilterChainBuilder storing the responses in c
+func NewFilterChainBuilder(c cache.Cache, opts ...Option) *FilterChainBuilder {
+	b := &FilterChainBuilder{
+		cache:    c,
+		ttl:      time.Minute,
+		tagTTL:   24 * time.Hour,
+		prefix:   "beego:resp:",
+		keyParts: []KeyPart{KeyMethod, KeyPath, KeyQuery},
+		statusCodes: map[int]bool{
+			http.StatusOK:                   true,
+			http.StatusNonAuthoritativeInfo: true,
+			http.StatusNoContent:            true,
+			http.StatusMultipleChoices:      true,
+			http.StatusMovedPermanently:     true,
+			http.StatusNotFound:             true,
+			http.StatusGone:                 true,
+		},
+		maxBodySize:     1 << 20,
+		excludedHeaders: make(map[string]bool, len(defaultExcludedHeaders)),
+	}
+	for _, name := range defaultExcludedHeaders {
+		b.excludedHeaders[name] = true
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// entry is a cached response.
+// The entry stored under the key of a request whose response varies on request headers
+// only holds Vary, the response is stored under the key of the variant.
+type entry struct {
+	Status int               `json:"status,omitempty"`
+	Header http.Header       `json:"header,omitempty"`
+	Body   []byte            `json:"body,omitempty"`
+	Time   time.Time         `json:"time"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	Vary   []string          `json:"vary,omitempty"`
+}
+
+// FilterChain serves the GET and HEAD requests from the cache, the other requests are passed to next
+func (b *FilterChainBuilder) FilterChain(next web.FilterFunc) web.FilterFunc {
+	return func(ctx *context.Context) {
+		r := ctx.Request
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(ctx)
+			return
+		}
+		reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+		if reqCC.has("no-store") {
+			next(ctx)
+			return
+		}
+
+		key := b.key(ctx)
+		if !reqCC.has("no-cache") && reqCC["max-age"] != "0" {
+			if e := b.lookup(r.Context(), key, r); e != nil {
+				b.serve(ctx, e)
+				return
+			}
+		}
+
+		rw := ctx.ResponseWriter.ResponseWriter
+		rec := &recorder{ResponseWriter: rw, limit: b.maxBodySize}
+		ctx.ResponseWriter.ResponseWriter = rec
+		defer func() {
+			ctx.ResponseWriter.ResponseWriter = rw
+		}()
+		next(ctx)
+		if rec.passthrough {
+			return
+		}
+		ctx.ResponseWriter.ResponseWriter = rw
+
+		if rec.status == 0 && rec.body.Len() == 0 {
+			return
+		}
+		e := &entry{
+			Status: rec.status,
+			Header: rw.Header(),
+			Body:   rec.body.Bytes(),
+			Time:   time.Now(),
+		}
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+		if ttl, ok := b.cacheable(e, r); ok {
+			addValidators(e)
+			if err := b.store(ctx, key, e, ttl); err != nil {
+				logs.Warn("cache the response of %s failed: %v", r.URL.Path, err)
+			}
+		}
+		write(ctx, e)
+	}
+}
+
+// Invalidate drops the responses tagged with tags
+func (b *FilterChainBuilder) Invalidate(ctx gocontext.Context, tags ...string) error {
+	version := strconv.FormatInt(time.Now().UnixNano(), 36)
+	for _, tag := range tags {
+		if err := b.cache.Put(ctx, b.tagKey(tag), version, b.tagTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *FilterChainBuilder) key(ctx *context.Context) string {
+	parts := make([]string, 0, len(b.keyParts))
+	for _, part := range b.keyParts {
+		parts = append(parts, part(ctx))
+	}
+	if c := credentials(ctx.Request); c != "" {
+		parts = append(parts, c)
+	}
+	return b.hashKey(strings.Join(parts, "\n"))
+}
+
+// credentials returns the Authorization header and the session id of the request.
+// They are a part of every key, so the responses personalized for a user are never served to another one.
+func credentials(r *http.Request) string {
+	var parts []string
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		parts = append(parts, "authorization="+auth)
+	}
+	sess := web.BConfig.WebConfig.Session
+	if c, err := r.Cookie(sess.SessionName); err == nil && c.Value != "" {
+		parts = append(parts, "session="+c.Value)
+	}
+	if sess.SessionEnableSidInHTTPHeader {
+		if sid := r.Header.Get(sess.SessionNameInHTTPHeader); sid != "" {
+			parts = append(parts, "session="+sid)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func (b *FilterChainBuilder) hashKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return b.prefix + hex.EncodeToString(sum[:16])
+}
+
+func (b *FilterChainBuilder) tagKey(tag string) string {
+	return b.prefix + "tag:" + tag
+}
+
+// variantKey returns the key of the response varying on the headers of the request
+func (b *FilterChainBuilder) variantKey(key string, vary []string, r *http.Request) string {
+	var sb strings.Builder
+	sb.WriteString(key)
+	for _, name := range vary {
+		sb.WriteString("\n" + name + ":" + strings.Join(r.Header.Values(name), ","))
+	}
+	return b.hashKey(sb.String())
+}
+
+// lookup returns the fresh entry of the request, nil if there's none
+func (b *FilterChainBuilder) lookup(ctx gocontext.Context, key string, r *http.Request) *entry {
+	e := b.get(ctx, key)
+	if e != nil && len(e.Vary) > 0 {
+		e = b.get(ctx, b.variantKey(key, e.Vary, r))
+	}
+	if e == nil || !b.validTags(ctx, e) {
+		return nil
+	}
+	return e
+}
+
+func (b *FilterChainBuilder) get(ctx gocontext.Context, key string) *entry {
+	v, err := b.cache.Get(ctx, key)
+	if err != nil || v == nil {
+		return nil
+	}
+	e := &entry{}
+	if err = json.Unmarshal([]byte(cache.GetString(v)), e); err != nil {
+		logs.Warn("decode the cached response %s failed: %v", key, err)
+		return nil
+	}
+	return e
+}
+
+// validTags reports whether none of the tags of e has been invalidated since e was stored
+func (b *FilterChainBuilder) validTags(ctx gocontext.Context, e *entry) bool {
+	if len(e.Tags) == 0 {
+		return true
+	}
+	tags := make([]string, 0, len(e.Tags))
+	keys := make([]string, 0, len(e.Tags))
+	for tag := range e.Tags {
+		tags = append(tags, tag)
+		keys = append(keys, b.tagKey(tag))
+	}
+	// missing keys are nil
+	versions, _ := b.cache.GetMulti(ctx, keys)
+	if len(versions) != len(keys) {
+		return false
+	}
+	for i, tag := range tags {
+		if versions[i] == nil || cache.GetString(versions[i]) != e.Tags[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheable returns how long the response can be cached
+func (b *FilterChainBuilder) cacheable(e *entry, r *http.Request) (time.Duration, bool) {
+	if r.Method != http.MethodGet || !b.statusCodes[e.Status] || e.Header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	cc := parseCacheControl(e.Header.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") || cc.has("no-cache") {
+		return 0, false
+	}
+	for _, name := range e.Header.Values("Vary") {
+		if strings.TrimSpace(name) == "*" {
+			return 0, false
+		}
+	}
+	ttl := b.ttl
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[directive]; ok {
+			seconds, err := strconv.Atoi(v)
+			if err != nil {
+				continue
+			}
+			ttl = time.Duration(seconds) * time.Second
+			break
+		}
+	}
+	return ttl, ttl > 0
+}
+
+func (b *FilterChainBuilder) store(ctx *context.Context, key string, e *entry, ttl time.Duration) error {
+	c := ctx.Request.Context()
+	tags := b.entryTags(ctx)
+	if len(tags) > 0 {
+		e.Tags = make(map[string]string, len(tags))
+		for _, tag := range tags {
+			version, err := b.tagVersion(c, tag)
+			if err != nil {
+				return err
+			}
+			e.Tags[tag] = version
+		}
+	}
+
+	vary := varyHeaders(e.Header)
+	if len(vary) > 0 {
+		data, err := json.Marshal(&entry{Vary: vary, Time: e.Time})
+		if err != nil {
+			return err
+		}
+		if err = b.cache.Put(c, key, string(data), ttl); err != nil {
+			return err
+		}
+		key = b.variantKey(key, vary, ctx.Request)
+	}
+	stored := *e
+	stored.Header = make(http.Header, len(e.Header))
+	for k, v := range e.Header {
+		if !b.excludedHeaders[k] {
+			stored.Header[k] = v
+		}
+	}
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	return b.cache.Put(c, key, string(data), ttl)
+}
+
+func (b *FilterChainBuilder) entryTags(ctx *context.Context) []string {
+	var tags []string
+	if b.tags != nil {
+		tags = append(tags, b.tags(ctx)...)
+	}
+	if v, ok := ctx.Input.GetData(tagsDataKey).([]string); ok {
+		tags = append(tags, v...)
+	}
+	return tags
+}
+
+// tagVersion returns the current version of tag, it's created if the tag is unknown
+func (b *FilterChainBuilder) tagVersion(ctx gocontext.Context, tag string) (string, error) {
+	if v, err := b.cache.Get(ctx, b.tagKey(tag)); err == nil && v != nil {
+		return cache.GetString(v), nil
+	}
+	version := strconv.FormatInt(time.Now().UnixNano(), 36)
+	return version, b.cache.Put(ctx, b.tagKey(tag), version, b.tagTTL)
+}
+
+// serve writes the cached response
+func (b *FilterChainBuilder) serve(ctx *context.Context, e *entry) {
+	header := ctx.ResponseWriter.Header()
+	for k, v := range e.Header {
+		// the headers of the current request are kept
+		if !b.excludedHeaders[k] {
+			header[k] = append([]string(nil), v...)
+		}
+	}
+	header.Set("Age", strconv.Itoa(int(time.Since(e.Time).Seconds())))
+	write(ctx, e)
+}
+
+// write writes the response, or 304 if the request is conditional and the response has not changed.
+// The status written by the handler into the recorder is replaced.
+func write(ctx *context.Context, e *entry) {
+	status := e.Status
+	if status == http.StatusOK && notModified(ctx.Request, e.Header) {
+		status = http.StatusNotModified
+		ctx.ResponseWriter.Header().Del("Content-Length")
+	}
+	ctx.ResponseWriter.Status = status
+	ctx.ResponseWriter.Started = true
+	ctx.ResponseWriter.ResponseWriter.WriteHeader(status)
+	if status != http.StatusNotModified && ctx.Request.Method != http.MethodHead {
+		_, _ = ctx.ResponseWriter.ResponseWriter.Write(e.Body)
+	}
+}
+
+// addValidators adds ETag and Last-Modified if the handler didn't set them
+func addValidators(e *entry) {
+	if e.Header.Get("ETag") == "" {
+		sum := sha256.Sum256(e.Body)
+		e.Header.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	}
+	if e.Header.Get("Last-Modified") == "" {
+		e.Header.Set("Last-Modified", e.Time.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there's no If-None-Match
+func notModified(r *http.Request, header http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := strings.TrimPrefix(header.Get("ETag"), "W/")
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(header.Get("Last-Modified"))
+	return err == nil && !lm.After(ims)
+}
+
+// varyHeaders returns the canonical names of the request headers the response varies on.
+// Accept-Encoding is added if the response is encoded.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			add(name)
+		}
+	}
+	if header.Get("Content-Encoding") != "" {
+		add("Accept-Encoding")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cacheControl holds the directives of a Cache-Control header, the directives without argument map to ""
+type cacheControl map[string]string
+
+func parseCacheControl(header string) cacheControl {
+	cc := cacheControl{}
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(directive, "=")
+		cc[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	gocontext "context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/client/cache"
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+	"github.com/asish-tom/beego/v2/server/web/filter/requestid"
+)
+
+func newTestHandler(t *testing.T, opts ...Option) (*web.ControllerRegister, *FilterChainBuilder, *int) {
+	bm, err := cache.NewCache("memory", `{"interval":60}`)
+	require.NoError(t, err)
+	builder := NewFilterChainBuilder(bm, opts...)
+
+	calls := 0
+	handler := web.NewControllerRegister()
+	handler.InsertFilterChain("/*", builder.FilterChain)
+	handler.Get("/products/:id", func(ctx *context.Context) {
+		calls++
+		Tag(ctx, "product:"+ctx.Input.Param(":id"))
+		ctx.Output.Body([]byte("product " + ctx.Input.Param(":id") + " #" + strconv.Itoa(calls)))
+	})
+	handler.Get("/private", func(ctx *context.Context) {
+		calls++
+		ctx.Output.Header("Cache-Control", "private")
+		ctx.Output.Body([]byte(strconv.Itoa(calls)))
+	})
+	handler.Get("/negotiated", func(ctx *context.Context) {
+		calls++
+		ctx.Output.Header("Vary", "Accept")
+		ctx.Output.Body([]byte(ctx.Input.Header("Accept") + strconv.Itoa(calls)))
+	})
+	handler.Get("/error", func(ctx *context.Context) {
+		calls++
+		ctx.Output.SetStatus(http.StatusInternalServerError)
+		ctx.Output.Body([]byte(strconv.Itoa(calls)))
+	})
+	handler.Post("/products/:id", func(ctx *context.Context) {
+		calls++
+		ctx.Output.Body([]byte("created"))
+	})
+	handler.Init()
+	return handler, builder, &calls
+}
+
+func do(handler http.Handler, method, url string, header ...string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestFilterChain(t *testing.T) {
+	handler, builder, calls := newTestHandler(t)
+
+	w := do(handler, http.MethodGet, "/products/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "product 1 #1", w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+
+	w = do(handler, http.MethodGet, "/products/1")
+	assert.Equal(t, "product 1 #1", w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, "0", w.Header().Get("Age"))
+	assert.Equal(t, 1, *calls)
+
+	// the query is a part of the key
+	w = do(handler, http.MethodGet, "/products/1?lang=en")
+	assert.Equal(t, "product 1 #2", w.Body.String())
+
+	// HEAD is served from the GET entry
+	w = do(handler, http.MethodHead, "/products/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, 2, *calls)
+
+	// conditional requests
+	w = do(handler, http.MethodGet, "/products/1", "If-None-Match", `"other", `+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	w = do(handler, http.MethodGet, "/products/1", "If-Modified-Since", w.Header().Get("Last-Modified"))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// the request asks for a fresh response
+	w = do(handler, http.MethodGet, "/products/1", "Cache-Control", "no-cache")
+	assert.Equal(t, "product 1 #3", w.Body.String())
+	w = do(handler, http.MethodGet, "/products/1")
+	assert.Equal(t, "product 1 #3", w.Body.String())
+	w = do(handler, http.MethodGet, "/products/1", "Cache-Control", "no-store")
+	assert.Equal(t, "product 1 #4", w.Body.String())
+
+	// invalidation by tag
+	require.NoError(t, builder.Invalidate(gocontext.Background(), "product:1"))
+	w = do(handler, http.MethodGet, "/products/1")
+	assert.Equal(t, "product 1 #5", w.Body.String())
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	w = do(handler, http.MethodGet, "/products/1")
+	assert.Equal(t, "product 1 #5", w.Body.String())
+
+	w = do(handler, http.MethodPost, "/products/1")
+	assert.Equal(t, "created", w.Body.String())
+	assert.Equal(t, 6, *calls)
+}
+
+func TestFilterChainNotCached(t *testing.T) {
+	handler, _, calls := newTestHandler(t)
+
+	for i := 1; i <= 2; i++ {
+		w := do(handler, http.MethodGet, "/private")
+		assert.Equal(t, strconv.Itoa(i), w.Body.String())
+	}
+	for i := 3; i <= 4; i++ {
+		w := do(handler, http.MethodGet, "/error")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, strconv.Itoa(i), w.Body.String())
+	}
+	assert.Equal(t, 4, *calls)
+}
+
+func TestFilterChainVary(t *testing.T) {
+	handler, _, calls := newTestHandler(t)
+
+	assert.Equal(t, "a1", do(handler, http.MethodGet, "/negotiated", "Accept", "a").Body.String())
+	assert.Equal(t, "b2", do(handler, http.MethodGet, "/negotiated", "Accept", "b").Body.String())
+	assert.Equal(t, "b2", do(handler, http.MethodGet, "/negotiated", "Accept", "b").Body.String())
+	// each variant has its own entry
+	assert.Equal(t, "a1", do(handler, http.MethodGet, "/negotiated", "Accept", "a").Body.String())
+	assert.Equal(t, "c3", do(handler, http.MethodGet, "/negotiated", "Accept", "c").Body.String())
+	assert.Equal(t, 3, *calls)
+}
+
+func TestFilterChainKey(t *testing.T) {
+	handler, _, calls := newTestHandler(t,
+		WithKey(KeyPath, KeyHeader("X-Tenant")),
+		WithMaxBodySize(len("product 1 #1")))
+
+	assert.Equal(t, "product 1 #1", do(handler, http.MethodGet, "/products/1?a=1", "X-Tenant", "t1").Body.String())
+	assert.Equal(t, "product 1 #1", do(handler, http.MethodGet, "/products/1?a=2", "X-Tenant", "t1").Body.String())
+	assert.Equal(t, "product 1 #2", do(handler, http.MethodGet, "/products/1", "X-Tenant", "t2").Body.String())
+	assert.Equal(t, 2, *calls)
+
+	// too large to be cached
+	for i := 3; i <= 12; i++ {
+		body := do(handler, http.MethodGet, "/products/22").Body.String()
+		assert.True(t, strings.HasSuffix(body, strconv.Itoa(i)))
+	}
+}
+
+func TestFilterChainCredentials(t *testing.T) {
+	handler, _, calls := newTestHandler(t)
+
+	assert.Equal(t, "product 1 #1", do(handler, http.MethodGet, "/products/1", "Authorization", "Bearer alice").Body.String())
+	assert.Equal(t, "product 1 #1", do(handler, http.MethodGet, "/products/1", "Authorization", "Bearer alice").Body.String())
+	// neither another user nor an anonymous one get the response of alice
+	assert.Equal(t, "product 1 #2", do(handler, http.MethodGet, "/products/1", "Authorization", "Bearer bob").Body.String())
+	assert.Equal(t, "product 1 #3", do(handler, http.MethodGet, "/products/1").Body.String())
+
+	cookie := web.BConfig.WebConfig.Session.SessionName + "="
+	assert.Equal(t, "product 1 #4", do(handler, http.MethodGet, "/products/1", "Cookie", cookie+"s1").Body.String())
+	assert.Equal(t, "product 1 #4", do(handler, http.MethodGet, "/products/1", "Cookie", cookie+"s1").Body.String())
+	assert.Equal(t, "product 1 #5", do(handler, http.MethodGet, "/products/1", "Cookie", cookie+"s2").Body.String())
+	// the other cookies are not credentials
+	assert.Equal(t, "product 1 #3", do(handler, http.MethodGet, "/products/1", "Cookie", "theme=dark").Body.String())
+	assert.Equal(t, 5, *calls)
+}
+
+func TestParseCacheControl(t *testing.T) {
+	cc := parseCacheControl(`public, max-age=60, s-maxage="120", No-Store`)
+	assert.Equal(t, cacheControl{"public": "", "max-age": "60", "s-maxage": "120", "no-store": ""}, cc)
+	assert.True(t, cc.has("no-store"))
+	assert.False(t, cc.has("private"))
+}
+
+func TestFilterChainRequestID(t *testing.T) {
+	bm, err := cache.NewCache("memory", `{"interval":60}`)
+	require.NoError(t, err)
+	handler := web.NewControllerRegister()
+	// the request id is set before the cache filter runs, so that the cached responses get it too
+	setID := requestid.NewFilter()
+	handler.InsertFilterChain("/*", func(next web.FilterFunc) web.FilterFunc {
+		return func(ctx *context.Context) {
+			setID(ctx)
+			next(ctx)
+		}
+	})
+	handler.InsertFilterChain("/*", NewFilterChainBuilder(bm).FilterChain)
+	handler.Get("/products", func(ctx *context.Context) {
+		ctx.Output.Header("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+		ctx.Output.Header("X-Custom", "kept")
+		_ = ctx.Output.Body([]byte("products"))
+	})
+	handler.Init()
+
+	w := do(handler, "GET", "/products", requestid.HeaderName, "first")
+	assert.Equal(t, "first", w.Header().Get(requestid.HeaderName))
+
+	w = do(handler, "GET", "/products", requestid.HeaderName, "second")
+	assert.Equal(t, "products", w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("Age"))
+	assert.Equal(t, "second", w.Header().Get(requestid.HeaderName))
+	assert.Equal(t, "kept", w.Header().Get("X-Custom"))
+	assert.Empty(t, w.Header().Get("Date"))
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// recorder buffers the response of the handler.
+// When the body exceeds the limit, or when the handler flushes or hijacks the connection,
+// the buffered response is written and the rest passes through, it's not cached.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	limit       int
+	passthrough bool
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.passthrough {
+		r.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if r.status == 0 {
+		r.status = code
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if !r.passthrough && r.body.Len()+len(p) > r.limit {
+		r.bypass()
+	}
+	if r.passthrough {
+		return r.ResponseWriter.Write(p)
+	}
+	return r.body.Write(p)
+}
+
+// bypass writes the buffered response and stops buffering
+func (r *recorder) bypass() {
+	if r.passthrough {
+		return
+	}
+	r.passthrough = true
+	if r.status != 0 {
+		r.ResponseWriter.WriteHeader(r.status)
+	}
+	if r.body.Len() > 0 {
+		_, _ = r.ResponseWriter.Write(r.body.Bytes())
+		r.body.Reset()
+	}
+}
+
+func (r *recorder) Flush() {
+	r.bypass()
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("webserver doesn't support hijacking")
+	}
+	r.passthrough = true
+	return hj.Hijack()
+}
@@ -0,0 +1,304 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package csrf provides a filter protecting the unsafe requests against cross-site request forgery.
+// It works for the controllers as well as for the functional routes, and doesn't rely on the XSRF config.
+//
+// The token is stored in a signed cookie (double-submit cookie),
+// the unsafe requests must send it back in the X-CSRF-Token header or in the _csrf form field.
+// When the sessions are enabled, the signature covers the session id: a cookie planted by a compromised subdomain,
+// or issued for another session, is rejected, and a new token is issued after the session id changes, eg. on login.
+// Without the sessions it is a plain double-submit cookie, which doesn't protect against the subdomains.
+//
+// The form field is read from the form parsed by the router, limited by MaxMemory and MaxUploadSize,
+// so the requests to the routes with a streamed body, or filtered at BeforeStatic, must send the header.
+// When the Origin or the Referer header is sent, it must match the host of the request or a trusted origin.
+// Usage:
+//
+//	import(
+//		"github.com/asish-tom/beego/v2"
+//		"github.com/asish-tom/beego/v2/server/web/filter/csrf"
+//	)
+//
+//	func main(){
+//		beego.InsertFilter("*", beego.BeforeRouter, csrf.NewProtector([]byte("32-byte-long-secret"),
+//			csrf.WithExemptPaths("/webhooks/*"),
+//			csrf.WithTrustedOrigins("https://app.example.com")))
+//		beego.Run()
+//	}
+//
+// The forms send the token returned by Token:
+//
+//	c.Data["csrf"] = csrf.Token(c.Ctx)
+//	<input type="hidden" name="_csrf" value="{{.csrf}}">
+//
+// A single page application reads the cookie, which is not HttpOnly, and sends it in the X-CSRF-Token header.
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+// TokenKey is the key of the token in the input data
+const TokenKey = "CSRFToken"
+
+// the reasons of the rejections, passed to the error handler
+var (
+	ErrTokenMissing   = errors.New("csrf: missing token")
+	ErrTokenInvalid   = errors.New("csrf: invalid token")
+	ErrOriginMismatch = errors.New("csrf: origin doesn't match")
+	ErrRefererMissing = errors.New("csrf: missing referer")
+)
+
+// Option configures the protector
+type Option func(p *protector)
+
+// WithHeaderName sets the header holding the token, the default is X-CSRF-Token
+func WithHeaderName(name string) Option {
+	return func(p *protector) {
+		p.header = name
+	}
+}
+
+// WithFieldName sets the form field holding the token, the default is _csrf.
+// An empty name only accepts the header.
+func WithFieldName(name string) Option {
+	return func(p *protector) {
+		p.field = name
+	}
+}
+
+// WithCookie sets the attributes of the cookie storing the token:
+// Name, Path, Domain, MaxAge, Secure, HttpOnly and SameSite.
+// The default cookie is _csrf on path /, lasting for the browser session with SameSite=Lax.
+func WithCookie(c http.Cookie) Option {
+	return func(p *protector) {
+		p.cookie = c
+	}
+}
+
+// WithExemptPaths skips the requests whose path is one of paths,
+// a path ending with * is a prefix, eg. /webhooks/*
+func WithExemptPaths(paths ...string) Option {
+	return func(p *protector) {
+		p.exemptPaths = append(p.exemptPaths, paths...)
+	}
+}
+
+// WithExemptFunc skips the requests for which f returns true, eg. the requests authenticated with a bearer token
+func WithExemptFunc(f func(ctx *context.Context) bool) Option {
+	return func(p *protector) {
+		p.exemptFuncs = append(p.exemptFuncs, f)
+	}
+}
+
+// WithTrustedOrigins allows the unsafe requests sent by other origins, eg. https://app.example.com
+func WithTrustedOrigins(origins ...string) Option {
+	return func(p *protector) {
+		for _, o := range origins {
+			p.trustedOrigins = append(p.trustedOrigins, strings.TrimSuffix(strings.ToLower(o), "/"))
+		}
+	}
+}
+
+// WithErrorHandler replaces the handler of the rejected requests, which sends 403
+func WithErrorHandler(h func(ctx *context.Context, err error)) Option {
+	return func(p *protector) {
+		p.errorHandler = h
+	}
+}
+
+type protector struct {
+	secret         []byte
+	header         string
+	field          string
+	cookie         http.Cookie
+	exemptPaths    []string
+	exemptFuncs    []func(ctx *context.Context) bool
+	trustedOrigins []string
+	errorHandler   func(ctx *context.Context, err error)
+}
+
+// NewProtector returns the filter checking the token of the unsafe requests,
+// secret signs the cookies and must be shared by the instances of the application.
+// The token of the request is stored in the input data, see Token.
+func NewProtector(secret []byte, opts ...Option) web.FilterFunc {
+	p := &protector{
+		secret: secret,
+		header: "X-CSRF-Token",
+		field:  "_csrf",
+		cookie: http.Cookie{
+			Name:     "_csrf",
+			Path:     "/",
+			SameSite: http.SameSiteLaxMode,
+		},
+		errorHandler: defaultErrorHandler,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p.filter
+}
+
+// Token returns the token of the request, it must be sent back by the unsafe requests
+func Token(ctx *context.Context) string {
+	token, _ := ctx.Input.GetData(TokenKey).(string)
+	return token
+}
+
+func (p *protector) filter(ctx *context.Context) {
+	if p.exempt(ctx) {
+		return
+	}
+	sid := sessionID(ctx)
+	token := ""
+	if c, err := ctx.Request.Cookie(p.cookie.Name); err == nil && p.valid(c.Value, sid) {
+		token = c.Value
+	}
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		if token == "" {
+			token = p.newToken(sid)
+			c := p.cookie
+			c.Value = token
+			http.SetCookie(ctx.ResponseWriter, &c)
+		}
+		ctx.Input.SetData(TokenKey, token)
+		return
+	}
+
+	if err := p.checkOrigin(ctx); err != nil {
+		p.errorHandler(ctx, err)
+		return
+	}
+	sent := p.sentToken(ctx)
+	if token == "" || sent == "" {
+		p.errorHandler(ctx, ErrTokenMissing)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(sent)) != 1 {
+		p.errorHandler(ctx, ErrTokenInvalid)
+		return
+	}
+	ctx.Input.SetData(TokenKey, token)
+}
+
+func (p *protector) exempt(ctx *context.Context) bool {
+	path := ctx.Request.URL.Path
+	for _, e := range p.exemptPaths {
+		if prefix, ok := strings.CutSuffix(e, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == e {
+			return true
+		}
+	}
+	for _, f := range p.exemptFuncs {
+		if f(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin compares the Origin header, or the Referer header of the HTTPS requests, to the origin of the request
+func (p *protector) checkOrigin(ctx *context.Context) error {
+	origin := ctx.Input.Header("Origin")
+	if origin == "" {
+		referer := ctx.Input.Header("Referer")
+		if referer == "" {
+			// the browsers always send the Referer over HTTPS, unless a proxy or the policy removes it
+			if ctx.Input.IsSecure() {
+				return ErrRefererMissing
+			}
+			return nil
+		}
+		u, err := url.Parse(referer)
+		if err != nil {
+			return ErrOriginMismatch
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+	origin = strings.ToLower(origin)
+	if origin == strings.ToLower(ctx.Input.Scheme()+"://"+ctx.Request.Host) {
+		return nil
+	}
+	for _, o := range p.trustedOrigins {
+		if origin == o {
+			return nil
+		}
+	}
+	return ErrOriginMismatch
+}
+
+func (p *protector) sentToken(ctx *context.Context) string {
+	if token := ctx.Input.Header(p.header); token != "" {
+		return token
+	}
+	if p.field == "" {
+		return ""
+	}
+	// the router has parsed the form with its limits, parsing it here would use the default limit of 32MB
+	if ctx.Request.PostForm == nil {
+		return ""
+	}
+	return ctx.Request.PostForm.Get(p.field)
+}
+
+// sessionID returns the id of the session started by the router, empty when the sessions are disabled
+func sessionID(ctx *context.Context) string {
+	if ctx.Input.CruSession == nil {
+		return ""
+	}
+	return ctx.Input.CruSession.SessionID(ctx.Request.Context())
+}
+
+// newToken returns a random value and its signature bound to the session sid
+func (p *protector) newToken(sid string) string {
+	buf := make([]byte, 32)
+	_, _ = rand.Read(buf)
+	value := base64.RawURLEncoding.EncodeToString(buf)
+	return value + "." + p.sign(value, sid)
+}
+
+// valid checks the signature of the token for the session sid
+func (p *protector) valid(token, sid string) bool {
+	value, sig, ok := strings.Cut(token, ".")
+	return ok && hmac.Equal([]byte(sig), []byte(p.sign(value, sid)))
+}
+
+func (p *protector) sign(value, sid string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(value))
+	mac.Write([]byte{0})
+	mac.Write([]byte(sid))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func defaultErrorHandler(ctx *context.Context, err error) {
+	ctx.ResponseWriter.WriteHeader(http.StatusForbidden)
+	_, _ = ctx.ResponseWriter.Write([]byte("403 Forbidden: " + strings.TrimPrefix(err.Error(), "csrf: ") + "\n"))
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+	"github.com/asish-tom/beego/v2/server/web/session"
+)
+
+func newTestHandler(opts ...Option) *web.ControllerRegister {
+	handler := web.NewControllerRegister()
+	handler.InsertFilter("*", web.BeforeRouter, NewProtector([]byte("secret"), opts...))
+	handler.Get("/form", func(ctx *context.Context) {
+		_ = ctx.Output.Body([]byte(Token(ctx)))
+	})
+	handler.Post("/form", func(ctx *context.Context) {
+		_ = ctx.Output.Body([]byte("ok"))
+	})
+	handler.Post("/webhooks/github", func(ctx *context.Context) {
+		_ = ctx.Output.Body([]byte("ok"))
+	})
+	return handler
+}
+
+// getToken returns the token and the cookie set by a GET request
+func getToken(t *testing.T, handler http.Handler) (string, *http.Cookie) {
+	r, _ := http.NewRequest("GET", "/form", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "_csrf", cookies[0].Name)
+	assert.Equal(t, cookies[0].Value, w.Body.String())
+	return w.Body.String(), cookies[0]
+}
+
+func post(handler http.Handler, cookie *http.Cookie, body string, header ...string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("POST", "http://example.com/form", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestProtector(t *testing.T) {
+	handler := newTestHandler()
+	token, cookie := getToken(t, handler)
+
+	// the cookie is reused
+	r, _ := http.NewRequest("GET", "/form", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Empty(t, w.Result().Cookies())
+	assert.Equal(t, token, w.Body.String())
+
+	assert.Equal(t, "ok", post(handler, cookie, "", "X-CSRF-Token", token).Body.String())
+	assert.Equal(t, "ok", post(handler, cookie, "_csrf="+url.QueryEscape(token)).Body.String())
+
+	w = post(handler, cookie, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "403 Forbidden: missing token\n", w.Body.String())
+	w = post(handler, nil, "", "X-CSRF-Token", token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = post(handler, cookie, "", "X-CSRF-Token", token+"x")
+	assert.Equal(t, "403 Forbidden: invalid token\n", w.Body.String())
+
+	// a cookie which is not signed with the secret is ignored
+	forged := &http.Cookie{Name: "_csrf", Value: "value.signature"}
+	w = post(handler, forged, "", "X-CSRF-Token", forged.Value)
+	assert.Equal(t, "403 Forbidden: missing token\n", w.Body.String())
+
+	handler = newTestHandler(WithFieldName(""), WithHeaderName("X-XSRF-Token"))
+	token, cookie = getToken(t, handler)
+	assert.Equal(t, http.StatusForbidden, post(handler, cookie, "_csrf="+url.QueryEscape(token)).Code)
+	assert.Equal(t, "ok", post(handler, cookie, "", "X-XSRF-Token", token).Body.String())
+}
+
+func TestProtectorOrigin(t *testing.T) {
+	handler := newTestHandler(WithTrustedOrigins("https://app.example.com/"))
+	token, cookie := getToken(t, handler)
+
+	w := post(handler, cookie, "", "X-CSRF-Token", token, "Origin", "http://example.com")
+	assert.Equal(t, "ok", w.Body.String())
+	w = post(handler, cookie, "", "X-CSRF-Token", token, "Origin", "https://app.example.com")
+	assert.Equal(t, "ok", w.Body.String())
+	w = post(handler, cookie, "", "X-CSRF-Token", token, "Origin", "https://evil.com")
+	assert.Equal(t, "403 Forbidden: origin doesn't match\n", w.Body.String())
+	w = post(handler, cookie, "", "X-CSRF-Token", token, "Referer", "https://evil.com/page")
+	assert.Equal(t, "403 Forbidden: origin doesn't match\n", w.Body.String())
+	w = post(handler, cookie, "", "X-CSRF-Token", token, "Referer", "http://example.com/form")
+	assert.Equal(t, "ok", w.Body.String())
+
+	// the HTTPS requests must have a Referer
+	w = post(handler, cookie, "", "X-CSRF-Token", token, "X-Forwarded-Proto", "https")
+	assert.Equal(t, "403 Forbidden: missing referer\n", w.Body.String())
+	w = post(handler, cookie, "", "X-CSRF-Token", token, "X-Forwarded-Proto", "https", "Referer", "https://example.com/form")
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestProtectorExempt(t *testing.T) {
+	handler := newTestHandler(WithExemptPaths("/webhooks/*"), WithExemptFunc(func(ctx *context.Context) bool {
+		return strings.HasPrefix(ctx.Input.Header("Authorization"), "Bearer ")
+	}), WithErrorHandler(func(ctx *context.Context, err error) {
+		ctx.ResponseWriter.WriteHeader(http.StatusTeapot)
+	}))
+
+	r, _ := http.NewRequest("POST", "/webhooks/github", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "ok", w.Body.String())
+
+	assert.Equal(t, "ok", post(handler, nil, "", "Authorization", "Bearer token").Body.String())
+	assert.Equal(t, http.StatusTeapot, post(handler, nil, "").Code)
+}
+
+func TestProtectorSession(t *testing.T) {
+	manager, err := session.NewManager("memory", &session.ManagerConfig{CookieName: "sid", EnableSetCookie: true, Gclifetime: 3600})
+	require.NoError(t, err)
+	handler := web.NewControllerRegister()
+	// starts the session before the protector, like the router does
+	handler.InsertFilter("*", web.BeforeRouter, func(ctx *context.Context) {
+		ctx.Input.CruSession, _ = manager.SessionStart(ctx.ResponseWriter, ctx.Request)
+	})
+	handler.InsertFilter("*", web.BeforeRouter, NewProtector([]byte("secret")))
+	handler.Get("/form", func(ctx *context.Context) {
+		_ = ctx.Output.Body([]byte(Token(ctx)))
+	})
+	handler.Post("/form", func(ctx *context.Context) {
+		_ = ctx.Output.Body([]byte("ok"))
+	})
+	request := func(method string, cookies []*http.Cookie, token string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(method, "/form", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		r.Header.Set("X-CSRF-Token", token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request("GET", nil, "")
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 2)
+	token := w.Body.String()
+	assert.Equal(t, "ok", request("POST", cookies, token).Body.String())
+
+	// the token of another session is rejected
+	w = request("GET", nil, "")
+	other := w.Result().Cookies()
+	var sid, csrf *http.Cookie
+	for _, c := range other {
+		if c.Name == "sid" {
+			sid = c
+		}
+	}
+	for _, c := range cookies {
+		if c.Name == "_csrf" {
+			csrf = c
+		}
+	}
+	w = request("POST", []*http.Cookie{sid, csrf}, token)
+	assert.Equal(t, "403 Forbidden: missing token\n", w.Body.String())
+
+	// and replaced on the next safe request
+	w = request("GET", []*http.Cookie{sid, csrf}, "")
+	assert.NotEqual(t, token, w.Body.String())
+	assert.Equal(t, "ok", request("POST", []*http.Cookie{sid, w.Result().Cookies()[0]}, w.Body.String()).Body.String())
+}
+
+func TestProtectorMultipart(t *testing.T) {
+	handler := newTestHandler()
+	token, cookie := getToken(t, handler)
+	body := "--b\r\nContent-Disposition: form-data; name=\"_csrf\"\r\n\r\n" + token + "\r\n--b--\r\n"
+	w := post(handler, cookie, body, "Content-Type", "multipart/form-data; boundary=b")
+	assert.Equal(t, "ok", w.Body.String())
+}
@@ -0,0 +1,427 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package idempotency provides a FilterChain making the unsafe requests idempotent
+// when the clients send an Idempotency-Key header.
+//
+// The first request with a key is processed and its response is stored in a client/cache adapter,
+// the retries with the same key get the stored response instead of being processed again.
+// A retry sent while the first request is in flight gets 409,
+// a request reusing a key with another method, path or body gets 422.
+// The keys are scoped by the credentials of the clients, see WithScope.
+//
+// Usage:
+//
+//	bm, _ := cache.NewCache("redis", `{"conn":"127.0.0.1:6379"}`)
+//	builder := idempotency.NewFilterChainBuilder(bm, idempotency.WithTTL(24*time.Hour))
+//	web.InsertFilterChain("/api/payments/*", builder.FilterChain)
+//
+// Adapters shared by several instances don't provide an atomic "set if absent",
+// so two duplicates received at the same time by two instances may both be processed.
+// Inside one instance the duplicates are always serialized.
+package idempotency
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asish-tom/beego/v2/client/cache"
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+// HeaderReplayed is set on the stored responses sent to the retries
+const HeaderReplayed = "Idempotent-Replayed"
+
+const (
+	stateProcessing = "processing"
+	stateCompleted  = "completed"
+)
+
+// Option configures FilterChainBuilder
+type Option func(b *FilterChainBuilder)
+
+// WithHeader sets the name of the header holding the key, the default is Idempotency-Key
+func WithHeader(name string) Option {
+	return func(b *FilterChainBuilder) {
+		b.header = name
+	}
+}
+
+// WithMethods sets the methods which are made idempotent, the default methods are POST and PATCH
+func WithMethods(methods ...string) Option {
+	return func(b *FilterChainBuilder) {
+		b.methods = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			b.methods[strings.ToUpper(m)] = true
+		}
+	}
+}
+
+// WithTTL sets how long the responses are stored, the default is 24 hours
+func WithTTL(ttl time.Duration) Option {
+	return func(b *FilterChainBuilder) {
+		b.ttl = ttl
+	}
+}
+
+// WithLockTTL sets how long a request is considered in flight, the default is 1 minute.
+// It should be longer than the slowest request, the lock is released when a crashed instance doesn't.
+func WithLockTTL(ttl time.Duration) Option {
+	return func(b *FilterChainBuilder) {
+		b.lockTTL = ttl
+	}
+}
+
+// WithScope sets the function computing the scope of the keys, eg. the id of the user or of the API client,
+// so that the clients can't read the responses of each other.
+// The default scope is made of the Authorization header and the session id of the request,
+// the anonymous clients share the same scope.
+func WithScope(scope func(ctx *context.Context) string) Option {
+	return func(b *FilterChainBuilder) {
+		b.scope = scope
+	}
+}
+
+// WithPrefix sets the prefix of the keys stored in the cache
+func WithPrefix(prefix string) Option {
+	return func(b *FilterChainBuilder) {
+		b.prefix = prefix
+	}
+}
+
+// WithMaxBodySize sets the size of the largest request body, the default is 1MB.
+// The larger requests get 413.
+func WithMaxBodySize(size int64) Option {
+	return func(b *FilterChainBuilder) {
+		b.maxBodySize = size
+	}
+}
+
+// WithMaxResponseSize sets the size of the largest response which can be stored, the default is 1MB.
+// The retries of a request whose response is larger get 409, they are not processed again.
+func WithMaxResponseSize(size int) Option {
+	return func(b *FilterChainBuilder) {
+		b.maxResponseSize = size
+	}
+}
+
+// FilterChainBuilder builds the FilterChain storing the responses of the requests with an idempotency key
+type FilterChainBuilder struct {
+	cache       cache.Cache
+	header      string
+	methods     map[string]bool
+	ttl         time.Duration
+	lockTTL     time.Duration
+	scope       func(ctx *context.Context) string
+	prefix      string
+	maxBodySize int64
+	// the size of the largest response which can be stored
+	maxResponseSize int
+
+	// the keys of the requests in flight in this instance
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// NewFilterChainBuilder returns a FilterChainBuilder storing the responses in c
+func NewFilterChainBuilder(c cache.Cache, opts ...Option) *FilterChainBuilder {
+	b := &FilterChainBuilder{
+		cache:           c,
+		header:          "Idempotency-Key",
+		methods:         map[string]bool{http.MethodPost: true, http.MethodPatch: true},
+		ttl:             24 * time.Hour,
+		lockTTL:         time.Minute,
+		scope:           defaultScope,
+		prefix:          "beego:idempotency:",
+		maxBodySize:     1 << 20,
+		maxResponseSize: 1 << 20,
+		inFlight:        make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// record is stored under the idempotency key
+type record struct {
+	State       string      `json:"state"`
+	Fingerprint string      `json:"fingerprint"`
+	Token       string      `json:"token,omitempty"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	// the response was too large to be stored
+	TooLarge bool `json:"too_large,omitempty"`
+}
+
+// FilterChain processes the requests with a new idempotency key and replays the stored responses
+func (b *FilterChainBuilder) FilterChain(next web.FilterFunc) web.FilterFunc {
+	return func(ctx *context.Context) {
+		r := ctx.Request
+		idempotencyKey := r.Header.Get(b.header)
+		if !b.methods[r.Method] || idempotencyKey == "" {
+			next(ctx)
+			return
+		}
+		if len(idempotencyKey) > 255 {
+			reject(ctx, http.StatusBadRequest, b.header+" is longer than 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, b.maxBodySize+1))
+		if err != nil {
+			reject(ctx, http.StatusBadRequest, "read request body failed")
+			return
+		}
+		if int64(len(body)) > b.maxBodySize {
+			reject(ctx, http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := b.key(ctx, idempotencyKey)
+		fingerprint := fingerprint(r, body)
+		c := r.Context()
+
+		if rec := b.get(c, key); rec != nil {
+			b.replay(ctx, rec, fingerprint)
+			return
+		}
+		if !b.lock(key) {
+			reject(ctx, http.StatusConflict, "a request with the same "+b.header+" is in progress")
+			return
+		}
+		defer b.unlock(key)
+		// the first request may have completed meanwhile
+		if rec := b.get(c, key); rec != nil {
+			b.replay(ctx, rec, fingerprint)
+			return
+		}
+
+		token := newToken()
+		if !b.acquire(c, key, fingerprint, token) {
+			// another instance won the race
+			if rec := b.get(c, key); rec != nil {
+				b.replay(ctx, rec, fingerprint)
+				return
+			}
+			reject(ctx, http.StatusConflict, "a request with the same "+b.header+" is in progress")
+			return
+		}
+
+		rw := ctx.ResponseWriter.ResponseWriter
+		tee := &teeWriter{ResponseWriter: rw, limit: b.maxResponseSize}
+		ctx.ResponseWriter.ResponseWriter = tee
+		completed := false
+		defer func() {
+			ctx.ResponseWriter.ResponseWriter = rw
+			if !completed {
+				// let the client retry
+				if err := b.cache.Delete(c, key); err != nil {
+					logs.Warn("release idempotency key %s failed: %v", idempotencyKey, err)
+				}
+			}
+		}()
+		next(ctx)
+
+		status := tee.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		// the server errors are not stored, the client can retry
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		rec := &record{
+			State:       stateCompleted,
+			Fingerprint: fingerprint,
+			Status:      status,
+		}
+		if tee.overflow {
+			// the request must not be processed again, but its response can't be replayed
+			rec.TooLarge = true
+		} else {
+			rec.Header = rw.Header().Clone()
+			rec.Body = tee.body.Bytes()
+		}
+		if err := b.put(c, key, rec, b.ttl); err != nil {
+			logs.Warn("store the response of idempotency key %s failed: %v", idempotencyKey, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// defaultScope is made of the Authorization header and the session id of the request
+func defaultScope(ctx *context.Context) string {
+	r := ctx.Request
+	parts := []string{r.Header.Get("Authorization")}
+	sess := web.BConfig.WebConfig.Session
+	if c, err := r.Cookie(sess.SessionName); err == nil {
+		parts = append(parts, c.Value)
+	}
+	if sess.SessionEnableSidInHTTPHeader {
+		parts = append(parts, r.Header.Get(sess.SessionNameInHTTPHeader))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (b *FilterChainBuilder) key(ctx *context.Context, idempotencyKey string) string {
+	scope := ""
+	if b.scope != nil {
+		scope = b.scope(ctx)
+	}
+	sum := sha256.Sum256([]byte(scope + "\n" + idempotencyKey))
+	return b.prefix + hex.EncodeToString(sum[:16])
+}
+
+// lock prevents the duplicates received by this instance from being processed concurrently
+func (b *FilterChainBuilder) lock(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.inFlight[key] {
+		return false
+	}
+	b.inFlight[key] = true
+	return true
+}
+
+func (b *FilterChainBuilder) unlock(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.inFlight, key)
+}
+
+// acquire stores the processing record, and reads it back to check that another instance didn't overwrite it
+func (b *FilterChainBuilder) acquire(ctx gocontext.Context, key, fingerprint, token string) bool {
+	rec := &record{State: stateProcessing, Fingerprint: fingerprint, Token: token}
+	if err := b.put(ctx, key, rec, b.lockTTL); err != nil {
+		logs.Warn("store idempotency key failed: %v", err)
+		return false
+	}
+	stored := b.get(ctx, key)
+	return stored != nil && stored.Token == token
+}
+
+// replay writes the stored response, or rejects the request if it's not a retry of the stored one
+func (b *FilterChainBuilder) replay(ctx *context.Context, rec *record, fingerprint string) {
+	if rec.Fingerprint != fingerprint {
+		reject(ctx, http.StatusUnprocessableEntity, b.header+" has already been used by another request")
+		return
+	}
+	if rec.State != stateCompleted {
+		reject(ctx, http.StatusConflict, "a request with the same "+b.header+" is in progress")
+		return
+	}
+	if rec.TooLarge {
+		reject(ctx, http.StatusConflict, "the response of the request with the same "+b.header+" is too large to be replayed")
+		return
+	}
+	header := ctx.ResponseWriter.Header()
+	for k, v := range rec.Header {
+		header[k] = v
+	}
+	header.Set(HeaderReplayed, "true")
+	ctx.ResponseWriter.WriteHeader(rec.Status)
+	_, _ = ctx.ResponseWriter.Write(rec.Body)
+}
+
+func (b *FilterChainBuilder) get(ctx gocontext.Context, key string) *record {
+	v, err := b.cache.Get(ctx, key)
+	if err != nil || v == nil {
+		return nil
+	}
+	rec := &record{}
+	if err = json.Unmarshal([]byte(cache.GetString(v)), rec); err != nil {
+		logs.Warn("decode the idempotency record %s failed: %v", key, err)
+		return nil
+	}
+	return rec
+}
+
+func (b *FilterChainBuilder) put(ctx gocontext.Context, key string, rec *record, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return b.cache.Put(ctx, key, string(data), ttl)
+}
+
+// fingerprint identifies the request, the retries must have the same method, path, query and body
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func reject(ctx *context.Context, code int, msg string) {
+	ctx.ResponseWriter.WriteHeader(code)
+	ctx.WriteString(msg)
+}
+
+// teeWriter copies the response written to the client, up to limit bytes
+type teeWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *teeWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *teeWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.overflow {
+		if w.body.Len()+len(p) > w.limit {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *teeWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
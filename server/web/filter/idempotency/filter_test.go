@@ -0,0 +1,188 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/client/cache"
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+func newTestHandler(t *testing.T, opts ...Option) (*web.ControllerRegister, *int32) {
+	bm, err := cache.NewCache("memory", `{"interval":60}`)
+	require.NoError(t, err)
+	builder := NewFilterChainBuilder(bm, opts...)
+
+	var calls int32
+	handler := web.NewControllerRegister()
+	handler.InsertFilterChain("/*", builder.FilterChain)
+	handler.Post("/orders", func(ctx *context.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.Output.Header("Location", "/orders/"+strconv.Itoa(int(n)))
+		ctx.Output.SetStatus(http.StatusCreated)
+		_ = ctx.Output.Body([]byte("order " + strconv.Itoa(int(n)) + " " + string(body)))
+	})
+	handler.Post("/error", func(ctx *context.Context) {
+		atomic.AddInt32(&calls, 1)
+		ctx.Output.SetStatus(http.StatusInternalServerError)
+		_ = ctx.Output.Body([]byte("failed"))
+	})
+	handler.Init()
+	return handler, &calls
+}
+
+func do(handler http.Handler, url, key, body string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestFilterChain(t *testing.T) {
+	handler, calls := newTestHandler(t)
+
+	w := do(handler, "/orders", "k1", "pizza")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "order 1 pizza", w.Body.String())
+	assert.Empty(t, w.Header().Get(HeaderReplayed))
+
+	w = do(handler, "/orders", "k1", "pizza")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "order 1 pizza", w.Body.String())
+	assert.Equal(t, "/orders/1", w.Header().Get("Location"))
+	assert.Equal(t, "true", w.Header().Get(HeaderReplayed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	// another key is another request
+	w = do(handler, "/orders", "k2", "pizza")
+	assert.Equal(t, "order 2 pizza", w.Body.String())
+
+	// the key can't be reused with another body
+	w = do(handler, "/orders", "k1", "pasta")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// the requests without key are not deduplicated
+	do(handler, "/orders", "", "pizza")
+	do(handler, "/orders", "", "pizza")
+	assert.Equal(t, int32(4), atomic.LoadInt32(calls))
+}
+
+func TestFilterChainServerError(t *testing.T) {
+	handler, calls := newTestHandler(t)
+
+	w := do(handler, "/error", "k1", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w = do(handler, "/error", "k1", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderReplayed))
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestFilterChainInFlight(t *testing.T) {
+	bm, err := cache.NewCache("memory", `{"interval":60}`)
+	require.NoError(t, err)
+	builder := NewFilterChainBuilder(bm)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := web.NewControllerRegister()
+	handler.InsertFilterChain("/*", builder.FilterChain)
+	handler.Post("/slow", func(ctx *context.Context) {
+		close(started)
+		<-release
+		_ = ctx.Output.Body([]byte("done"))
+	})
+	handler.Init()
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() {
+		first <- do(handler, "/slow", "k1", "")
+	}()
+	<-started
+
+	w := do(handler, "/slow", "k1", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = do(handler, "/slow", "k1", "other")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	close(release)
+	assert.Equal(t, "done", (<-first).Body.String())
+	w = do(handler, "/slow", "k1", "")
+	assert.Equal(t, "done", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(HeaderReplayed))
+}
+
+func TestFilterChainOptions(t *testing.T) {
+	handler, calls := newTestHandler(t, WithMaxBodySize(4), WithMethods(http.MethodPut))
+
+	// POST is not handled any more
+	do(handler, "/orders", "k1", "ab")
+	do(handler, "/orders", "k1", "ab")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+	handler, _ = newTestHandler(t, WithMaxBodySize(4))
+	w := do(handler, "/orders", "k1", "too large")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	w = do(handler, "/orders", strings.Repeat("k", 256), "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFilterChainScope(t *testing.T) {
+	handler, calls := newTestHandler(t)
+	doAs := func(auth string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(http.MethodPost, "/orders", strings.NewReader("pizza"))
+		r.Header.Set("Idempotency-Key", "k1")
+		r.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, "order 1 pizza", doAs("Bearer alice").Body.String())
+	// bob reuses the key of alice but doesn't get her response
+	w := doAs("Bearer bob")
+	assert.Equal(t, "order 2 pizza", w.Body.String())
+	assert.Empty(t, w.Header().Get(HeaderReplayed))
+	w = doAs("Bearer alice")
+	assert.Equal(t, "order 1 pizza", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(HeaderReplayed))
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestFilterChainMaxResponseSize(t *testing.T) {
+	handler, calls := newTestHandler(t, WithMaxResponseSize(4))
+
+	w := do(handler, "/orders", "k1", "pizza")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "order 1 pizza", w.Body.String())
+	// the request is not processed again
+	w = do(handler, "/orders", "k1", "pizza")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
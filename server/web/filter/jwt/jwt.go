@@ -0,0 +1,394 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jwt provides a filter authenticating the requests with JSON Web Tokens sent as bearer tokens.
+// The HS256, RS256 and ES256 signatures are verified against a static key set or a JWKS document.
+// Simple Usage:
+//
+//	import(
+//		"github.com/asish-tom/beego/v2"
+//		"github.com/asish-tom/beego/v2/server/web/filter/jwt"
+//	)
+//
+//	func main(){
+//		keys := jwt.StaticKeys{"": []byte("secret")}
+//		beego.InsertFilter("/api/*", beego.BeforeRouter, jwt.NewAuthenticator(keys, jwt.WithIssuer("https://auth.example.com")))
+//		beego.Run()
+//	}
+//
+// Advanced Usage:
+//
+//	keys := jwt.NewJWKS("https://auth.example.com/.well-known/jwks.json")
+//	beego.InsertFilter("/api/*", beego.BeforeRouter, jwt.NewAuthenticator(keys,
+//		jwt.WithAudience("orders"), jwt.WithClockSkew(time.Minute)))
+//	// use the subject of the token in the casbin policies
+//	beego.InsertFilter("/api/*", beego.BeforeRouter, authz.NewAuthorizer(e, authz.WithSubject(jwt.Subject)))
+//
+// The claims of the verified token are stored in the input data, see GetClaims.
+package jwt
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+// ClaimsKey is the key of the claims in the input data
+const ClaimsKey = "JWTClaims"
+
+// The supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// the errors returned by Verifier.Verify
+var (
+	ErrTokenMissing     = errors.New("jwt: missing bearer token")
+	ErrTokenMalformed   = errors.New("jwt: malformed token")
+	ErrAlgorithm        = errors.New("jwt: unsupported signing algorithm")
+	ErrUnknownKey       = errors.New("jwt: unknown signing key")
+	ErrSignatureInvalid = errors.New("jwt: invalid signature")
+	ErrTokenExpired     = errors.New("jwt: token is expired")
+	ErrTokenNotValidYet = errors.New("jwt: token is not valid yet")
+	ErrInvalidIssuer    = errors.New("jwt: invalid issuer")
+	ErrInvalidAudience  = errors.New("jwt: invalid audience")
+)
+
+// Claims are the claims of a verified token, the numbers are json.Number
+type Claims map[string]interface{}
+
+// Subject returns the sub claim
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Issuer returns the iss claim
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Audience returns the aud claim, which is either a string or an array of strings
+func (c Claims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		res := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// ExpiresAt returns the exp claim, ok is false when it's missing
+func (c Claims) ExpiresAt() (t time.Time, ok bool) {
+	return c.time("exp")
+}
+
+// NotBefore returns the nbf claim, ok is false when it's missing
+func (c Claims) NotBefore() (t time.Time, ok bool) {
+	return c.time("nbf")
+}
+
+// time reads a NumericDate, the number of seconds since the epoch
+func (c Claims) time(name string) (time.Time, bool) {
+	n, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
+
+// Option configures the Verifier
+type Option func(v *Verifier)
+
+// WithAlgorithms sets the accepted signing algorithms, all the supported ones are accepted by default
+func WithAlgorithms(algs ...string) Option {
+	return func(v *Verifier) {
+		v.algorithms = algs
+	}
+}
+
+// WithIssuer sets the accepted issuers, the iss claim must be one of them
+func WithIssuer(issuers ...string) Option {
+	return func(v *Verifier) {
+		v.issuers = issuers
+	}
+}
+
+// WithAudience sets the accepted audiences, the aud claim must contain one of them
+func WithAudience(audiences ...string) Option {
+	return func(v *Verifier) {
+		v.audiences = audiences
+	}
+}
+
+// WithClockSkew sets the tolerance applied to the exp and nbf claims, the default is 0
+func WithClockSkew(d time.Duration) Option {
+	return func(v *Verifier) {
+		v.clockSkew = d
+	}
+}
+
+// WithRealm sets the realm of the WWW-Authenticate header sent with the 401 responses
+func WithRealm(realm string) Option {
+	return func(v *Verifier) {
+		v.realm = realm
+	}
+}
+
+// WithTokenExtractor replaces the function reading the token from the request,
+// by default the token is read from the Authorization header with the Bearer scheme.
+func WithTokenExtractor(f func(ctx *context.Context) string) Option {
+	return func(v *Verifier) {
+		v.extract = f
+	}
+}
+
+// Verifier verifies the tokens signed with the keys of a KeySet
+type Verifier struct {
+	keys       KeySet
+	algorithms []string
+	issuers    []string
+	audiences  []string
+	clockSkew  time.Duration
+	realm      string
+	extract    func(ctx *context.Context) string
+	now        func() time.Time
+}
+
+// NewVerifier returns a Verifier checking the signatures with keys
+func NewVerifier(keys KeySet, opts ...Option) *Verifier {
+	v := &Verifier{
+		keys:       keys,
+		algorithms: []string{HS256, RS256, ES256},
+		realm:      "Authorization Required",
+		extract:    BearerToken,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// NewAuthenticator returns a filter rejecting the requests without a valid token with 401,
+// the claims of the valid tokens are stored in the input data.
+func NewAuthenticator(keys KeySet, opts ...Option) web.FilterFunc {
+	v := NewVerifier(keys, opts...)
+	return func(ctx *context.Context) {
+		claims, err := v.Verify(ctx.Request.Context(), v.extract(ctx))
+		if err != nil {
+			v.requireAuth(ctx, err)
+			return
+		}
+		ctx.Input.SetData(ClaimsKey, claims)
+	}
+}
+
+// GetClaims returns the claims stored by the authenticator, nil if the request was not authenticated
+func GetClaims(ctx *context.Context) Claims {
+	claims, _ := ctx.Input.GetData(ClaimsKey).(Claims)
+	return claims
+}
+
+// Subject returns the sub claim of the authenticated request,
+// it can be passed to authz.WithSubject
+func Subject(ctx *context.Context) string {
+	return GetClaims(ctx).Subject()
+}
+
+// BearerToken reads the token of the Authorization header with the Bearer scheme
+func BearerToken(ctx *context.Context) string {
+	scheme, token, ok := strings.Cut(ctx.Input.Header("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// requireAuth sends 401 with the error of the token as described by RFC 6750
+func (v *Verifier) requireAuth(ctx *context.Context, err error) {
+	challenge := `Bearer realm="` + v.realm + `"`
+	if !errors.Is(err, ErrTokenMissing) {
+		challenge += `, error="invalid_token", error_description="` + strings.TrimPrefix(err.Error(), "jwt: ") + `"`
+	}
+	ctx.ResponseWriter.Header().Set("WWW-Authenticate", challenge)
+	ctx.ResponseWriter.WriteHeader(http.StatusUnauthorized)
+	_, _ = ctx.ResponseWriter.Write([]byte("401 Unauthorized\n"))
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature and the registered claims of token, and returns its claims
+func (v *Verifier) Verify(ctx gocontext.Context, token string) (Claims, error) {
+	if token == "" {
+		return nil, ErrTokenMissing
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	if !v.accepts(h.Alg) {
+		return nil, ErrAlgorithm
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	key, err := v.keys.Key(ctx, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifySignature(h.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	claims := Claims{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, v.validate(claims)
+}
+
+func (v *Verifier) accepts(alg string) bool {
+	for _, a := range v.algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the exp, nbf, iss and aud claims
+func (v *Verifier) validate(claims Claims) error {
+	now := v.now()
+	if exp, ok := claims.ExpiresAt(); ok && !now.Before(exp.Add(v.clockSkew)) {
+		return ErrTokenExpired
+	}
+	if nbf, ok := claims.NotBefore(); ok && now.Add(v.clockSkew).Before(nbf) {
+		return ErrTokenNotValidYet
+	}
+	if len(v.issuers) > 0 && !contains(v.issuers, claims.Issuer()) {
+		return ErrInvalidIssuer
+	}
+	if len(v.audiences) > 0 {
+		for _, aud := range claims.Audience() {
+			if contains(v.audiences, aud) {
+				return nil
+			}
+		}
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	}
+	return nil
+}
+
+// verifySignature checks sig with key, the type of key must match alg
+func verifySignature(alg string, key interface{}, signed string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("%w for %s", ErrUnknownKey, alg)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrSignatureInvalid
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w for %s", ErrUnknownKey, alg)
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) != nil {
+			return ErrSignatureInvalid
+		}
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return fmt.Errorf("%w for %s", ErrUnknownKey, alg)
+		}
+		// the signature is r and s on 32 bytes each
+		if len(sig) != 64 {
+			return ErrSignatureInvalid
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, sum[:], r, s) {
+			return ErrSignatureInvalid
+		}
+	default:
+		return ErrAlgorithm
+	}
+	return nil
+}
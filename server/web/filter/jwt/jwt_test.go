@@ -0,0 +1,183 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	gocontext "context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+// sign returns a token signed with key, which is []byte, *rsa.PrivateKey or *ecdsa.PrivateKey
+func sign(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	h, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString(h) + "." + enc.EncodeToString(c)
+	sum := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, sum[:])
+		require.NoError(t, err)
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + enc.EncodeToString(sig)
+}
+
+func TestVerifySignature(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keys := StaticKeys{
+		"hs": []byte("secret"),
+		"rs": &rsaKey.PublicKey,
+		"es": &ecKey.PublicKey,
+	}
+	v := NewVerifier(keys)
+	ctx := gocontext.Background()
+	claims := map[string]interface{}{"sub": "alice"}
+
+	for _, tc := range []struct {
+		alg, kid string
+		key      interface{}
+	}{
+		{HS256, "hs", []byte("secret")},
+		{RS256, "rs", rsaKey},
+		{ES256, "es", ecKey},
+	} {
+		c, err := v.Verify(ctx, sign(t, tc.alg, tc.kid, tc.key, claims))
+		require.NoError(t, err, tc.alg)
+		assert.Equal(t, "alice", c.Subject())
+	}
+
+	_, err = v.Verify(ctx, sign(t, HS256, "hs", []byte("other"), claims))
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+	// the public RSA key can't be used as a HMAC secret
+	_, err = v.Verify(ctx, sign(t, HS256, "rs", []byte("secret"), claims))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = v.Verify(ctx, sign(t, HS256, "missing", []byte("secret"), claims))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = v.Verify(ctx, sign(t, "none", "hs", []byte("secret"), claims))
+	assert.ErrorIs(t, err, ErrAlgorithm)
+	_, err = NewVerifier(keys, WithAlgorithms(RS256)).Verify(ctx, sign(t, HS256, "hs", []byte("secret"), claims))
+	assert.ErrorIs(t, err, ErrAlgorithm)
+	_, err = v.Verify(ctx, "a.b")
+	assert.ErrorIs(t, err, ErrTokenMalformed)
+	_, err = v.Verify(ctx, "")
+	assert.ErrorIs(t, err, ErrTokenMissing)
+}
+
+func TestVerifyClaims(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	v := NewVerifier(StaticKeys{"": secret}, WithIssuer("https://auth.example.com"),
+		WithAudience("orders"), WithClockSkew(time.Minute))
+	verify := func(claims map[string]interface{}) error {
+		_, err := v.Verify(gocontext.Background(), sign(t, HS256, "", secret, claims))
+		return err
+	}
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": "https://auth.example.com",
+			"aud": []string{"billing", "orders"},
+			"exp": now.Add(time.Minute).Unix(),
+			"nbf": now.Unix(),
+		}
+	}
+
+	assert.NoError(t, verify(valid()))
+
+	c := valid()
+	c["exp"] = now.Add(-30 * time.Second).Unix()
+	assert.NoError(t, verify(c), "the clock skew is tolerated")
+	c["exp"] = now.Add(-2 * time.Minute).Unix()
+	assert.ErrorIs(t, verify(c), ErrTokenExpired)
+
+	c = valid()
+	c["nbf"] = now.Add(30 * time.Second).Unix()
+	assert.NoError(t, verify(c))
+	c["nbf"] = now.Add(2 * time.Minute).Unix()
+	assert.ErrorIs(t, verify(c), ErrTokenNotValidYet)
+
+	c = valid()
+	c["iss"] = "https://evil.example.com"
+	assert.ErrorIs(t, verify(c), ErrInvalidIssuer)
+
+	c = valid()
+	c["aud"] = "orders"
+	assert.NoError(t, verify(c))
+	c["aud"] = "billing"
+	assert.ErrorIs(t, verify(c), ErrInvalidAudience)
+	delete(c, "aud")
+	assert.ErrorIs(t, verify(c), ErrInvalidAudience)
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	secret := []byte("secret")
+	handler := web.NewControllerRegister()
+	handler.InsertFilter("*", web.BeforeRouter, NewAuthenticator(StaticKeys{"": secret}, WithRealm("api")))
+	handler.Any("*", func(ctx *context.Context) {
+		_ = ctx.Output.Body([]byte(Subject(ctx) + " " + GetClaims(ctx)["role"].(string)))
+	})
+
+	r, _ := http.NewRequest("GET", "/orders", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer realm="api"`, w.Header().Get("WWW-Authenticate"))
+
+	r.Header.Set("Authorization", "Bearer "+sign(t, HS256, "", []byte("other"), map[string]interface{}{"sub": "alice"}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer realm="api", error="invalid_token", error_description="invalid signature"`, w.Header().Get("WWW-Authenticate"))
+
+	r.Header.Set("Authorization", "Bearer "+sign(t, HS256, "", secret, map[string]interface{}{"sub": "alice", "role": "admin"}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice admin", w.Body.String())
+}
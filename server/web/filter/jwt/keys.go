@@ -0,0 +1,296 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	gocontext "context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asish-tom/beego/v2/core/logs"
+)
+
+// KeySet provides the keys verifying the signatures.
+// The keys are []byte for HS256, *rsa.PublicKey for RS256 and *ecdsa.PublicKey for ES256.
+type KeySet interface {
+	// Key returns the key identified by the kid header of the token, kid may be empty
+	Key(ctx gocontext.Context, kid string) (interface{}, error)
+}
+
+// StaticKeys is a KeySet indexed by key id.
+// A token without kid is verified with the key of the empty id, or with the only key of the set.
+type StaticKeys map[string]interface{}
+
+// Key see KeySet.Key
+func (s StaticKeys) Key(_ gocontext.Context, kid string) (interface{}, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(s) == 1 {
+		for _, key := range s {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// jwk is a JSON Web Key of RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parses a JWKS document, the keys which are not used for signatures
+// or whose type is not supported are skipped
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("jwt: invalid JWKS: %w", err)
+	}
+	keys := make(StaticKeys, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			logs.Warn("jwt: skip the key %s of the JWKS: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("the point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("missing parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// JWKSOption configures JWKS
+type JWKSOption func(j *JWKS)
+
+// WithRefreshInterval sets how long the keys are cached, the default is 1 hour
+func WithRefreshInterval(d time.Duration) JWKSOption {
+	return func(j *JWKS) {
+		j.refreshInterval = d
+	}
+}
+
+// WithMinRefreshInterval sets the minimum interval between two loads triggered by unknown key ids,
+// and between a failed load and the next one. The default is 1 minute
+func WithMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(j *JWKS) {
+		j.minRefreshInterval = d
+	}
+}
+
+// WithHTTPClient sets the client downloading the JWKS document,
+// the default client gives up after 10 seconds
+func WithHTTPClient(c *http.Client) JWKSOption {
+	return func(j *JWKS) {
+		j.client = c
+	}
+}
+
+// JWKS is a KeySet loaded from a JWKS document, either a file or an http(s) URL.
+// The keys are cached and reloaded periodically, a token signed with an unknown key
+// reloads them as well, so that the rotated keys are used as soon as they are published.
+type JWKS struct {
+	source             string
+	client             *http.Client
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	mu       sync.RWMutex
+	keys     StaticKeys
+	loadedAt time.Time
+	// the last failed load, the source is not loaded again before minRefreshInterval
+	failedAt time.Time
+	loadErr  error
+	// serializes the loads
+	loading sync.Mutex
+}
+
+// NewJWKS returns a KeySet loading the JWKS document of source when it's first used
+func NewJWKS(source string, opts ...JWKSOption) *JWKS {
+	j := &JWKS{
+		source:             source,
+		client:             &http.Client{Timeout: 10 * time.Second},
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Key see KeySet.Key
+func (j *JWKS) Key(ctx gocontext.Context, kid string) (interface{}, error) {
+	keys, loadedAt := j.cached()
+	if keys == nil || time.Since(loadedAt) > j.refreshInterval {
+		// keep the stale keys until the source is available
+		if err := j.refresh(ctx, loadedAt); err != nil && keys == nil {
+			return nil, err
+		}
+		keys, loadedAt = j.cached()
+	}
+	key, err := keys.Key(ctx, kid)
+	if err == nil || time.Since(loadedAt) < j.minRefreshInterval {
+		return key, err
+	}
+	// the key may have been rotated
+	if err = j.refresh(ctx, loadedAt); err != nil {
+		return nil, ErrUnknownKey
+	}
+	keys, _ = j.cached()
+	return keys.Key(ctx, kid)
+}
+
+func (j *JWKS) cached() (StaticKeys, time.Time) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.keys, j.loadedAt
+}
+
+// refresh loads the keys, unless they have been loaded after seen by a concurrent call.
+// The error of the last load is returned without loading again during minRefreshInterval,
+// so that an unavailable source doesn't slow down every request.
+func (j *JWKS) refresh(ctx gocontext.Context, seen time.Time) error {
+	if err := j.failure(); err != nil {
+		return err
+	}
+	j.loading.Lock()
+	defer j.loading.Unlock()
+	if _, loadedAt := j.cached(); loadedAt.After(seen) {
+		return nil
+	}
+	// a concurrent load may have failed meanwhile
+	if err := j.failure(); err != nil {
+		return err
+	}
+	keys, err := j.parse(ctx)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		// the request giving up is not a failure of the source
+		if ctx.Err() == nil {
+			logs.Warn("jwt: load the JWKS %s failed: %v", j.source, err)
+			j.failedAt, j.loadErr = time.Now(), err
+		}
+		return err
+	}
+	j.keys, j.loadedAt, j.loadErr = keys, time.Now(), nil
+	return nil
+}
+
+// failure returns the error of the last load if it failed less than minRefreshInterval ago
+func (j *JWKS) failure() error {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if j.loadErr != nil && time.Since(j.failedAt) < j.minRefreshInterval {
+		return j.loadErr
+	}
+	return nil
+}
+
+func (j *JWKS) parse(ctx gocontext.Context) (StaticKeys, error) {
+	data, err := j.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+func (j *JWKS) load(ctx gocontext.Context) ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: GET %s: %s", j.source, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
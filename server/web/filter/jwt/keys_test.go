@@ -0,0 +1,169 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	gocontext "context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	enc := base64.RawURLEncoding
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig",
+		"n": enc.EncodeToString(key.N.Bytes()),
+		"e": enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	enc := base64.RawURLEncoding
+	data, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		rsaJWK("rs", &rsaKey.PublicKey),
+		{"kty": "EC", "kid": "es", "crv": "P-256", "x": enc.EncodeToString(ecKey.X.Bytes()), "y": enc.EncodeToString(ecKey.Y.Bytes())},
+		{"kty": "oct", "kid": "hs", "k": enc.EncodeToString([]byte("secret"))},
+		{"kty": "RSA", "kid": "enc", "use": "enc"},
+		{"kty": "OKP", "kid": "ed"},
+	}})
+	require.NoError(t, err)
+
+	keys, err := ParseJWKS(data)
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+	assert.True(t, rsaKey.PublicKey.Equal(keys["rs"]))
+	assert.True(t, ecKey.PublicKey.Equal(keys["es"]))
+	assert.Equal(t, []byte("secret"), keys["hs"])
+
+	_, err = ParseJWKS([]byte("{"))
+	assert.Error(t, err)
+}
+
+func TestJWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{rsaJWK("rs", &rsaKey.PublicKey)}})
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+
+	v := NewVerifier(NewJWKS(file))
+	claims, err := v.Verify(gocontext.Background(), sign(t, RS256, "rs", rsaKey, map[string]interface{}{"sub": "alice"}))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject())
+
+	_, err = NewJWKS(filepath.Join(t.TempDir(), "missing.json")).Key(gocontext.Background(), "rs")
+	assert.Error(t, err)
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		keys  = []map[string]string{rsaJWK("old", &oldKey.PublicKey)}
+		loads int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&loads, 1)
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, WithMinRefreshInterval(0), WithRefreshInterval(time.Hour))
+	v := NewVerifier(jwks)
+	ctx := gocontext.Background()
+	claims := map[string]interface{}{"sub": "alice"}
+
+	_, err = v.Verify(ctx, sign(t, RS256, "old", oldKey, claims))
+	require.NoError(t, err)
+	_, err = v.Verify(ctx, sign(t, RS256, "old", oldKey, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads), "the keys are cached")
+
+	// the new key is published
+	mu.Lock()
+	keys = append(keys, rsaJWK("new", &newKey.PublicKey))
+	mu.Unlock()
+	_, err = v.Verify(ctx, sign(t, RS256, "new", newKey, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+
+	// the unknown keys don't reload the document more than once per min refresh interval
+	jwks.minRefreshInterval = time.Hour
+	_, err = v.Verify(ctx, sign(t, RS256, "unknown", newKey, claims))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+}
+
+func TestJWKSFailureBackoff(t *testing.T) {
+	var loads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&loads, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, WithMinRefreshInterval(time.Hour))
+	ctx := gocontext.Background()
+	for i := 0; i < 3; i++ {
+		_, err := jwks.Key(ctx, "rs")
+		assert.Error(t, err)
+	}
+	// the source is not loaded again until the min refresh interval has elapsed
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	jwks.mu.Lock()
+	jwks.failedAt = time.Now().Add(-2 * time.Hour)
+	jwks.mu.Unlock()
+	_, err := jwks.Key(ctx, "rs")
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+
+	// the requests which give up don't delay the next loads
+	canceled, cancel := gocontext.WithCancel(ctx)
+	cancel()
+	jwks = NewJWKS(server.URL, WithMinRefreshInterval(time.Hour))
+	_, err = jwks.Key(canceled, "rs")
+	assert.Error(t, err)
+	_, err = jwks.Key(ctx, "rs")
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&loads))
+}
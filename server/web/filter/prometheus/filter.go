@@ -40,7 +40,10 @@ var (
 	initSummaryVec sync.Once
 )
 
-// FilterChain returns a FilterFunc. The filter will records some metrics
+// FilterChain returns a FilterFunc. The filter will records some metrics:
+// the summary http_request_beego in milliseconds by pattern, method and status,
+// and the route statistics of web.StatisticsMap, which are shown by the admin service too, so that both agree.
+// Only the routes of the requests matching the pattern of the filter are exported
 func (builder *FilterChainBuilder) FilterChain(next web.FilterFunc) web.FilterFunc {
 	initSummaryVec.Do(func() {
 		summaryVec = builder.buildVec()
@@ -48,14 +51,20 @@ func (builder *FilterChainBuilder) FilterChain(next web.FilterFunc) web.FilterFu
 		if _, ok := err.(*prometheus.AlreadyRegisteredError); err != nil && !ok {
 			logs.Error("web module register prometheus vector failed, %+v", err)
 		}
+		err = prometheus.Register(newStatisticsCollector())
+		if _, ok := err.(*prometheus.AlreadyRegisteredError); err != nil && !ok {
+			logs.Error("web module register prometheus collector failed, %+v", err)
+		}
 		registerBuildInfo()
 	})
 
 	return func(ctx *context.Context) {
+		// the router records the route statistics
+		web.RecordRouteStatistics(ctx)
 		startTime := time.Now()
 		next(ctx)
 		endTime := time.Now()
-		go report(endTime.Sub(startTime), ctx, summaryVec)
+		report(endTime.Sub(startTime), ctx, summaryVec)
 	}
 }
 
@@ -73,6 +82,60 @@ func (builder *FilterChainBuilder) buildVec() *prometheus.SummaryVec {
 	return summaryVec
 }
 
+// statisticsCollector collects the statistics of the routes of web.StatisticsMap exported by the filter
+type statisticsCollector struct {
+	duration *prometheus.Desc
+	requests *prometheus.Desc
+	inFlight *prometheus.Desc
+}
+
+func newStatisticsCollector() *statisticsCollector {
+	constLabels := prometheus.Labels{
+		"server":  web.BConfig.ServerName,
+		"env":     web.BConfig.RunMode,
+		"appname": web.BConfig.AppName,
+	}
+	return &statisticsCollector{
+		duration: prometheus.NewDesc("beego_http_request_duration_seconds",
+			"The latency of the http requests", []string{"pattern", "method"}, constLabels),
+		requests: prometheus.NewDesc("beego_http_requests_total",
+			"The number of the http requests by status class", []string{"pattern", "method", "status"}, constLabels),
+		inFlight: prometheus.NewDesc("beego_http_requests_in_flight",
+			"The number of the http requests being served", []string{"pattern", "method"}, constLabels),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *statisticsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.duration
+	ch <- c.requests
+	ch <- c.inFlight
+}
+
+// Collect implements prometheus.Collector
+func (c *statisticsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, route := range web.StatisticsMap.RouteStatistics() {
+		if !route.Exported {
+			continue
+		}
+		total := route.Total
+		buckets := make(map[float64]uint64, len(web.LatencyBuckets))
+		var cumulative uint64
+		for i, bound := range web.LatencyBuckets {
+			cumulative += uint64(total.Buckets[i])
+			buckets[bound.Seconds()] = cumulative
+		}
+		ch <- prometheus.MustNewConstHistogram(c.duration, uint64(total.Count), total.Sum.Seconds(), buckets,
+			route.Pattern, route.Method)
+		for status, n := range total.Status {
+			ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(n),
+				route.Pattern, route.Method, status)
+		}
+		ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(route.InFlight),
+			route.Pattern, route.Method)
+	}
+}
+
 func registerBuildInfo() {
 	buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name:      "beego",
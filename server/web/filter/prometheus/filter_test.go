@@ -20,8 +20,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/asish-tom/beego/v2/server/web"
 	"github.com/asish-tom/beego/v2/server/web/context"
 )
 
@@ -53,3 +57,80 @@ func TestFilterChainBuilder_report(t *testing.T) {
 	ctx.Input.SetData("RouterPattern", "my-route")
 	report(time.Second, ctx, fb.buildVec())
 }
+
+// patternMetrics returns the metrics of the pattern by name
+func patternMetrics(t *testing.T, g prometheus.Gatherer, pattern string) map[string][]*dto.Metric {
+	families, err := g.Gather()
+	require.NoError(t, err)
+	metrics := make(map[string][]*dto.Metric, len(families))
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "pattern" && l.GetValue() == pattern {
+					metrics[f.GetName()] = append(metrics[f.GetName()], m)
+				}
+			}
+		}
+	}
+	return metrics
+}
+
+func TestStatisticsCollector(t *testing.T) {
+	handler := web.NewControllerRegister()
+	handler.InsertFilterChain("/prometheus/*", (&FilterChainBuilder{}).FilterChain)
+	handler.Init()
+	handler.Get("/prometheus/:id", func(ctx *context.Context) {
+		if ctx.Input.Param(":id") == "missing" {
+			ctx.Output.SetStatus(http.StatusNotFound)
+		}
+		_ = ctx.Output.Body([]byte("ok"))
+	})
+	handler.Get("/private", func(ctx *context.Context) {
+		_ = ctx.Output.Body([]byte("ok"))
+	})
+	for _, url := range []string{"/prometheus/1", "/prometheus/2", "/prometheus/missing", "/private"} {
+		r, _ := http.NewRequest(http.MethodGet, url, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(newStatisticsCollector()))
+	metrics := patternMetrics(t, registry, "/prometheus/:id")
+
+	// the routes out of the pattern of the filter aren't exported
+	assert.Empty(t, patternMetrics(t, registry, "/private"))
+
+	require.Len(t, metrics["beego_http_request_duration_seconds"], 1)
+	histogram := metrics["beego_http_request_duration_seconds"][0].GetHistogram()
+	assert.Equal(t, uint64(3), histogram.GetSampleCount())
+
+	requests := make(map[string]float64)
+	for _, m := range metrics["beego_http_requests_total"] {
+		for _, l := range m.GetLabel() {
+			if l.GetName() == "status" {
+				requests[l.GetValue()] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, float64(2), requests["2xx"])
+	assert.Equal(t, float64(1), requests["4xx"])
+	assert.Equal(t, float64(0), requests["5xx"])
+
+	require.Len(t, metrics["beego_http_requests_in_flight"], 1)
+	assert.Equal(t, float64(0), metrics["beego_http_requests_in_flight"][0].GetGauge().GetValue())
+
+	// the summary of the previous versions is kept
+	summaries := patternMetrics(t, prometheus.DefaultGatherer, "/prometheus/:id")["http_request_beego"]
+	require.NotEmpty(t, summaries)
+	var count uint64
+	for _, m := range summaries {
+		labels := make(map[string]string)
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		assert.Equal(t, http.MethodGet, labels["method"])
+		assert.Contains(t, labels, "status")
+		count += m.GetSummary().GetSampleCount()
+	}
+	assert.Equal(t, uint64(3), count)
+}
@@ -5,10 +5,80 @@ import "time"
 // bucket is an interface store ratelimit info
 type bucket interface {
 	take(amount uint) bool
+	// allow takes amount like take, and reports the state of the bucket after it
+	allow(amount uint) result
 	getCapacity() uint
 	getRemaining() uint
 	getRate() time.Duration
+	// getReset returns how long it takes for the bucket to be full again, 0 if it's full
+	getReset() time.Duration
+}
+
+// result is the state of a bucket after a request, it's sent in the RateLimit-* headers
+type result struct {
+	allowed   bool
+	limit     uint
+	remaining uint
+	// reset is how long it takes to restore the whole quota
+	reset time.Duration
+	// retryAfter is how long the client should wait for the next request to be allowed
+	retryAfter time.Duration
 }
 
 // bucketOption is constructor option
 type bucketOption func(bucket)
+
+// windowBucket is implemented by the buckets counting the requests in a time window
+type windowBucket interface {
+	settings() *windowSettings
+}
+
+// windowSettings are the settings shared by the window buckets
+type windowSettings struct {
+	capacity uint
+	rate     time.Duration
+	window   time.Duration
+}
+
+func (s *windowSettings) settings() *windowSettings {
+	return s
+}
+
+// init sets the default window, in which capacity tokens are generated at the rate of the token bucket
+func (s *windowSettings) init() {
+	if s.window <= 0 {
+		s.window = s.rate * time.Duration(s.capacity)
+	}
+}
+
+func withCapacity(capacity uint) bucketOption {
+	return func(b bucket) {
+		switch bucket := b.(type) {
+		case *tokenBucket:
+			bucket.capacity = capacity
+			bucket.remaining = capacity
+		case windowBucket:
+			bucket.settings().capacity = capacity
+		}
+	}
+}
+
+func withRate(rate time.Duration) bucketOption {
+	return func(b bucket) {
+		switch bucket := b.(type) {
+		case *tokenBucket:
+			bucket.rate = rate
+		case windowBucket:
+			bucket.settings().rate = rate
+		}
+	}
+}
+
+// withWindow sets the length of the window, it's ignored by the token bucket
+func withWindow(window time.Duration) bucketOption {
+	return func(b bucket) {
+		if bucket, ok := b.(windowBucket); ok {
+			bucket.settings().window = window
+		}
+	}
+}
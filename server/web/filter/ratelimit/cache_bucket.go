@@ -0,0 +1,144 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/asish-tom/beego/v2/client/cache"
+	"github.com/asish-tom/beego/v2/core/logs"
+)
+
+// cacheBucket counts the requests of one session key in a cache shared by the instances.
+// The counters of the fixed windows are increased with Cache.Incr and expire with the window.
+// When sliding is true, the count of the previous window is weighted by its overlap with the sliding window.
+type cacheBucket struct {
+	windowSettings
+	ctx     context.Context
+	cache   cache.Cache
+	key     string
+	sliding bool
+}
+
+func (b *cacheBucket) getCapacity() uint {
+	return b.capacity
+}
+
+func (b *cacheBucket) getRate() time.Duration {
+	return b.rate
+}
+
+func (b *cacheBucket) getRemaining() uint {
+	now := time.Now()
+	used := b.count(now, b.get(b.windowKey(now, 0)))
+	if used >= float64(b.capacity) {
+		return 0
+	}
+	return b.capacity - uint(used)
+}
+
+// getReset returns 0, the counters are not stored in the limiter
+func (b *cacheBucket) getReset() time.Duration {
+	return 0
+}
+
+func (b *cacheBucket) take(amount uint) bool {
+	return b.allow(amount).allowed
+}
+
+func (b *cacheBucket) allow(amount uint) result {
+	res := result{allowed: true, limit: b.capacity, remaining: b.capacity}
+	if b.window <= 0 {
+		return res
+	}
+	now := time.Now()
+	current, err := b.incr(b.windowKey(now, 0), int64(amount))
+	if err != nil {
+		// the requests are accepted while the cache is unavailable
+		logs.Warn("ratelimit: increase the counter %s failed: %v", b.key, err)
+		return res
+	}
+	used := b.count(now, current)
+	end := now.Truncate(b.window).Add(b.window).Sub(now)
+	res.allowed = used <= float64(b.capacity)
+	if used < float64(b.capacity) {
+		res.remaining = b.capacity - uint(used)
+	} else {
+		res.remaining = 0
+	}
+	res.reset = end
+	if b.sliding {
+		res.reset += b.window
+	}
+	if !res.allowed {
+		res.retryAfter = end
+	}
+	return res
+}
+
+// count returns the number of requests in the window, current is the counter of the current fixed window
+func (b *cacheBucket) count(now time.Time, current int64) float64 {
+	used := float64(current)
+	if b.sliding {
+		elapsed := now.Sub(now.Truncate(b.window))
+		weight := 1 - float64(elapsed)/float64(b.window)
+		used += float64(b.get(b.windowKey(now, -1))) * weight
+	}
+	return used
+}
+
+// windowKey returns the key of the counter of the fixed window containing now, or of the previous ones
+func (b *cacheBucket) windowKey(now time.Time, offset int64) string {
+	index := now.UnixNano()/int64(b.window) + offset
+	return b.key + ":" + strconv.FormatInt(index, 10)
+}
+
+func (b *cacheBucket) get(key string) int64 {
+	v, err := b.cache.Get(b.ctx, key)
+	if err != nil || v == nil {
+		return 0
+	}
+	return cache.GetInt64(v)
+}
+
+// counterCache is implemented by the adapters increasing a counter and setting its expiration together,
+// eg. redis and ssdb
+type counterCache interface {
+	IncrBy(ctx context.Context, key string, n int64, timeout time.Duration) (int64, error)
+}
+
+// incr increases the counter and returns its value.
+// The counter must outlive the sliding window, so it expires after two windows.
+func (b *cacheBucket) incr(key string, amount int64) (int64, error) {
+	ttl := 2 * b.window
+	if c, ok := b.cache.(counterCache); ok {
+		return c.IncrBy(b.ctx, key, amount, ttl)
+	}
+	// the other adapters, eg. memory, file and memcache, don't create the missing counters
+	if err := b.cache.Incr(b.ctx, key); err != nil {
+		if err = b.cache.Put(b.ctx, key, amount, ttl); err != nil {
+			return 0, err
+		}
+		return amount, nil
+	}
+	for i := int64(1); i < amount; i++ {
+		if err := b.cache.Incr(b.ctx, key); err != nil {
+			return 0, err
+		}
+	}
+	return b.get(key), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// fixedWindowBucket counts the requests in fixed windows aligned on the clock,
+// the count is reset at the beginning of each window
+type fixedWindowBucket struct {
+	sync.Mutex
+	windowSettings
+	start time.Time
+	count uint
+}
+
+// newFixedWindowBucket return an bucket that implements fixed window counter
+func newFixedWindowBucket(opts ...bucketOption) bucket {
+	b := &fixedWindowBucket{}
+	for _, o := range opts {
+		o(b)
+	}
+	b.init()
+	return b
+}
+
+func (b *fixedWindowBucket) getCapacity() uint {
+	return b.capacity
+}
+
+func (b *fixedWindowBucket) getRate() time.Duration {
+	return b.rate
+}
+
+func (b *fixedWindowBucket) getRemaining() uint {
+	b.Lock()
+	defer b.Unlock()
+	b.roll(time.Now())
+	return b.capacity - b.count
+}
+
+func (b *fixedWindowBucket) getReset() time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	b.roll(now)
+	if b.count == 0 {
+		return 0
+	}
+	return b.start.Add(b.window).Sub(now)
+}
+
+func (b *fixedWindowBucket) take(amount uint) bool {
+	return b.allow(amount).allowed
+}
+
+func (b *fixedWindowBucket) allow(amount uint) result {
+	if b.window <= 0 {
+		return result{allowed: true, limit: b.capacity, remaining: b.capacity}
+	}
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	b.roll(now)
+	res := result{limit: b.capacity}
+	if b.count+amount <= b.capacity {
+		res.allowed = true
+		b.count += amount
+	}
+	res.remaining = b.capacity - b.count
+	if b.count > 0 {
+		res.reset = b.start.Add(b.window).Sub(now)
+	}
+	if !res.allowed {
+		res.retryAfter = b.start.Add(b.window).Sub(now)
+	}
+	return res
+}
+
+// roll starts a new window when the current one is over
+func (b *fixedWindowBucket) roll(now time.Time) {
+	if b.window <= 0 {
+		return
+	}
+	if start := now.Truncate(b.window); !start.Equal(b.start) {
+		b.start = start
+		b.count = 0
+	}
+}
@@ -0,0 +1,29 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedWindowBucket(t *testing.T) {
+	b := newFixedWindowBucket(withCapacity(2), withWindow(50*time.Millisecond))
+	assert.Equal(t, uint(2), b.getRemaining())
+
+	// start at the beginning of a window
+	time.Sleep(time.Until(time.Now().Truncate(50 * time.Millisecond).Add(50 * time.Millisecond)))
+	assert.True(t, b.take(1))
+	res := b.allow(1)
+	assert.True(t, res.allowed)
+	assert.Equal(t, uint(0), res.remaining)
+	res = b.allow(1)
+	assert.False(t, res.allowed)
+	assert.Equal(t, res.reset, res.retryAfter)
+	assert.True(t, res.retryAfter > 0 && res.retryAfter <= 50*time.Millisecond)
+	assert.True(t, b.getReset() > 0)
+
+	time.Sleep(res.retryAfter)
+	assert.Equal(t, time.Duration(0), b.getReset())
+	assert.True(t, b.take(1))
+}
@@ -15,9 +15,13 @@
 package ratelimit
 
 import (
+	"math"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/asish-tom/beego/v2/client/cache"
 	"github.com/asish-tom/beego/v2/server/web"
 	"github.com/asish-tom/beego/v2/server/web/context"
 )
@@ -29,12 +33,36 @@ type limiter struct {
 	sync.RWMutex
 	capacity      uint
 	rate          time.Duration
+	window        time.Duration
+	algorithm     Algorithm
 	buckets       map[string]bucket
 	bucketFactory func(opts ...bucketOption) bucket
 	sessionKey    func(ctx *context.Context) string
 	resp          RejectionResponse
+
+	// the buckets back to their full capacity are removed every evictionInterval
+	evictionInterval time.Duration
+	lastEviction     int64
+
+	cache       cache.Cache
+	cachePrefix string
 }
 
+// Algorithm is the algorithm limiting the requests of a session key
+type Algorithm string
+
+const (
+	// TokenBucket generates a token at the configured rate, a request takes one token.
+	// It allows bursts of capacity requests.
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindowLog logs the time of the requests,
+	// it allows capacity requests in any window.
+	SlidingWindowLog Algorithm = "sliding_window_log"
+	// FixedWindow counts the requests in windows aligned on the clock,
+	// it allows capacity requests per window, and up to twice as many across the boundary of two windows.
+	FixedWindow Algorithm = "fixed_window"
+)
+
 // RejectionResponse stores response information
 // for the request rejected by limiter
 type RejectionResponse struct {
@@ -53,19 +81,28 @@ var defaultRejectionResponse = RejectionResponse{
 // according to the configuration.
 func NewLimiter(opts ...limiterOption) web.FilterFunc {
 	l := &limiter{
-		buckets:       make(map[string]bucket),
-		sessionKey:    defaultSessionKey,
-		rate:          time.Millisecond * 10,
-		capacity:      100,
-		bucketFactory: newTokenBucket,
-		resp:          defaultRejectionResponse,
+		buckets:          make(map[string]bucket),
+		sessionKey:       defaultSessionKey,
+		rate:             time.Millisecond * 10,
+		capacity:         100,
+		bucketFactory:    newTokenBucket,
+		resp:             defaultRejectionResponse,
+		evictionInterval: time.Minute,
+		lastEviction:     time.Now().UnixNano(),
+		cachePrefix:      "beego:ratelimit:",
 	}
 	for _, o := range opts {
 		o(l)
 	}
 
 	return func(ctx *context.Context) {
-		if !l.take(perRequestConsumedAmount, ctx) {
+		res := l.getBucket(ctx).allow(perRequestConsumedAmount)
+		header := ctx.ResponseWriter.Header()
+		header.Set("RateLimit-Limit", strconv.FormatUint(uint64(res.limit), 10))
+		header.Set("RateLimit-Remaining", strconv.FormatUint(uint64(res.remaining), 10))
+		header.Set("RateLimit-Reset", seconds(res.reset))
+		if !res.allowed {
+			header.Set("Retry-After", seconds(res.retryAfter))
 			ctx.ResponseWriter.WriteHeader(l.resp.code)
 			ctx.WriteString(l.resp.body)
 		}
@@ -105,6 +142,60 @@ func WithBucketFactory(f func(opts ...bucketOption) bucket) limiterOption {
 	}
 }
 
+// WithAlgorithm return limiterOption. WithAlgorithm config the algorithm
+// limiting the requests, the default is TokenBucket.
+func WithAlgorithm(a Algorithm) limiterOption {
+	return func(l *limiter) {
+		l.algorithm = a
+		switch a {
+		case SlidingWindowLog:
+			l.bucketFactory = newSlidingWindowBucket
+		case FixedWindow:
+			l.bucketFactory = newFixedWindowBucket
+		default:
+			l.bucketFactory = newTokenBucket
+		}
+	}
+}
+
+// WithWindow return limiterOption. WithWindow config the length of the window
+// of SlidingWindowLog and FixedWindow, in which capacity requests are allowed.
+// The default window is capacity * rate, so that the algorithms allow the same average rate.
+func WithWindow(w time.Duration) limiterOption {
+	return func(l *limiter) {
+		l.window = w
+	}
+}
+
+// WithEvictionInterval return limiterOption. WithEvictionInterval config how often
+// the buckets of the idle session keys are removed, the default is 1 minute.
+// Only the buckets back to their full capacity are removed, so the limits are not affected.
+// Zero disables the eviction.
+func WithEvictionInterval(d time.Duration) limiterOption {
+	return func(l *limiter) {
+		l.evictionInterval = d
+	}
+}
+
+// WithCache return limiterOption. WithCache stores the counters in c, eg. a redis cache,
+// so that the instances of the application share the quota of each session key.
+// The counters are increased with Cache.Incr, the algorithm is FixedWindow unless it's SlidingWindowLog,
+// in which case the sliding window is approximated with the counters of the current and of the previous window.
+// The requests are accepted when the cache is unavailable.
+func WithCache(c cache.Cache) limiterOption {
+	return func(l *limiter) {
+		l.cache = c
+	}
+}
+
+// WithCachePrefix return limiterOption. WithCachePrefix config the prefix
+// of the counters stored by WithCache, the default is beego:ratelimit:
+func WithCachePrefix(prefix string) limiterOption {
+	return func(l *limiter) {
+		l.cachePrefix = prefix
+	}
+}
+
 // WithRejectionResponse return limiterOption. WithRejectionResponse
 // customize the response for the request rejected by the limiter.
 func WithRejectionResponse(resp RejectionResponse) limiterOption {
@@ -123,6 +214,19 @@ func (l *limiter) take(amount uint, ctx *context.Context) bool {
 
 func (l *limiter) getBucket(ctx *context.Context) bucket {
 	key := l.sessionKey(ctx)
+	if l.cache != nil {
+		// the counters are stored in the cache, the bucket is not kept
+		b := &cacheBucket{
+			windowSettings: windowSettings{capacity: l.capacity, rate: l.rate, window: l.window},
+			ctx:            ctx.Request.Context(),
+			cache:          l.cache,
+			key:            l.cachePrefix + key,
+			sliding:        l.algorithm == SlidingWindowLog,
+		}
+		b.init()
+		return b
+	}
+	l.evict()
 	l.RLock()
 	b, ok := l.buckets[key]
 	l.RUnlock()
@@ -141,23 +245,44 @@ func (l *limiter) createBucket(key string) bucket {
 	if ok {
 		return b
 	}
-	b = l.bucketFactory(withCapacity(l.capacity), withRate(l.rate))
+	b = l.bucketFactory(withCapacity(l.capacity), withRate(l.rate), withWindow(l.window))
 	l.buckets[key] = b
 	return b
 }
 
+// evict removes the buckets back to their full capacity, they are recreated by the next requests
+func (l *limiter) evict() {
+	if l.evictionInterval <= 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&l.lastEviction)
+	if now-last < int64(l.evictionInterval) || !atomic.CompareAndSwapInt64(&l.lastEviction, last, now) {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	for key, b := range l.buckets {
+		if b.getReset() == 0 {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// seconds formats d as a number of seconds rounded up
+func seconds(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
 func defaultSessionKey(ctx *context.Context) string {
 	return "BEEGO_ALL"
 }
 
+// RemoteIPSessionKey limits the requests per client IP,
+// the forwarding headers are only trusted when they are sent by the trusted proxies, see web.Config.TrustedProxies
 func RemoteIPSessionKey(ctx *context.Context) string {
-	r := ctx.Request
-	IPAddress := r.Header.Get("X-Real-Ip")
-	if IPAddress == "" {
-		IPAddress = r.Header.Get("X-Forwarded-For")
-	}
-	if IPAddress == "" {
-		IPAddress = r.RemoteAddr
-	}
-	return IPAddress
+	return ctx.Input.IP()
 }
@@ -1,11 +1,17 @@
 package ratelimit
 
 import (
+	gocontext "context"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/client/cache"
 	"github.com/asish-tom/beego/v2/server/web"
 	"github.com/asish-tom/beego/v2/server/web/context"
 )
@@ -39,6 +45,150 @@ func TestLimiter(t *testing.T) {
 	testRequest(t, handler, ip, "GET", route, 200)
 }
 
+func TestLimiterHeaders(t *testing.T) {
+	handler := web.NewControllerRegister()
+	err := handler.InsertFilter("*", web.BeforeRouter, NewLimiter(WithRate(time.Second), WithCapacity(2)))
+	require.NoError(t, err)
+	handler.Any("*", func(ctx *context.Context) {
+		ctx.Output.SetStatus(200)
+	})
+
+	r, _ := http.NewRequest("GET", "/foo", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
+
+func TestLimiterAlgorithm(t *testing.T) {
+	for _, a := range []Algorithm{TokenBucket, SlidingWindowLog, FixedWindow} {
+		handler := web.NewControllerRegister()
+		err := handler.InsertFilter("*", web.BeforeRouter, NewLimiter(WithAlgorithm(a), WithCapacity(1),
+			WithWindow(time.Minute), WithRate(time.Minute), WithSessionKey(RemoteIPSessionKey)))
+		require.NoError(t, err)
+		handler.Any("*", func(ctx *context.Context) {
+			ctx.Output.SetStatus(200)
+		})
+		testRequest(t, handler, "127.0.0.1", "GET", "/foo", 200)
+		testRequest(t, handler, "127.0.0.1", "GET", "/foo", 429)
+		testRequest(t, handler, "127.0.0.2", "GET", "/foo", 200)
+	}
+}
+
+func TestLimiterEviction(t *testing.T) {
+	l := &limiter{
+		buckets:          make(map[string]bucket),
+		sessionKey:       RemoteIPSessionKey,
+		rate:             time.Millisecond,
+		capacity:         1,
+		bucketFactory:    newTokenBucket,
+		evictionInterval: time.Millisecond,
+	}
+	newCtx := func(ip string) *context.Context {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = ip + ":1234"
+		ctx := context.NewContext()
+		ctx.Reset(httptest.NewRecorder(), r)
+		return ctx
+	}
+	assert.True(t, l.take(1, newCtx("127.0.0.1")))
+	assert.True(t, l.take(1, newCtx("127.0.0.2")))
+	assert.Len(t, l.buckets, 2)
+
+	time.Sleep(5 * time.Millisecond)
+	l.getBucket(newCtx("127.0.0.3"))
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestLimiterCache(t *testing.T) {
+	bm, err := cache.NewCache("memory", `{"interval":60}`)
+	require.NoError(t, err)
+
+	// two instances sharing the quota
+	handlers := make([]*web.ControllerRegister, 2)
+	for i := range handlers {
+		handlers[i] = web.NewControllerRegister()
+		err = handlers[i].InsertFilter("*", web.BeforeRouter, NewLimiter(WithCache(bm), WithCapacity(3),
+			WithWindow(time.Hour), WithSessionKey(RemoteIPSessionKey)))
+		require.NoError(t, err)
+		handlers[i].Any("*", func(ctx *context.Context) {
+			ctx.Output.SetStatus(200)
+		})
+	}
+	testRequest(t, handlers[0], "127.0.0.1", "GET", "/foo", 200)
+	testRequest(t, handlers[1], "127.0.0.1", "GET", "/foo", 200)
+	testRequest(t, handlers[0], "127.0.0.1", "GET", "/foo", 200)
+	testRequest(t, handlers[1], "127.0.0.1", "GET", "/foo", 429)
+	testRequest(t, handlers[0], "127.0.0.2", "GET", "/foo", 200)
+
+	r, _ := http.NewRequest("GET", "/foo", nil)
+	r.Header.Set("X-Real-Ip", "127.0.0.2")
+	w := httptest.NewRecorder()
+	handlers[1].ServeHTTP(w, r)
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+}
+
+func TestCacheBucketSliding(t *testing.T) {
+	bm, err := cache.NewCache("memory", `{"interval":60}`)
+	require.NoError(t, err)
+	b := &cacheBucket{
+		windowSettings: windowSettings{capacity: 10, window: time.Hour},
+		ctx:            gocontext.Background(),
+		cache:          bm,
+		key:            "test",
+		sliding:        true,
+	}
+	now := time.Now()
+	// the previous window is full
+	require.NoError(t, bm.Put(b.ctx, b.windowKey(now, -1), 10, time.Hour))
+	elapsed := now.Sub(now.Truncate(time.Hour))
+	expected := 10 - uint(math.Ceil(10*(1-float64(elapsed)/float64(time.Hour))))
+	assert.InDelta(t, expected, b.getRemaining(), 1)
+}
+
+// counterTestCache records the expirations given to IncrBy
+type counterTestCache struct {
+	cache.Cache
+	ttls []time.Duration
+}
+
+func (c *counterTestCache) IncrBy(ctx gocontext.Context, key string, n int64, timeout time.Duration) (int64, error) {
+	c.ttls = append(c.ttls, timeout)
+	v, err := c.Get(ctx, key)
+	if err != nil || v == nil {
+		return n, c.Put(ctx, key, n, timeout)
+	}
+	n += cache.GetInt64(v)
+	return n, c.Put(ctx, key, n, timeout)
+}
+
+func TestCacheBucketIncrBy(t *testing.T) {
+	bm, err := cache.NewCache("memory", `{"interval":60}`)
+	require.NoError(t, err)
+	c := &counterTestCache{Cache: bm}
+	b := &cacheBucket{
+		windowSettings: windowSettings{capacity: 3, window: time.Hour},
+		ctx:            gocontext.Background(),
+		cache:          c,
+		key:            "test",
+	}
+	assert.True(t, b.take(2))
+	assert.False(t, b.take(2))
+	// every increment sets the expiration of the counter
+	assert.Equal(t, []time.Duration{2 * time.Hour, 2 * time.Hour}, c.ttls)
+}
+
 func BenchmarkWithoutLimiter(b *testing.B) {
 	recorder := httptest.NewRecorder()
 	handler := web.NewControllerRegister()
@@ -0,0 +1,105 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// slidingWindowBucket logs the time of the requests accepted in the last window,
+// it never accepts more than capacity requests in any window
+type slidingWindowBucket struct {
+	sync.Mutex
+	windowSettings
+	log []time.Time
+}
+
+// newSlidingWindowBucket return an bucket that implements sliding window log
+func newSlidingWindowBucket(opts ...bucketOption) bucket {
+	b := &slidingWindowBucket{}
+	for _, o := range opts {
+		o(b)
+	}
+	b.init()
+	return b
+}
+
+func (b *slidingWindowBucket) getCapacity() uint {
+	return b.capacity
+}
+
+func (b *slidingWindowBucket) getRate() time.Duration {
+	return b.rate
+}
+
+func (b *slidingWindowBucket) getRemaining() uint {
+	b.Lock()
+	defer b.Unlock()
+	b.expire(time.Now())
+	return b.capacity - uint(len(b.log))
+}
+
+func (b *slidingWindowBucket) getReset() time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	b.expire(now)
+	if len(b.log) == 0 {
+		return 0
+	}
+	return b.log[len(b.log)-1].Add(b.window).Sub(now)
+}
+
+func (b *slidingWindowBucket) take(amount uint) bool {
+	return b.allow(amount).allowed
+}
+
+func (b *slidingWindowBucket) allow(amount uint) result {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	b.expire(now)
+	res := result{limit: b.capacity}
+	used := uint(len(b.log))
+	if amount <= b.capacity && used+amount <= b.capacity {
+		res.allowed = true
+		for i := uint(0); i < amount; i++ {
+			b.log = append(b.log, now)
+		}
+		used += amount
+	} else if amount <= b.capacity {
+		// wait for enough requests to leave the window
+		res.retryAfter = b.log[used+amount-b.capacity-1].Add(b.window).Sub(now)
+	} else {
+		res.retryAfter = b.window
+	}
+	res.remaining = b.capacity - used
+	if used > 0 {
+		res.reset = b.log[used-1].Add(b.window).Sub(now)
+	}
+	return res
+}
+
+// expire removes the requests which left the window
+func (b *slidingWindowBucket) expire(now time.Time) {
+	i := 0
+	for i < len(b.log) && !b.log[i].Add(b.window).After(now) {
+		i++
+	}
+	if i > 0 {
+		b.log = append(b.log[:0], b.log[i:]...)
+	}
+}
@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingWindowBucket(t *testing.T) {
+	b := newSlidingWindowBucket(withCapacity(2), withWindow(20*time.Millisecond))
+	assert.Equal(t, uint(2), b.getCapacity())
+	assert.Equal(t, time.Duration(0), b.getReset())
+
+	res := b.allow(1)
+	assert.True(t, res.allowed)
+	assert.Equal(t, uint(1), res.remaining)
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, b.take(1))
+	res = b.allow(1)
+	assert.False(t, res.allowed)
+	assert.Equal(t, uint(0), res.remaining)
+	// the first request leaves the window first
+	assert.True(t, res.retryAfter <= 10*time.Millisecond)
+	assert.True(t, res.reset > res.retryAfter)
+
+	time.Sleep(res.retryAfter + time.Millisecond)
+	assert.True(t, b.take(1))
+	assert.False(t, b.take(1))
+
+	// the default window is capacity * rate
+	b = newSlidingWindowBucket(withCapacity(10), withRate(time.Second))
+	assert.Equal(t, 10*time.Second, b.(*slidingWindowBucket).window)
+}
@@ -22,21 +22,6 @@ func newTokenBucket(opts ...bucketOption) bucket {
 	return b
 }
 
-func withCapacity(capacity uint) bucketOption {
-	return func(b bucket) {
-		bucket := b.(*tokenBucket)
-		bucket.capacity = capacity
-		bucket.remaining = capacity
-	}
-}
-
-func withRate(rate time.Duration) bucketOption {
-	return func(b bucket) {
-		bucket := b.(*tokenBucket)
-		bucket.rate = rate
-	}
-}
-
 func (b *tokenBucket) getRemaining() uint {
 	b.RLock()
 	defer b.RUnlock()
@@ -55,9 +40,27 @@ func (b *tokenBucket) getCapacity() uint {
 	return b.capacity
 }
 
+func (b *tokenBucket) getReset() time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	b.RLock()
+	defer b.RUnlock()
+	elapsed := time.Since(b.lastCheckAt)
+	remaining := b.remaining + uint(elapsed/b.rate)
+	if remaining >= b.capacity {
+		return 0
+	}
+	return b.rate - elapsed%b.rate + time.Duration(b.capacity-remaining-1)*b.rate
+}
+
 func (b *tokenBucket) take(amount uint) bool {
+	return b.allow(amount).allowed
+}
+
+func (b *tokenBucket) allow(amount uint) result {
 	if b.rate <= 0 {
-		return true
+		return result{allowed: true, limit: b.capacity, remaining: b.capacity}
 	}
 	b.Lock()
 	defer b.Unlock()
@@ -65,12 +68,21 @@ func (b *tokenBucket) take(amount uint) bool {
 	times := uint(now.Sub(b.lastCheckAt) / b.rate)
 	b.lastCheckAt = b.lastCheckAt.Add(time.Duration(times) * b.rate)
 	b.remaining += times
-	if b.remaining < amount {
-		return false
-	}
-	b.remaining -= amount
 	if b.remaining > b.capacity {
 		b.remaining = b.capacity
 	}
-	return true
+	res := result{allowed: b.remaining >= amount, limit: b.capacity}
+	if res.allowed {
+		b.remaining -= amount
+	}
+	res.remaining = b.remaining
+	// the next token is generated one rate after the last check
+	next := b.rate - now.Sub(b.lastCheckAt)
+	if b.remaining < b.capacity {
+		res.reset = next + time.Duration(b.capacity-b.remaining-1)*b.rate
+	}
+	if !res.allowed {
+		res.retryAfter = next + time.Duration(amount-b.remaining-1)*b.rate
+	}
+	return res
 }
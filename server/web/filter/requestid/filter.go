@@ -0,0 +1,134 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package requestid provides a filter giving an id to every request, so that its access log,
+// the messages logged with its context, its SQL statements and its outbound httplib requests can be correlated.
+//
+// The id sent by the client or the proxy in the X-Request-ID header is kept, otherwise a new one is generated.
+// It's sent back in the X-Request-ID header and stored in the context of the request with logs.WithRequestID.
+// Usage:
+//
+//	import(
+//		"github.com/asish-tom/beego/v2"
+//		"github.com/asish-tom/beego/v2/server/web/filter/requestid"
+//	)
+//
+//	func main(){
+//		// BeforeStatic gives an id to the requests of the static files too
+//		beego.InsertFilter("*", beego.BeforeStatic, requestid.NewFilter())
+//		beego.Run()
+//	}
+//
+// The handlers pass the context of the request to the loggers, the ORM and httplib:
+//
+//	logs.InfoContext(c.Ctx.Request.Context(), "order %d created", id)
+//	o.ReadWithCtx(c.Ctx.Request.Context(), &user)
+//	httplib.NewBeegoRequestWithCtx(c.Ctx.Request.Context(), url, "GET")
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+// HeaderName is the default header carrying the request id
+const HeaderName = "X-Request-ID"
+
+// maxLength is the max length of the ids sent by the clients
+const maxLength = 128
+
+// Option configures the filter
+type Option func(f *filter)
+
+// WithHeaderName sets the header carrying the request id, the default is X-Request-ID
+func WithHeaderName(name string) Option {
+	return func(f *filter) {
+		f.header = name
+	}
+}
+
+// WithGenerator sets the function generating the ids, the default one returns 16 random bytes in hex
+func WithGenerator(generate func() string) Option {
+	return func(f *filter) {
+		f.generate = generate
+	}
+}
+
+// WithoutIncoming ignores the ids sent by the clients, every request gets a new id.
+// It should be used when the clients are not trusted and there's no proxy setting the header
+func WithoutIncoming() Option {
+	return func(f *filter) {
+		f.trustIncoming = false
+	}
+}
+
+type filter struct {
+	header        string
+	generate      func() string
+	trustIncoming bool
+}
+
+// NewFilter returns the filter giving an id to the requests
+func NewFilter(opts ...Option) web.FilterFunc {
+	f := &filter{
+		header:        HeaderName,
+		generate:      newID,
+		trustIncoming: true,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f.serve
+}
+
+func (f *filter) serve(ctx *context.Context) {
+	id := ""
+	if f.trustIncoming {
+		id = ctx.Request.Header.Get(f.header)
+	}
+	if !valid(id) {
+		id = f.generate()
+	}
+	ctx.Request = ctx.Request.WithContext(logs.WithRequestID(ctx.Request.Context(), id))
+	ctx.Output.Header(f.header, id)
+}
+
+// Get returns the id of the request, or "" if the filter hasn't been applied
+func Get(ctx *context.Context) string {
+	return logs.RequestID(ctx.Request.Context())
+}
+
+// valid reports whether id can be logged and sent as is
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+func newID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
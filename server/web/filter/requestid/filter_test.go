@@ -0,0 +1,106 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/client/httplib"
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+func newHandler(opts ...Option) *web.ControllerRegister {
+	handler := web.NewControllerRegister()
+	handler.InsertFilter("*", web.BeforeRouter, NewFilter(opts...))
+	handler.Get("/id", func(ctx *context.Context) {
+		ctx.WriteString(Get(ctx) + "|" + logs.RequestID(ctx.Request.Context()))
+	})
+	return handler
+}
+
+func do(handler http.Handler, id string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(http.MethodGet, "/id", nil)
+	if id != "" {
+		r.Header.Set(HeaderName, id)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestFilter(t *testing.T) {
+	handler := newHandler()
+
+	w := do(handler, "")
+	id := w.Header().Get(HeaderName)
+	assert.Len(t, id, 32)
+	assert.Equal(t, id+"|"+id, w.Body.String())
+	assert.NotEqual(t, id, do(handler, "").Header().Get(HeaderName))
+
+	w = do(handler, "abc-123")
+	assert.Equal(t, "abc-123", w.Header().Get(HeaderName))
+	assert.Equal(t, "abc-123|abc-123", w.Body.String())
+
+	// the invalid ids are replaced
+	for _, invalid := range []string{"a b", "<script>", strings.Repeat("a", 129)} {
+		w = do(handler, invalid)
+		assert.Len(t, w.Header().Get(HeaderName), 32, invalid)
+	}
+}
+
+func TestFilterOptions(t *testing.T) {
+	handler := newHandler(WithoutIncoming(), WithGenerator(func() string {
+		return "generated"
+	}))
+	w := do(handler, "abc-123")
+	assert.Equal(t, "generated", w.Header().Get(HeaderName))
+	assert.Equal(t, "generated|generated", w.Body.String())
+
+	handler = newHandler(WithHeaderName("X-Correlation-ID"))
+	r, _ := http.NewRequest(http.MethodGet, "/id", nil)
+	r.Header.Set("X-Correlation-ID", "abc-123")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "abc-123", w.Header().Get("X-Correlation-ID"))
+}
+
+func TestFilterOutbound(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(httplib.RequestIDHeader)))
+	}))
+	defer upstream.Close()
+
+	handler := web.NewControllerRegister()
+	handler.InsertFilter("*", web.BeforeRouter, NewFilter())
+	handler.Get("/proxy", func(ctx *context.Context) {
+		body, err := httplib.NewBeegoRequestWithCtx(ctx.Request.Context(), upstream.URL, http.MethodGet).String()
+		require.NoError(t, err)
+		ctx.WriteString(body)
+	})
+
+	r, _ := http.NewRequest(http.MethodGet, "/proxy", nil)
+	r.Header.Set(HeaderName, "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "abc-123", w.Body.String())
+}
@@ -0,0 +1,243 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secure provides a FilterChain setting the security headers of the responses,
+// and redirecting the plain HTTP requests to HTTPS.
+//
+// Usage:
+//
+//	builder := secure.NewFilterChainBuilder(
+//		secure.WithHTTPSRedirect(""),
+//		secure.WithHSTS(365*24*time.Hour, true, false),
+//		secure.WithContentSecurityPolicy("default-src 'self'; script-src 'self' 'nonce-{nonce}'"),
+//	)
+//	web.InsertFilterChain("/*", builder.FilterChain)
+//
+// The {nonce} placeholder of the policy is replaced with a random value generated for each request.
+// The nonce is stored in the input data, so the templates rendered by the controllers read it as {{.CSPNonce}}:
+//
+//	<script nonce="{{.CSPNonce}}">...</script>
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+// NonceKey is the key of the nonce of the Content-Security-Policy in the input data
+const NonceKey = "CSPNonce"
+
+const noncePlaceholder = "{nonce}"
+
+// Option configures FilterChainBuilder
+type Option func(b *FilterChainBuilder)
+
+// WithContentSecurityPolicy sets the Content-Security-Policy header,
+// the {nonce} placeholders are replaced with the nonce of the request
+func WithContentSecurityPolicy(policy string) Option {
+	return func(b *FilterChainBuilder) {
+		b.csp = policy
+	}
+}
+
+// WithReportOnly sends the Content-Security-Policy, Cross-Origin-Opener-Policy and Cross-Origin-Embedder-Policy
+// in their report-only headers, so that the violations are reported without being enforced
+func WithReportOnly() Option {
+	return func(b *FilterChainBuilder) {
+		b.reportOnly = true
+	}
+}
+
+// WithHSTS sets the Strict-Transport-Security header of the HTTPS responses, a zero maxAge disables it
+func WithHSTS(maxAge time.Duration, includeSubdomains bool, preload bool) Option {
+	return func(b *FilterChainBuilder) {
+		b.hsts = ""
+		if maxAge <= 0 {
+			return
+		}
+		b.hsts = "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+		if includeSubdomains {
+			b.hsts += "; includeSubDomains"
+		}
+		if preload {
+			b.hsts += "; preload"
+		}
+	}
+}
+
+// WithFrameOptions sets the X-Frame-Options header, the default is SAMEORIGIN.
+// An empty value removes the header.
+func WithFrameOptions(value string) Option {
+	return func(b *FilterChainBuilder) {
+		b.frameOptions = value
+	}
+}
+
+// WithReferrerPolicy sets the Referrer-Policy header, the default is strict-origin-when-cross-origin.
+// An empty value removes the header.
+func WithReferrerPolicy(policy string) Option {
+	return func(b *FilterChainBuilder) {
+		b.referrerPolicy = policy
+	}
+}
+
+// WithPermissionsPolicy sets the Permissions-Policy header, eg. camera=(), geolocation=(self)
+func WithPermissionsPolicy(policy string) Option {
+	return func(b *FilterChainBuilder) {
+		b.permissionsPolicy = policy
+	}
+}
+
+// WithCrossOriginOpenerPolicy sets the Cross-Origin-Opener-Policy header, eg. same-origin
+func WithCrossOriginOpenerPolicy(policy string) Option {
+	return func(b *FilterChainBuilder) {
+		b.coop = policy
+	}
+}
+
+// WithCrossOriginEmbedderPolicy sets the Cross-Origin-Embedder-Policy header, eg. require-corp
+func WithCrossOriginEmbedderPolicy(policy string) Option {
+	return func(b *FilterChainBuilder) {
+		b.coep = policy
+	}
+}
+
+// WithoutContentTypeNosniff doesn't send X-Content-Type-Options: nosniff
+func WithoutContentTypeNosniff() Option {
+	return func(b *FilterChainBuilder) {
+		b.nosniff = false
+	}
+}
+
+// WithHTTPSRedirect redirects the plain HTTP requests to HTTPS,
+// host replaces the host of the request when it's not empty, eg. example.com:8443.
+// The scheme is read from X-Forwarded-Proto when the application is behind a proxy.
+func WithHTTPSRedirect(host string) Option {
+	return func(b *FilterChainBuilder) {
+		b.redirect = true
+		b.redirectHost = host
+	}
+}
+
+// FilterChainBuilder builds the FilterChain setting the security headers
+type FilterChainBuilder struct {
+	csp               string
+	reportOnly        bool
+	hsts              string
+	frameOptions      string
+	referrerPolicy    string
+	permissionsPolicy string
+	coop              string
+	coep              string
+	nosniff           bool
+	redirect          bool
+	redirectHost      string
+}
+
+// NewFilterChainBuilder returns a FilterChainBuilder sending X-Content-Type-Options: nosniff,
+// X-Frame-Options: SAMEORIGIN and Referrer-Policy: strict-origin-when-cross-origin by default
+func NewFilterChainBuilder(opts ...Option) *FilterChainBuilder {
+	b := &FilterChainBuilder{
+		frameOptions:   "SAMEORIGIN",
+		referrerPolicy: "strict-origin-when-cross-origin",
+		nosniff:        true,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// FilterChain sets the headers before calling next, so that next can override them
+func (b *FilterChainBuilder) FilterChain(next web.FilterFunc) web.FilterFunc {
+	return func(ctx *context.Context) {
+		secure := ctx.Input.IsSecure()
+		if b.redirect && !secure {
+			b.redirectToHTTPS(ctx)
+			return
+		}
+
+		header := ctx.ResponseWriter.Header()
+		if b.csp != "" {
+			policy := b.csp
+			if strings.Contains(policy, noncePlaceholder) {
+				nonce := newNonce()
+				ctx.Input.SetData(NonceKey, nonce)
+				policy = strings.ReplaceAll(policy, noncePlaceholder, nonce)
+			}
+			header.Set(b.reportOnlyName("Content-Security-Policy"), policy)
+		}
+		if b.hsts != "" && secure {
+			header.Set("Strict-Transport-Security", b.hsts)
+		}
+		if b.frameOptions != "" {
+			header.Set("X-Frame-Options", b.frameOptions)
+		}
+		if b.referrerPolicy != "" {
+			header.Set("Referrer-Policy", b.referrerPolicy)
+		}
+		if b.permissionsPolicy != "" {
+			header.Set("Permissions-Policy", b.permissionsPolicy)
+		}
+		if b.coop != "" {
+			header.Set(b.reportOnlyName("Cross-Origin-Opener-Policy"), b.coop)
+		}
+		if b.coep != "" {
+			header.Set(b.reportOnlyName("Cross-Origin-Embedder-Policy"), b.coep)
+		}
+		if b.nosniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+		next(ctx)
+	}
+}
+
+// Nonce returns the nonce of the Content-Security-Policy of the request
+func Nonce(ctx *context.Context) string {
+	nonce, _ := ctx.Input.GetData(NonceKey).(string)
+	return nonce
+}
+
+func (b *FilterChainBuilder) reportOnlyName(name string) string {
+	if b.reportOnly {
+		return name + "-Report-Only"
+	}
+	return name
+}
+
+// redirectToHTTPS keeps the method and the body of the unsafe requests with 308
+func (b *FilterChainBuilder) redirectToHTTPS(ctx *context.Context) {
+	host := b.redirectHost
+	if host == "" {
+		host = ctx.Request.Host
+	}
+	code := http.StatusMovedPermanently
+	if m := ctx.Request.Method; m != http.MethodGet && m != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(ctx.ResponseWriter, ctx.Request, "https://"+host+ctx.Request.URL.RequestURI(), code)
+}
+
+func newNonce() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/asish-tom/beego/v2/server/web"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+func newTestHandler(opts ...Option) *web.ControllerRegister {
+	builder := NewFilterChainBuilder(opts...)
+	handler := web.NewControllerRegister()
+	handler.InsertFilterChain("/*", builder.FilterChain)
+	handler.Any("/page", func(ctx *context.Context) {
+		_ = ctx.Output.Body([]byte(Nonce(ctx)))
+	})
+	handler.Init()
+	return handler
+}
+
+func do(handler http.Handler, method, url string, header ...string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestFilterChainDefault(t *testing.T) {
+	w := do(newTestHandler(), http.MethodGet, "http://example.com/page")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "SAMEORIGIN", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", w.Header().Get("Referrer-Policy"))
+	assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestFilterChainHeaders(t *testing.T) {
+	handler := newTestHandler(
+		WithContentSecurityPolicy("script-src 'self' 'nonce-{nonce}'; style-src 'nonce-{nonce}'"),
+		WithHSTS(365*24*time.Hour, true, true),
+		WithFrameOptions("DENY"),
+		WithReferrerPolicy(""),
+		WithPermissionsPolicy("camera=()"),
+		WithCrossOriginOpenerPolicy("same-origin"),
+		WithCrossOriginEmbedderPolicy("require-corp"),
+		WithoutContentTypeNosniff(),
+	)
+	w := do(handler, http.MethodGet, "http://example.com/page", "X-Forwarded-Proto", "https")
+	nonce := w.Body.String()
+	assert.Len(t, nonce, 24)
+	assert.Equal(t, "script-src 'self' 'nonce-"+nonce+"'; style-src 'nonce-"+nonce+"'", w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "max-age=31536000; includeSubDomains; preload", w.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Empty(t, w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "camera=()", w.Header().Get("Permissions-Policy"))
+	assert.Equal(t, "same-origin", w.Header().Get("Cross-Origin-Opener-Policy"))
+	assert.Equal(t, "require-corp", w.Header().Get("Cross-Origin-Embedder-Policy"))
+	assert.Empty(t, w.Header().Get("X-Content-Type-Options"))
+
+	// a new nonce for each request
+	w = do(handler, http.MethodGet, "http://example.com/page", "X-Forwarded-Proto", "https")
+	assert.NotEqual(t, nonce, w.Body.String())
+
+	// HSTS is only sent over HTTPS
+	w = do(handler, http.MethodGet, "http://example.com/page")
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+}
+
+func TestFilterChainReportOnly(t *testing.T) {
+	handler := newTestHandler(
+		WithContentSecurityPolicy("default-src 'self'; report-uri /csp"),
+		WithCrossOriginOpenerPolicy("same-origin"),
+		WithCrossOriginEmbedderPolicy("require-corp"),
+		WithReportOnly(),
+	)
+	w := do(handler, http.MethodGet, "http://example.com/page")
+	assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "default-src 'self'; report-uri /csp", w.Header().Get("Content-Security-Policy-Report-Only"))
+	assert.Equal(t, "same-origin", w.Header().Get("Cross-Origin-Opener-Policy-Report-Only"))
+	assert.Equal(t, "require-corp", w.Header().Get("Cross-Origin-Embedder-Policy-Report-Only"))
+	// no nonce without placeholder
+	assert.Empty(t, w.Body.String())
+}
+
+func TestFilterChainHTTPSRedirect(t *testing.T) {
+	handler := newTestHandler(WithHTTPSRedirect(""))
+	w := do(handler, http.MethodGet, "http://example.com/page?a=1")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://example.com/page?a=1", w.Header().Get("Location"))
+
+	w = do(handler, http.MethodPost, "http://example.com/page")
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+
+	// the proxy terminated TLS
+	w = do(handler, http.MethodGet, "http://example.com/page", "X-Forwarded-Proto", "https")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	handler = newTestHandler(WithHTTPSRedirect("secure.example.com:8443"))
+	w = do(handler, http.MethodGet, "http://example.com/page")
+	assert.True(t, strings.HasPrefix(w.Header().Get("Location"), "https://secure.example.com:8443/page"))
+}
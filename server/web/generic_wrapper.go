@@ -15,7 +15,14 @@
 package web
 
 import (
+	context2 "context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
 	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/core/validation"
 	"github.com/asish-tom/beego/v2/server/web/context"
 )
 
@@ -25,59 +32,350 @@ type bizFunc[T any] func(ctx *context.Context, param T) (any, error)
 // extractFunc is a function that extracts parameters from the context.
 type extractFunc[T any] func(ctx *context.Context) (params T, err error)
 
+// WrapperErrorRenderer writes the response of a failed wrapped request
+type WrapperErrorRenderer func(ctx *context.Context, status int, err error)
+
+// wrapperConfig holds the behaviour of the generic wrappers
+type wrapperConfig struct {
+	validate              bool
+	bindErrorStatus       int
+	validationErrorStatus int
+	bizErrorStatus        int
+	errorRenderer         WrapperErrorRenderer
+
+	// used by the OpenAPI document
+	binding      string
+	summary      string
+	description  string
+	tags         []string
+	responseType reflect.Type
+
+	// used by AddWrapper
+	routerOptions []ControllerOption
+}
+
+// WrapperOption configures the generic wrappers
+type WrapperOption func(cfg *wrapperConfig)
+
+// WithBindErrorStatus sets the status code used when the input can't be bound, 400 by default
+func WithBindErrorStatus(status int) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.bindErrorStatus = status
+	}
+}
+
+// WithValidationErrorStatus sets the status code used when the `valid` tags are not satisfied, 422 by default
+func WithValidationErrorStatus(status int) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.validationErrorStatus = status
+	}
+}
+
+// WithBizErrorStatus sets the status code used when the business function returns an error, 500 by default
+func WithBizErrorStatus(status int) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.bizErrorStatus = status
+	}
+}
+
+// WithErrorRenderer replaces DefaultWrapperErrorRenderer
+func WithErrorRenderer(renderer WrapperErrorRenderer) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.errorRenderer = renderer
+	}
+}
+
+// WithoutValidation skips the `valid` tags of the bound input
+func WithoutValidation() WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.validate = false
+	}
+}
+
+// WithSummary sets the summary of the operation in the OpenAPI document
+func WithSummary(summary string) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.summary = summary
+	}
+}
+
+// WithDescription sets the description of the operation in the OpenAPI document
+func WithDescription(description string) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.description = description
+	}
+}
+
+// WithTags sets the tags of the operation in the OpenAPI document
+func WithTags(tags ...string) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.tags = tags
+	}
+}
+
+// WithResponseType declares the type returned by the business function, eg. WithResponseType(User{}).
+// The business function returns any, so the OpenAPI document can't describe the response without it.
+func WithResponseType(v any) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.responseType = reflect.TypeOf(v)
+	}
+}
+
+// WithRouterOptions sets the options of the router registered by AddWrapper, eg. WithRouterTimeout
+func WithRouterOptions(opts ...ControllerOption) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.routerOptions = append(cfg.routerOptions, opts...)
+	}
+}
+
+// withBinding records how the wrapper binds its input, the options of the caller are applied afterwards
+func withBinding(binding string, opts []WrapperOption) []WrapperOption {
+	return append([]WrapperOption{func(cfg *wrapperConfig) {
+		cfg.binding = binding
+	}}, opts...)
+}
+
+func newWrapperConfig(opts []WrapperOption) *wrapperConfig {
+	cfg := &wrapperConfig{
+		validate:              true,
+		binding:               bindingAny,
+		bindErrorStatus:       http.StatusBadRequest,
+		validationErrorStatus: http.StatusUnprocessableEntity,
+		bizErrorStatus:        http.StatusInternalServerError,
+		errorRenderer:         DefaultWrapperErrorRenderer,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// FieldError describes one field which failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when the bound input doesn't satisfy its `valid` tags.
+// It is rendered as the JSON body of the error response.
+type ValidationError struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return e.Message + ": " + strings.Join(msgs, "; ")
+}
+
+// NewValidationError converts the errors of v, field names are taken from the json tags of obj
+func NewValidationError(v *validation.Validation, obj interface{}) *ValidationError {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	ve := &ValidationError{Message: "validation failed", Errors: make([]FieldError, 0, len(v.Errors))}
+	for _, e := range v.Errors {
+		ve.Errors = append(ve.Errors, FieldError{
+			Field:   jsonFieldName(t, e.Field),
+			Rule:    e.Name,
+			Message: strings.TrimSpace(e.Message),
+		})
+	}
+	return ve
+}
+
+// jsonFieldName returns the json name of the struct field, or field itself
+func jsonFieldName(t reflect.Type, field string) string {
+	if t == nil || t.Kind() != reflect.Struct {
+		return field
+	}
+	sf, ok := t.FieldByName(field)
+	if !ok {
+		return field
+	}
+	name := strings.Split(sf.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field
+	}
+	return name
+}
+
+// DefaultWrapperErrorRenderer renders a *ValidationError as JSON with the given status,
+// other errors abort the request so that the registered error handlers are used.
+// If EnableProblemJSON is true and the request accepts JSON, every error is rendered as problem+json,
+// the detail of the server errors is left empty unless the run mode is dev.
+func DefaultWrapperErrorRenderer(ctx *context.Context, status int, err error) {
+	if acceptsProblem(BConfig, ctx) {
+		p := ProblemFromError(status, err)
+		if ve, ok := err.(*ValidationError); ok {
+			p.Detail = ve.Message
+			p.Errors = ve.Errors
+		} else if status >= http.StatusInternalServerError && BConfig.RunMode != DEV {
+			// like the panics, the messages of the server errors are only shown in dev mode
+			p.Detail = ""
+		}
+		WriteProblem(ctx, p)
+		return
+	}
+	if ve, ok := err.(*ValidationError); ok {
+		ctx.Output.SetStatus(status)
+		if err = ctx.Output.JSON(ve, false, false); err != nil {
+			logs.Error("err {%v} happen in write validation error ", err)
+		}
+		return
+	}
+	ctx.Abort(status, err.Error())
+}
+
 // WrapperFromJson  for handling JSON in request's body.
 // It binds the JSON request body to the specified type T
 // Usage can see test cases : ExampleWrapperFromJson
 func WrapperFromJson[T any](
-	biz bizFunc[T]) func(ctx *context.Context) {
+	biz bizFunc[T], opts ...WrapperOption) func(ctx *context.Context) {
 	return internalWrapper(biz, func(ctx *context.Context) (params T, err error) {
 		err = ctx.BindJSON(&params)
 		return
-	})
+	}, withBinding(bindingJSON, opts)...)
 }
 
 // WrapperFromForm  for handling form data in request.
 // It binds the form data to the specified type T
 // Usage can see test cases : ExampleWrapperFromForm
 func WrapperFromForm[T any](
-	biz bizFunc[T]) func(ctx *context.Context) {
+	biz bizFunc[T], opts ...WrapperOption) func(ctx *context.Context) {
 	return internalWrapper(biz, func(ctx *context.Context) (params T, err error) {
 		err = ctx.BindForm(&params)
 		return
-	})
+	}, withBinding(bindingForm, opts)...)
 }
 
 // Wrapper is use by beego ctx.Bind(any) api
 // It binds the data to the specified type T
 // Usage can see test cases: ExampleWrapper
 func Wrapper[T any](
-	biz bizFunc[T]) func(ctx *context.Context) {
+	biz bizFunc[T], opts ...WrapperOption) func(ctx *context.Context) {
 	return internalWrapper(biz, func(ctx *context.Context) (params T, err error) {
 		err = ctx.Bind(&params)
 		return
-	})
+	}, withBinding(bindingAny, opts)...)
 }
 
+// internalWrapper binds and validates the input before calling biz.
+// The `valid` tags of core/validation are checked when T is a struct or a struct pointer.
 func internalWrapper[T any](
 	biz bizFunc[T],
-	ef extractFunc[T]) func(ctx *context.Context) {
+	ef extractFunc[T], opts ...WrapperOption) func(ctx *context.Context) {
+	cfg := newWrapperConfig(opts)
 	return func(ctx *context.Context) {
 		params, err := ef(ctx)
 		if err != nil {
 			logs.Error("err {%v} happen in subject ctx ", err)
-			ctx.Abort(400, err.Error())
+			cfg.errorRenderer(ctx, cfg.bindErrorStatus, err)
 			return
 		}
+		if cfg.validate {
+			if err = validate(params); err != nil {
+				status := cfg.validationErrorStatus
+				if _, ok := err.(*ValidationError); !ok {
+					status = http.StatusInternalServerError
+				}
+				cfg.errorRenderer(ctx, status, err)
+				return
+			}
+		}
 		res, err := biz(ctx, params)
 		if err != nil {
 			logs.Error("err {%v} happen in biz ", err)
-			ctx.Abort(500, err.Error())
+			status := cfg.bizErrorStatus
+			if _, ok := err.(*ValidationError); ok {
+				status = cfg.validationErrorStatus
+			} else if errors.Is(err, context2.DeadlineExceeded) {
+				// the deadline set by WithRouterTimeout or NSTimeout
+				status = http.StatusGatewayTimeout
+			}
+			cfg.errorRenderer(ctx, status, err)
 			return
 		}
 		err = ctx.Resp(res)
+		if errors.Is(err, context.ErrNotAcceptable) {
+			return
+		}
 		if err != nil {
 			logs.Error("err {%v} happen in write response ", err)
 			panic(err)
 		}
 	}
 }
+
+// validate checks the `valid` tags of params and of its struct fields,
+// a *ValidationError is returned when they are not satisfied
+func validate(params interface{}) error {
+	rv := reflect.ValueOf(params)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs []FieldError
+	if err := validateStruct(rv, "", &errs); err != nil {
+		logs.Error("err {%v} happen in validation ", err)
+		return err
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Message: "validation failed", Errors: errs}
+	}
+	return nil
+}
+
+// validateStruct validates the struct rv, then its struct fields, the nil pointers are skipped.
+// The fields of the errors are the json paths from the params, eg. address.city
+func validateStruct(rv reflect.Value, prefix string, errs *[]FieldError) error {
+	t := rv.Type()
+	v := &validation.Validation{}
+	if _, err := v.Valid(rv.Interface()); err != nil {
+		return err
+	}
+	if v.HasErrors() {
+		for _, e := range v.Errors {
+			*errs = append(*errs, FieldError{
+				Field:   prefix + jsonFieldName(t, e.Field),
+				Rule:    e.Name,
+				Message: strings.TrimSpace(e.Message),
+			})
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := rv.Field(i)
+		if !f.CanInterface() {
+			continue
+		}
+		if f.Kind() == reflect.Ptr {
+			// the optional structs which are not sent
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		if f.Kind() != reflect.Struct {
+			continue
+		}
+		path := prefix
+		// the fields of the embedded structs are inlined by encoding/json
+		if sf := t.Field(i); !sf.Anonymous || strings.Split(sf.Tag.Get("json"), ",")[0] != "" {
+			path += jsonFieldName(t, sf.Name) + "."
+		}
+		if err := validateStruct(f, path, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -278,6 +278,117 @@ func TestAllWrapperTestCase(t *testing.T) {
 
 }
 
+type signupRequest struct {
+	Name  string `json:"name" form:"name" valid:"Required"`
+	Email string `json:"email" form:"email" valid:"Required;Email"`
+	Age   int    `json:"age" form:"age" valid:"Range(1, 140)"`
+}
+
+func signup(_ *context.Context, params signupRequest) (any, error) {
+	return params.Name, nil
+}
+
+type shippingAddress struct {
+	City string `json:"city" valid:"Required"`
+}
+
+type orderRequest struct {
+	Item    string           `json:"item" valid:"Required"`
+	Address *shippingAddress `json:"shipping_address"`
+	Billing shippingAddress  `json:"billing"`
+}
+
+func order(_ *context.Context, params orderRequest) (any, error) {
+	return params.Item, nil
+}
+
+func TestWrapperValidation(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		handler      HandleFunc
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "valid input",
+			body:         `{"name": "rose", "email": "rose@beego.dev", "age": 17}`,
+			handler:      WrapperFromJson(signup),
+			expectedCode: http.StatusOK,
+			expectedBody: `"rose"`,
+		},
+		{
+			name:         "invalid input",
+			body:         `{"name": "", "email": "rose", "age": 170}`,
+			handler:      WrapperFromJson(signup),
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"message":"validation failed","errors":[` +
+				`{"field":"name","rule":"Required","message":"Name Can not be empty"},` +
+				`{"field":"email","rule":"Email","message":"Email Must be a valid email address"},` +
+				`{"field":"age","rule":"Range","message":"Age Range is 1 to 140"}]}`,
+		},
+		{
+			name:         "custom status",
+			body:         `{"name": "rose", "email": "rose", "age": 17}`,
+			handler:      Wrapper(signup, WithValidationErrorStatus(http.StatusBadRequest)),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"message":"validation failed","errors":[` +
+				`{"field":"email","rule":"Email","message":"Email Must be a valid email address"}]}`,
+		},
+		{
+			name:         "omitted optional struct",
+			body:         `{"item": "book", "billing": {"city": "Paris"}}`,
+			handler:      WrapperFromJson(order),
+			expectedCode: http.StatusOK,
+			expectedBody: `"book"`,
+		},
+		{
+			name:         "invalid nested structs",
+			body:         `{"item": "book", "shipping_address": {"city": ""}}`,
+			handler:      WrapperFromJson(order),
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"message":"validation failed","errors":[` +
+				`{"field":"shipping_address.city","rule":"Required","message":"City Can not be empty"},` +
+				`{"field":"billing.city","rule":"Required","message":"City Can not be empty"}]}`,
+		},
+		{
+			name:         "validation disabled",
+			body:         `{"name": "rose", "email": "rose", "age": 17}`,
+			handler:      Wrapper(signup, WithoutValidation()),
+			expectedCode: http.StatusOK,
+			expectedBody: `"rose"`,
+		},
+		{
+			name: "custom renderer",
+			body: `{"name": "rose"`,
+			handler: WrapperFromJson(signup, WithBindErrorStatus(http.StatusNotAcceptable),
+				WithErrorRenderer(func(ctx *context.Context, status int, err error) {
+					ctx.Output.SetStatus(status)
+					_ = ctx.Output.Body([]byte("bad input"))
+				})),
+			expectedCode: http.StatusNotAcceptable,
+			expectedBody: "bad input",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := NewHttpServerWithCfg(newBConfig())
+			app.Cfg.CopyRequestBody = true
+			app.Post(sendUrl, tc.handler)
+
+			req := httptest.NewRequest("POST", sendUrl, strings.NewReader(tc.body))
+			req.Header.Set(contentType, context.ApplicationJSON)
+			req.Header.Set(accept, "*/*")
+			w := httptest.NewRecorder()
+			app.Handlers.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			assert.Equal(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
 type userInfo struct {
 	ID       int
 	Username string
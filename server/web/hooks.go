@@ -87,6 +87,17 @@ func registerTemplate() error {
 	return nil
 }
 
+func registerOpenAPI() error {
+	if cfg := BConfig.WebConfig.OpenAPI; cfg.EnableOpenAPI && !cfg.OpenAPIOnAdmin {
+		serveOpenAPI(BeeApp, BeeApp.Handlers, cfg)
+	}
+	return nil
+}
+
+func registerTrustedProxies() error {
+	return context.SetTrustedProxies(BConfig.TrustedProxies)
+}
+
 func registerGzip() error {
 	if BConfig.EnableGzip {
 		context.InitGzip(
@@ -94,6 +105,10 @@ func registerGzip() error {
 			AppConfig.DefaultInt("gzipCompressLevel", -1),
 			AppConfig.DefaultStrings("includedMethods", []string{"GET"}),
 		)
+		context.InitCompressTypes(
+			AppConfig.DefaultStrings("gzipIncludeTypes", nil),
+			AppConfig.DefaultStrings("gzipExcludeTypes", nil),
+		)
 	}
 	return nil
 }
@@ -0,0 +1,380 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/core/utils"
+	"github.com/asish-tom/beego/v2/server/web/grace"
+)
+
+// Listener accepts the connections of HttpServer on one transport.
+// HttpServer.Run serves all the listeners and returns when one of them stops,
+// implement it and call AddListener to serve the application over other transports, eg. QUIC.
+type Listener interface {
+	// Serve blocks until the listener stops,
+	// handler is the application wrapped by the middlewares passed to Run
+	Serve(app *HttpServer, handler http.Handler) error
+	// Shutdown stops accepting connections and waits for the active requests
+	Shutdown(ctx context.Context) error
+}
+
+// AddListener adds a listener served by Run besides those enabled by the Listen config
+func (app *HttpServer) AddListener(l Listener) *HttpServer {
+	app.Listeners = append(app.Listeners, l)
+	return app
+}
+
+// AddListener see HttpServer.AddListener
+func AddListener(l Listener) *HttpServer {
+	return BeeApp.AddListener(l)
+}
+
+// listeners returns the listeners enabled by the Listen config, addr is the HTTP address
+func (app *HttpServer) listeners(addr string) []Listener {
+	cfg := app.Cfg.Listen
+	if cfg.EnableFcgi {
+		return []Listener{&fcgiListener{addr: addr, stdio: cfg.EnableStdIo, unix: cfg.HTTPPort == 0}}
+	}
+	network := "tcp"
+	if cfg.ListenTCP4 {
+		network = "tcp4"
+	}
+	httpsAddr := cfg.HTTPSAddr
+	if cfg.HTTPSPort != 0 {
+		httpsAddr = fmt.Sprintf("%s:%d", cfg.HTTPSAddr, cfg.HTTPSPort)
+	}
+
+	var res []Listener
+	if cfg.EnableHTTPS || cfg.EnableMutualHTTPS {
+		if cfg.Graceful {
+			res = append(res, &graceListener{addr: httpsAddr, network: "tcp", tls: true})
+		} else if cfg.HTTPSPort == 0 && cfg.EnableHTTP {
+			logs.Info("Start https server error, conflict with http. Please reset https port")
+		} else {
+			res = append(res, &httpsListener{addr: httpsAddr})
+		}
+	}
+	if cfg.EnableHTTP {
+		if cfg.Graceful {
+			res = append(res, &graceListener{addr: addr, network: network})
+		} else {
+			res = append(res, &httpListener{addr: addr, network: network})
+		}
+	}
+	return res
+}
+
+// serve runs the listeners and waits for the first one to stop,
+// if the server is shutting down, it waits for the shutdown pipeline too
+func (app *HttpServer) serve(handler http.Handler, listeners []Listener) {
+	app.mu.Lock()
+	app.running = listeners
+	app.mu.Unlock()
+
+	endRunning := make(chan bool, len(listeners))
+	for _, l := range listeners {
+		go func(l Listener) {
+			if err := l.Serve(app, handler); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logs.Critical("Serve: ", err, fmt.Sprintf("%d", os.Getpid()))
+			}
+			endRunning <- true
+		}(l)
+	}
+	// the grace module handles the signals itself, see graceListener
+	if app.Cfg.Listen.ShutdownOnSignal && !app.Cfg.Listen.Graceful {
+		stopped := make(chan struct{})
+		defer close(stopped)
+		go app.shutdownOnSignal(stopped)
+	}
+	<-endRunning
+
+	if app.stopping.Load() {
+		if err := app.Shutdown(context.Background()); err != nil {
+			logs.Error("shutdown: %v", err)
+		}
+	}
+}
+
+// h2cHandler serves HTTP/2 requests over cleartext connections, either with prior knowledge
+// or upgraded from HTTP/1.1. Requests received over TLS are served by handler directly.
+// The HTTP/2 server is configured on server, so it gets the same timeouts
+// and its connections are closed gracefully when server shuts down.
+func (app *HttpServer) h2cHandler(server *http.Server, handler http.Handler) (http.Handler, error) {
+	h2s := &http2.Server{}
+	if err := http2.ConfigureServer(server, h2s); err != nil {
+		return nil, err
+	}
+	h2cHandler := h2c.NewHandler(handler, h2s)
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			handler.ServeHTTP(rw, r)
+			return
+		}
+		// an h2c connection is served until it's closed
+		app.h2cConns.Add(1)
+		defer app.h2cConns.Add(-1)
+		h2cHandler.ServeHTTP(rw, r)
+	}), nil
+}
+
+// waitH2CConns waits for the h2c connections to be closed
+func (app *HttpServer) waitH2CConns(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for app.h2cConns.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// autoTLSConfig returns the TLS config getting certificates from Let's Encrypt
+func (app *HttpServer) autoTLSConfig() *tls.Config {
+	m := autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(app.Cfg.Listen.Domains...),
+		Cache:      autocert.DirCache(app.Cfg.Listen.TLSCacheDir),
+	}
+	app.Cfg.Listen.HTTPSCertFile, app.Cfg.Listen.HTTPSKeyFile = "", ""
+	return &tls.Config{GetCertificate: m.GetCertificate}
+}
+
+// httpListener serves HTTP with app.Server
+type httpListener struct {
+	addr    string
+	network string
+	server  *http.Server
+}
+
+func (l *httpListener) Serve(app *HttpServer, handler http.Handler) error {
+	l.server = app.Server
+	ln, err := net.Listen(l.network, l.addr)
+	if err != nil {
+		return fmt.Errorf("listen for HTTP[normal mode]: %w", err)
+	}
+	logs.Info("http server Running on http://%s", ln.Addr())
+	return app.Server.Serve(ln)
+}
+
+func (l *httpListener) Shutdown(ctx context.Context) error {
+	if l.server == nil {
+		return nil
+	}
+	return l.server.Shutdown(ctx)
+}
+
+// httpsListener serves HTTPS or mutual TLS with app.Server
+type httpsListener struct {
+	addr   string
+	server *http.Server
+}
+
+func (l *httpsListener) Serve(app *HttpServer, handler http.Handler) error {
+	l.server = app.Server
+	cfg := &app.Cfg.Listen
+	// the config may be set up for HTTP/2 already, see h2cHandler
+	if app.Server.TLSConfig == nil {
+		app.Server.TLSConfig = &tls.Config{}
+	}
+	if cfg.AutoTLS {
+		app.Server.TLSConfig.GetCertificate = app.autoTLSConfig().GetCertificate
+	} else if cfg.EnableMutualHTTPS {
+		data, err := os.ReadFile(cfg.TrustCaFile)
+		if err != nil {
+			return fmt.Errorf("MutualHTTPS should provide TrustCaFile: %w", err)
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(data)
+		app.Server.TLSConfig.ClientCAs = pool
+		app.Server.TLSConfig.ClientAuth = tls.ClientAuthType(cfg.ClientAuth)
+	}
+	ln, err := net.Listen("tcp", l.addr)
+	if err != nil {
+		return fmt.Errorf("listen for HTTPS: %w", err)
+	}
+	logs.Info("https server Running on https://%s", ln.Addr())
+	return app.Server.ServeTLS(ln, cfg.HTTPSCertFile, cfg.HTTPSKeyFile)
+}
+
+func (l *httpsListener) Shutdown(ctx context.Context) error {
+	if l.server == nil {
+		return nil
+	}
+	return l.server.Shutdown(ctx)
+}
+
+// graceListener serves HTTP or HTTPS with a grace.Server which can be restarted without dropping connections
+type graceListener struct {
+	addr    string
+	network string
+	tls     bool
+
+	mu     sync.Mutex
+	server *grace.Server
+}
+
+func (l *graceListener) Serve(app *HttpServer, handler http.Handler) error {
+	var opts []grace.ServerOption
+	for _, lifeCycleCallback := range app.LifeCycleCallbacks {
+		lifeCycleCallbackDup := lifeCycleCallback
+		opts = append(opts, grace.WithShutdownCallback(func() {
+			lifeCycleCallbackDup.BeforeShutdown(app)
+		}))
+	}
+	server := grace.NewServer(l.addr, handler, opts...)
+	if app.Cfg.Listen.ShutdownOnSignal {
+		// grace shuts the server down on these signals, drain the requests before,
+		// serve runs the rest of the shutdown pipeline when the server stops
+		for _, sig := range []os.Signal{syscall.SIGINT, syscall.SIGTERM} {
+			_ = server.RegisterSignalHook(grace.PreSignal, sig, func() {
+				app.drain(context.Background())
+			})
+		}
+	}
+	server.Server.ReadTimeout = app.Server.ReadTimeout
+	server.Server.WriteTimeout = app.Server.WriteTimeout
+	if app.Cfg.Listen.EnableH2C && !l.tls {
+		h, err := app.h2cHandler(server.Server, handler)
+		if err != nil {
+			return fmt.Errorf("h2c: %w", err)
+		}
+		server.Server.Handler = h
+	}
+	server.Network = l.network
+	l.mu.Lock()
+	l.server = server
+	l.mu.Unlock()
+
+	var (
+		ln  net.Listener
+		err error
+	)
+	cfg := &app.Cfg.Listen
+	switch {
+	case !l.tls:
+		ln, err = net.Listen(server.Network, server.Addr)
+		if err != nil {
+			return fmt.Errorf("listen for HTTP[graceful mode]: %w", err)
+		}
+		logs.Info("graceful http server Running on http://%s", server.Addr)
+	case cfg.EnableMutualHTTPS:
+		if ln, err = server.ListenMutualTLS(cfg.HTTPSCertFile, cfg.HTTPSKeyFile, cfg.TrustCaFile); err != nil {
+			return fmt.Errorf("ListenMutualTLS: %w", err)
+		}
+	default:
+		if cfg.AutoTLS {
+			server.Server.TLSConfig = app.autoTLSConfig()
+		}
+		if ln, err = server.ListenTLS(cfg.HTTPSCertFile, cfg.HTTPSKeyFile); err != nil {
+			return fmt.Errorf("ListenTLS: %w", err)
+		}
+	}
+	for _, callback := range app.LifeCycleCallbacks {
+		callback.AfterStart(app)
+	}
+	if l.tls {
+		return server.ServeTLS(ln)
+	}
+	return server.ServeWithListener(ln)
+}
+
+func (l *graceListener) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	server := l.server
+	l.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// fcgiListener serves FastCGI over a unix socket, TCP or the standard I/O
+type fcgiListener struct {
+	addr  string
+	stdio bool
+	unix  bool
+
+	mu sync.Mutex
+	ln net.Listener
+}
+
+func (l *fcgiListener) Serve(app *HttpServer, handler http.Handler) error {
+	for _, lifeCycleCallback := range app.LifeCycleCallbacks {
+		lifeCycleCallback.AfterStart(app)
+	}
+	defer func() {
+		for _, lifeCycleCallback := range app.LifeCycleCallbacks {
+			lifeCycleCallback.BeforeShutdown(app)
+		}
+	}()
+	if l.stdio {
+		if err := fcgi.Serve(nil, handler); err != nil {
+			return fmt.Errorf("cannot use FCGI via standard I/O: %w", err)
+		}
+		logs.Info("Use FCGI via standard I/O")
+		return nil
+	}
+
+	var (
+		ln  net.Listener
+		err error
+	)
+	if l.unix {
+		// remove the Socket file before start
+		if utils.FileExists(l.addr) {
+			os.Remove(l.addr)
+		}
+		ln, err = net.Listen("unix", l.addr)
+	} else {
+		ln, err = net.Listen("tcp", l.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("listen for Fcgi: %w", err)
+	}
+	l.mu.Lock()
+	l.ln = ln
+	l.mu.Unlock()
+	return fcgi.Serve(ln, handler)
+}
+
+func (l *fcgiListener) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ln == nil {
+		return nil
+	}
+	return l.ln.Close()
+}
@@ -0,0 +1,175 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+type testListener struct {
+	served chan http.Handler
+	stop   chan struct{}
+}
+
+func (l *testListener) Serve(app *HttpServer, handler http.Handler) error {
+	l.served <- handler
+	<-l.stop
+	return http.ErrServerClosed
+}
+
+func (l *testListener) Shutdown(ctx context.Context) error {
+	close(l.stop)
+	return nil
+}
+
+func TestHttpServerListeners(t *testing.T) {
+	cfg := newBConfig()
+	app := NewHttpServerWithCfg(cfg)
+	ls := app.listeners(":8080")
+	require.Len(t, ls, 1)
+	assert.Equal(t, &httpListener{addr: ":8080", network: "tcp"}, ls[0])
+
+	cfg.Listen.EnableHTTPS = true
+	cfg.Listen.ListenTCP4 = true
+	ls = app.listeners(":8080")
+	require.Len(t, ls, 2)
+	assert.Equal(t, &httpsListener{addr: ":10443"}, ls[0])
+	assert.Equal(t, &httpListener{addr: ":8080", network: "tcp4"}, ls[1])
+
+	// https conflicts with http on the same port
+	cfg.Listen.HTTPSPort = 0
+	assert.Len(t, app.listeners(":8080"), 1)
+
+	cfg.Listen.Graceful = true
+	ls = app.listeners(":8080")
+	require.Len(t, ls, 2)
+	assert.True(t, ls[0].(*graceListener).tls)
+	assert.False(t, ls[1].(*graceListener).tls)
+
+	cfg.Listen.EnableFcgi = true
+	ls = app.listeners("/tmp/beego.sock")
+	require.Len(t, ls, 1)
+	assert.IsType(t, &fcgiListener{}, ls[0])
+}
+
+func TestHttpServerServe(t *testing.T) {
+	app := NewHttpServerWithCfg(newBConfig())
+	l1 := &testListener{served: make(chan http.Handler, 1), stop: make(chan struct{})}
+	l2 := &testListener{served: make(chan http.Handler, 1), stop: make(chan struct{})}
+	app.AddListener(l1).AddListener(l2)
+
+	done := make(chan struct{})
+	go func() {
+		app.serve(app.Handlers, app.Listeners)
+		close(done)
+	}()
+	assert.Equal(t, http.Handler(app.Handlers), <-l1.served)
+	assert.Equal(t, http.Handler(app.Handlers), <-l2.served)
+
+	// Run returns as soon as one listener stops
+	require.NoError(t, l1.Shutdown(context.Background()))
+	<-done
+	require.NoError(t, l2.Shutdown(context.Background()))
+}
+
+func TestH2CHandler(t *testing.T) {
+	app := &HttpServer{}
+	server := httptest.NewUnstartedServer(nil)
+	h, err := app.h2cHandler(server.Config, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(rw, r.Proto)
+	}))
+	require.NoError(t, err)
+	server.Config.Handler = h
+	server.Start()
+	defer server.Close()
+
+	// prior knowledge
+	client := &http.Client{Transport: newH2CTransport()}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", string(body))
+
+	// HTTP/1.1 clients are still served
+	resp, err = http.Get(server.URL)
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", string(body))
+}
+
+func TestH2CHandlerShutdown(t *testing.T) {
+	app := &HttpServer{}
+	started, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewUnstartedServer(nil)
+	h, err := app.h2cHandler(server.Config, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(rw, "done")
+	}))
+	require.NoError(t, err)
+	server.Config.Handler = h
+	server.Start()
+	defer server.Close()
+
+	client := &http.Client{Transport: newH2CTransport()}
+	done := make(chan string)
+	go func() {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		done <- string(body)
+	}()
+	<-started
+
+	// the connection is hijacked, so the server doesn't wait for it
+	require.NoError(t, server.Config.Shutdown(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, app.waitH2CConns(ctx), context.DeadlineExceeded)
+
+	// the active stream completes, then the connection is closed by GOAWAY
+	close(release)
+	assert.Equal(t, "done", <-done)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, app.waitH2CConns(ctx))
+}
+
+func newH2CTransport() *http2.Transport {
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
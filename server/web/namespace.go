@@ -17,8 +17,10 @@ package web
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	beecontext "github.com/asish-tom/beego/v2/server/web/context"
+	"github.com/asish-tom/beego/v2/server/web/ws"
 )
 
 type namespaceCond func(*beecontext.Context) bool
@@ -30,6 +32,18 @@ type LinkNamespace func(*Namespace)
 type Namespace struct {
 	prefix   string
 	handlers *ControllerRegister
+
+	// FilterChains of the namespace and of its nested namespaces,
+	// their patterns are relative to the prefix
+	filterChains []filterChainConfig
+	nestedChains []filterChainConfig
+
+	// timeout of the routers without their own timeout
+	timeout time.Duration
+	// body limits of the routers without their own limits
+	maxMemory     int64
+	maxUploadSize int64
+	streamBody    bool
 }
 
 // NewNamespace get new Namespace
@@ -99,6 +113,127 @@ func (n *Namespace) Filter(action string, filter ...FilterFunc) *Namespace {
 	return n
 }
 
+// InsertFilterChain adds a FilterChain which only applies to the routers of the namespace,
+// including its nested namespaces. pattern is relative to the prefix, "*" matches the whole namespace.
+// The chains of a namespace always wrap the ones of its nested namespaces, whatever the declaration order,
+// and they run after the chains inserted into the application before AddNamespace.
+// usage:
+//
+//	ns := beego.NewNamespace("/v1",
+//		beego.NSInsertFilterChain("*", authChain),
+//		beego.NSNamespace("/shop",
+//			beego.NSInsertFilterChain("/orders/*", tracingChain),
+//		),
+//	)
+func (n *Namespace) InsertFilterChain(pattern string, chain FilterChain, opts ...FilterOpt) *Namespace {
+	opts = append([]FilterOpt{WithCaseSensitive(n.handlers.cfg.RouterCaseSensitive)}, opts...)
+	n.filterChains = append(n.filterChains, filterChainConfig{
+		pattern: pattern,
+		chain:   chain,
+		opts:    opts,
+	})
+	return n
+}
+
+// MiddleWare adds http.Handler middlewares to the whole namespace, see InsertFilterChain
+func (n *Namespace) MiddleWare(mws ...MiddleWare) *Namespace {
+	for _, mw := range mws {
+		n.InsertFilterChain("*", middleWareChain(mw))
+	}
+	return n
+}
+
+// Timeout sets the deadline of the requests served by the routers of the namespace
+// and of its nested namespaces, unless they set their own timeout.
+// Like WithRouterTimeout, only the handlers watching ctx.Request.Context() are cut off
+func (n *Namespace) Timeout(timeout time.Duration) *Namespace {
+	n.timeout = timeout
+	return n
+}
+
+// MaxMemory sets the max size of the request bodies which are not uploads for the routers of the namespace
+// and of its nested namespaces, unless they set their own limit. See WithRouterMaxMemory
+func (n *Namespace) MaxMemory(size int64) *Namespace {
+	n.maxMemory = size
+	return n
+}
+
+// MaxUploadSize sets the max size of the uploads for the routers of the namespace
+// and of its nested namespaces, unless they set their own limit. See WithRouterMaxUploadSize
+func (n *Namespace) MaxUploadSize(size int64) *Namespace {
+	n.maxUploadSize = size
+	return n
+}
+
+// StreamBody leaves the request bodies of the routers of the namespace
+// and of its nested namespaces unread. See WithRouterStreamBody
+func (n *Namespace) StreamBody() *Namespace {
+	n.streamBody = true
+	return n
+}
+
+// applyRouterOptions sets the timeout and the body limits of the namespace to the routers without their own
+func (n *Namespace) applyRouterOptions() {
+	for _, t := range n.handlers.routers {
+		walkControllerInfos(t, func(c *ControllerInfo) {
+			if c.timeout == 0 {
+				c.timeout = n.timeout
+			}
+			if c.maxMemory == 0 {
+				c.maxMemory = n.maxMemory
+			}
+			if c.maxUploadSize == 0 {
+				c.maxUploadSize = n.maxUploadSize
+			}
+			c.streamBody = c.streamBody || n.streamBody
+		})
+	}
+}
+
+// walkControllerInfos calls f with the routers of t
+func walkControllerInfos(t *Tree, f func(c *ControllerInfo)) {
+	for _, v := range t.fixrouters {
+		walkControllerInfos(v, f)
+	}
+	if t.wildcard != nil {
+		walkControllerInfos(t.wildcard, f)
+	}
+	for _, l := range t.leaves {
+		if c, ok := l.runObject.(*ControllerInfo); ok {
+			f(c)
+		}
+	}
+}
+
+// prefixedFilterChains returns the FilterChains of the namespace, then the ones of its nested namespaces,
+// with the prefix added to their patterns
+func (n *Namespace) prefixedFilterChains() []filterChainConfig {
+	chains := make([]filterChainConfig, 0, len(n.filterChains)+len(n.nestedChains))
+	for _, list := range [][]filterChainConfig{n.filterChains, n.nestedChains} {
+		for _, fc := range list {
+			fc.pattern = strings.TrimSuffix(n.prefix, "/") + "/" + strings.TrimPrefix(fc.pattern, "/")
+			chains = append(chains, fc)
+		}
+	}
+	return chains
+}
+
+// middleWareChain adapts a MiddleWare to a FilterChain.
+// The request and the writer given by the middleware to the next handler are used by the router.
+func middleWareChain(mw MiddleWare) FilterChain {
+	return func(next FilterFunc) FilterFunc {
+		return func(ctx *beecontext.Context) {
+			rw := ctx.ResponseWriter.ResponseWriter
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx.Request = r
+				ctx.ResponseWriter.ResponseWriter = w
+				next(ctx)
+			})).ServeHTTP(rw, ctx.Request)
+			ctx.ResponseWriter.ResponseWriter = rw
+		}
+	}
+}
+
 // Router same as beego.Rourer
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Router
 func (n *Namespace) Router(rootpath string, c ControllerInterface, mappingMethods ...string) *Namespace {
@@ -106,6 +241,12 @@ func (n *Namespace) Router(rootpath string, c ControllerInterface, mappingMethod
 	return n
 }
 
+// RouterWithOpts same as beego.RouterWithOpts
+func (n *Namespace) RouterWithOpts(rootpath string, c ControllerInterface, opts ...ControllerOption) *Namespace {
+	n.handlers.Add(rootpath, c, opts...)
+	return n
+}
+
 // AutoRouter same as beego.AutoRouter
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#AutoRouter
 func (n *Namespace) AutoRouter(c ControllerInterface) *Namespace {
@@ -122,57 +263,57 @@ func (n *Namespace) AutoPrefix(prefix string, c ControllerInterface) *Namespace
 
 // Get same as beego.Get
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Get
-func (n *Namespace) Get(rootpath string, f HandleFunc) *Namespace {
-	n.handlers.Get(rootpath, f)
+func (n *Namespace) Get(rootpath string, f HandleFunc, opts ...ControllerOption) *Namespace {
+	n.handlers.Get(rootpath, f, opts...)
 	return n
 }
 
 // Post same as beego.Post
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Post
-func (n *Namespace) Post(rootpath string, f HandleFunc) *Namespace {
-	n.handlers.Post(rootpath, f)
+func (n *Namespace) Post(rootpath string, f HandleFunc, opts ...ControllerOption) *Namespace {
+	n.handlers.Post(rootpath, f, opts...)
 	return n
 }
 
 // Delete same as beego.Delete
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Delete
-func (n *Namespace) Delete(rootpath string, f HandleFunc) *Namespace {
-	n.handlers.Delete(rootpath, f)
+func (n *Namespace) Delete(rootpath string, f HandleFunc, opts ...ControllerOption) *Namespace {
+	n.handlers.Delete(rootpath, f, opts...)
 	return n
 }
 
 // Put same as beego.Put
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Put
-func (n *Namespace) Put(rootpath string, f HandleFunc) *Namespace {
-	n.handlers.Put(rootpath, f)
+func (n *Namespace) Put(rootpath string, f HandleFunc, opts ...ControllerOption) *Namespace {
+	n.handlers.Put(rootpath, f, opts...)
 	return n
 }
 
 // Head same as beego.Head
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Head
-func (n *Namespace) Head(rootpath string, f HandleFunc) *Namespace {
-	n.handlers.Head(rootpath, f)
+func (n *Namespace) Head(rootpath string, f HandleFunc, opts ...ControllerOption) *Namespace {
+	n.handlers.Head(rootpath, f, opts...)
 	return n
 }
 
 // Options same as beego.Options
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Options
-func (n *Namespace) Options(rootpath string, f HandleFunc) *Namespace {
-	n.handlers.Options(rootpath, f)
+func (n *Namespace) Options(rootpath string, f HandleFunc, opts ...ControllerOption) *Namespace {
+	n.handlers.Options(rootpath, f, opts...)
 	return n
 }
 
 // Patch same as beego.Patch
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Patch
-func (n *Namespace) Patch(rootpath string, f HandleFunc) *Namespace {
-	n.handlers.Patch(rootpath, f)
+func (n *Namespace) Patch(rootpath string, f HandleFunc, opts ...ControllerOption) *Namespace {
+	n.handlers.Patch(rootpath, f, opts...)
 	return n
 }
 
 // Any same as beego.Any
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Any
-func (n *Namespace) Any(rootpath string, f HandleFunc) *Namespace {
-	n.handlers.Any(rootpath, f)
+func (n *Namespace) Any(rootpath string, f HandleFunc, opts ...ControllerOption) *Namespace {
+	n.handlers.Any(rootpath, f, opts...)
 	return n
 }
 
@@ -183,6 +324,13 @@ func (n *Namespace) Handler(rootpath string, h http.Handler) *Namespace {
 	return n
 }
 
+// WebSocket same as beego.WebSocket
+// refer: https://godoc.org/github.com/asish-tom/beego/v2#WebSocket
+func (n *Namespace) WebSocket(rootpath string, handler WebSocketHandler, opts ...ws.Option) *Namespace {
+	n.handlers.WebSocket(rootpath, handler, opts...)
+	return n
+}
+
 // Include add include class
 // refer: https://godoc.org/github.com/asish-tom/beego/v2#Include
 func (n *Namespace) Include(cList ...ControllerInterface) *Namespace {
@@ -259,6 +407,7 @@ func (n *Namespace) CtrlAny(rootpath string, f interface{}) *Namespace {
 // )
 func (n *Namespace) Namespace(ns ...*Namespace) *Namespace {
 	for _, ni := range ns {
+		ni.applyRouterOptions()
 		for k, v := range ni.handlers.routers {
 			if _, ok := n.handlers.routers[k]; ok {
 				addPrefix(v, ni.prefix)
@@ -280,6 +429,7 @@ func (n *Namespace) Namespace(ns ...*Namespace) *Namespace {
 				}
 			}
 		}
+		n.nestedChains = append(n.nestedChains, ni.prefixedFilterChains()...)
 	}
 	return n
 }
@@ -288,6 +438,7 @@ func (n *Namespace) Namespace(ns ...*Namespace) *Namespace {
 // support multi Namespace
 func AddNamespace(nl ...*Namespace) {
 	for _, n := range nl {
+		n.applyRouterOptions()
 		for k, v := range n.handlers.routers {
 			if _, ok := BeeApp.Handlers.routers[k]; ok {
 				addPrefix(v, n.prefix)
@@ -309,6 +460,7 @@ func AddNamespace(nl ...*Namespace) {
 				}
 			}
 		}
+		BeeApp.Handlers.filterChains = append(BeeApp.Handlers.filterChains, n.prefixedFilterChains()...)
 	}
 }
 
@@ -335,6 +487,49 @@ func NSCond(cond namespaceCond) LinkNamespace {
 	}
 }
 
+// NSInsertFilterChain adds a FilterChain to the Namespace, see Namespace.InsertFilterChain
+func NSInsertFilterChain(pattern string, chain FilterChain, opts ...FilterOpt) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.InsertFilterChain(pattern, chain, opts...)
+	}
+}
+
+// NSMiddleWare adds http.Handler middlewares to the Namespace
+func NSMiddleWare(mws ...MiddleWare) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.MiddleWare(mws...)
+	}
+}
+
+// NSTimeout sets the deadline of the requests served by the Namespace,
+// only the handlers watching ctx.Request.Context() are cut off, see Namespace.Timeout
+func NSTimeout(timeout time.Duration) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.Timeout(timeout)
+	}
+}
+
+// NSMaxMemory sets the max size of the request bodies which are not uploads, see Namespace.MaxMemory
+func NSMaxMemory(size int64) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.MaxMemory(size)
+	}
+}
+
+// NSMaxUploadSize sets the max size of the uploads, see Namespace.MaxUploadSize
+func NSMaxUploadSize(size int64) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.MaxUploadSize(size)
+	}
+}
+
+// NSStreamBody leaves the request bodies unread, see Namespace.StreamBody
+func NSStreamBody() LinkNamespace {
+	return func(ns *Namespace) {
+		ns.StreamBody()
+	}
+}
+
 // NSBefore Namespace BeforeRouter filter
 func NSBefore(filterList ...FilterFunc) LinkNamespace {
 	return func(ns *Namespace) {
@@ -363,59 +558,66 @@ func NSRouter(rootpath string, c ControllerInterface, mappingMethods ...string)
 	}
 }
 
+// NSRouterWithOpts call Namespace RouterWithOpts
+func NSRouterWithOpts(rootpath string, c ControllerInterface, opts ...ControllerOption) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.RouterWithOpts(rootpath, c, opts...)
+	}
+}
+
 // NSGet call Namespace Get
-func NSGet(rootpath string, f HandleFunc) LinkNamespace {
+func NSGet(rootpath string, f HandleFunc, opts ...ControllerOption) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Get(rootpath, f)
+		ns.Get(rootpath, f, opts...)
 	}
 }
 
 // NSPost call Namespace Post
-func NSPost(rootpath string, f HandleFunc) LinkNamespace {
+func NSPost(rootpath string, f HandleFunc, opts ...ControllerOption) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Post(rootpath, f)
+		ns.Post(rootpath, f, opts...)
 	}
 }
 
 // NSHead call Namespace Head
-func NSHead(rootpath string, f HandleFunc) LinkNamespace {
+func NSHead(rootpath string, f HandleFunc, opts ...ControllerOption) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Head(rootpath, f)
+		ns.Head(rootpath, f, opts...)
 	}
 }
 
 // NSPut call Namespace Put
-func NSPut(rootpath string, f HandleFunc) LinkNamespace {
+func NSPut(rootpath string, f HandleFunc, opts ...ControllerOption) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Put(rootpath, f)
+		ns.Put(rootpath, f, opts...)
 	}
 }
 
 // NSDelete call Namespace Delete
-func NSDelete(rootpath string, f HandleFunc) LinkNamespace {
+func NSDelete(rootpath string, f HandleFunc, opts ...ControllerOption) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Delete(rootpath, f)
+		ns.Delete(rootpath, f, opts...)
 	}
 }
 
 // NSAny call Namespace Any
-func NSAny(rootpath string, f HandleFunc) LinkNamespace {
+func NSAny(rootpath string, f HandleFunc, opts ...ControllerOption) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Any(rootpath, f)
+		ns.Any(rootpath, f, opts...)
 	}
 }
 
 // NSOptions call Namespace Options
-func NSOptions(rootpath string, f HandleFunc) LinkNamespace {
+func NSOptions(rootpath string, f HandleFunc, opts ...ControllerOption) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Options(rootpath, f)
+		ns.Options(rootpath, f, opts...)
 	}
 }
 
 // NSPatch call Namespace Patch
-func NSPatch(rootpath string, f HandleFunc) LinkNamespace {
+func NSPatch(rootpath string, f HandleFunc, opts ...ControllerOption) LinkNamespace {
 	return func(ns *Namespace) {
-		ns.Patch(rootpath, f)
+		ns.Patch(rootpath, f, opts...)
 	}
 }
 
@@ -503,3 +705,10 @@ func NSHandler(rootpath string, h http.Handler) LinkNamespace {
 		ns.Handler(rootpath, h)
 	}
 }
+
+// NSWebSocket add websocket endpoint
+func NSWebSocket(rootpath string, handler WebSocketHandler, opts ...ws.Option) LinkNamespace {
+	return func(ns *Namespace) {
+		ns.WebSocket(rootpath, handler, opts...)
+	}
+}
@@ -16,10 +16,15 @@ package web
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 
 	"github.com/asish-tom/beego/v2/server/web/context"
 )
@@ -413,3 +418,186 @@ func TestNamespaceNSCtrlAny(t *testing.T) {
 		}
 	}
 }
+
+func TestNamespaceFilterChain(t *testing.T) {
+	old := BeeApp
+	BeeApp = NewHttpSever()
+	defer func() {
+		BeeApp = old
+	}()
+
+	record := func(name string) FilterChain {
+		return func(next FilterFunc) FilterFunc {
+			return func(ctx *context.Context) {
+				ctx.Output.Header("X-Chain", ctx.ResponseWriter.Header().Get("X-Chain")+name+";")
+				next(ctx)
+			}
+		}
+	}
+	BeeApp.InsertFilterChain("/*", record("app"))
+	ns := NewNamespace("/v1",
+		NSNamespace("/shop",
+			NSInsertFilterChain("/orders/*", record("orders")),
+			NSInsertFilterChain("*", record("shop")),
+			NSGet("/orders/:id", func(ctx *context.Context) {
+				ctx.Output.Body([]byte("order " + ctx.Input.Param(":id")))
+			}),
+			NSGet("/items", func(ctx *context.Context) {
+				ctx.Output.Body([]byte("items"))
+			}),
+		),
+		NSInsertFilterChain("*", record("v1")),
+		NSMiddleWare(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") == "" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("X-Middleware", "v1")
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+	AddNamespace(ns)
+	BeeApp.Get("/v2/items", func(ctx *context.Context) {
+		ctx.Output.Body([]byte("v2 items"))
+	})
+	BeeApp.Handlers.Init()
+
+	cases := []struct {
+		path   string
+		auth   bool
+		status int
+		chain  string
+		body   string
+	}{
+		{path: "/v1/shop/orders/7", auth: true, status: 200, chain: "app;v1;orders;shop;", body: "order 7"},
+		{path: "/v1/shop/items", auth: true, status: 200, chain: "app;v1;shop;", body: "items"},
+		{path: "/v1/shop/items", status: 401, chain: "app;v1;"},
+		{path: "/v2/items", status: 200, chain: "app;", body: "v2 items"},
+	}
+	for _, c := range cases {
+		r, _ := http.NewRequest(http.MethodGet, c.path, nil)
+		if c.auth {
+			r.Header.Set("Authorization", "token")
+		}
+		w := httptest.NewRecorder()
+		BeeApp.Handlers.ServeHTTP(w, r)
+		assert.Equal(t, c.status, w.Code, c.path)
+		assert.Equal(t, c.chain, w.Header().Get("X-Chain"), c.path)
+		assert.Equal(t, c.body, w.Body.String(), c.path)
+		if c.auth && strings.HasPrefix(c.path, "/v1") {
+			assert.Equal(t, "v1", w.Header().Get("X-Middleware"), c.path)
+		}
+	}
+}
+
+func TestNamespaceTimeout(t *testing.T) {
+	old := BeeApp
+	BeeApp = NewHttpSever()
+	defer func() {
+		BeeApp = old
+	}()
+
+	// responds the remaining minutes
+	deadline := func(ctx *context.Context) {
+		d, _ := ctx.Request.Context().Deadline()
+		ctx.Output.Body([]byte(strconv.Itoa(int(time.Until(d).Round(time.Minute).Minutes()))))
+	}
+	ns := NewNamespace("/v1",
+		NSTimeout(time.Minute),
+		NSGet("/lookup", deadline),
+		NSNamespace("/reports",
+			NSTimeout(10*time.Minute),
+			NSGet("/daily", deadline),
+		),
+		NSNamespace("/jobs",
+			NSTimeout(10*time.Millisecond),
+			NSGet("/slow", WrapperFromForm(func(ctx *context.Context, _ struct{}) (any, error) {
+				<-ctx.Request.Context().Done()
+				return nil, ctx.Request.Context().Err()
+			})),
+		),
+		NSRouterWithOpts("/export", &timeoutController{},
+			WithRouterMethods(&timeoutController{}, "get:Fast"), WithRouterTimeout(time.Hour)),
+	)
+	AddNamespace(ns)
+
+	cases := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{url: "/v1/lookup", status: http.StatusOK, body: "1"},
+		{url: "/v1/reports/daily", status: http.StatusOK, body: "10"},
+		{url: "/v1/export", status: http.StatusOK, body: "true"},
+		{url: "/v1/jobs/slow", status: http.StatusGatewayTimeout},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, c.url, nil)
+			w := httptest.NewRecorder()
+			BeeApp.Handlers.ServeHTTP(w, r)
+			assert.Equal(t, c.status, w.Code)
+			if c.body != "" {
+				assert.Equal(t, c.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNamespaceBodyLimits(t *testing.T) {
+	old := BeeApp
+	BeeApp = NewHttpSever()
+	defer func() {
+		BeeApp = old
+	}()
+
+	echo := func(ctx *context.Context) {
+		ctx.WriteString(ctx.Input.Query("v"))
+	}
+	ns := NewNamespace("/v1",
+		NSMaxMemory(16),
+		NSPost("/small", echo),
+		NSNamespace("/imports",
+			NSMaxMemory(1<<10),
+			NSPost("/large", echo),
+		),
+		NSNamespace("/ingest",
+			NSStreamBody(),
+			NSPost("/raw", func(ctx *context.Context) {
+				// the form is not parsed
+				body, _ := io.ReadAll(ctx.Request.Body)
+				ctx.WriteString(ctx.Input.Query("v") + "|" + string(body))
+			}),
+		),
+		NSRouterWithOpts("/export", &bodyController{}, WithRouterMaxMemory(1<<10)),
+	)
+	AddNamespace(ns)
+
+	large := "v=" + strings.Repeat("a", 32)
+	cases := []struct {
+		url    string
+		body   string
+		status int
+		resp   string
+	}{
+		{url: "/v1/small", body: "v=ok", status: http.StatusOK, resp: "ok"},
+		{url: "/v1/small", body: large, status: http.StatusRequestEntityTooLarge},
+		{url: "/v1/imports/large", body: large, status: http.StatusOK, resp: large[2:]},
+		{url: "/v1/export", body: large, status: http.StatusOK, resp: large[2:]},
+		{url: "/v1/ingest/raw", body: "v=ok", status: http.StatusOK, resp: "|v=ok"},
+		// the stream is still limited by the max memory
+		{url: "/v1/ingest/raw", body: large, status: http.StatusOK, resp: "|" + large[:16]},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			BeeApp.Handlers.ServeHTTP(w, newFormRequest(c.url, c.body))
+			assert.Equal(t, c.status, w.Code)
+			if c.resp != "" {
+				assert.Equal(t, c.resp, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,443 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/server/web/context"
+	"github.com/asish-tom/beego/v2/server/web/openapi"
+)
+
+// how a generic wrapper binds its input
+const (
+	bindingAny  = "any"
+	bindingJSON = "json"
+	bindingForm = "form"
+)
+
+// openAPIAnyMethods are documented for the routers accepting every method, eg. Any or Handler
+var openAPIAnyMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// routeDoc describes a router in the OpenAPI document, see WithRouterDoc and WithRouterHidden
+type routeDoc struct {
+	// the router is left out of the document
+	hidden bool
+	// the input type and the options of a generic wrapper
+	input reflect.Type
+	cfg   *wrapperConfig
+}
+
+// WithRouterHidden leaves the router out of the OpenAPI document
+func WithRouterHidden() ControllerOption {
+	return func(c *ControllerInfo) {
+		c.doc = &routeDoc{hidden: true}
+	}
+}
+
+// WithRouterDoc documents in the OpenAPI document a router which isn't created by a generic wrapper,
+// eg. a HandleFunc calling ctx.Bind, T is the type it binds and opts are the options describing the operation:
+//
+//	web.Post("/users", signUp, web.WithRouterDoc[*SignupRequest](web.WithResponseType(User{}), web.WithSummary("sign up")))
+//
+// The routers created by Wrapper, WrapperFromJson and WrapperFromForm are registered with AddWrapper,
+// which documents them by their own type parameter and options.
+func WithRouterDoc[T any](opts ...WrapperOption) ControllerOption {
+	return withRouterDoc[T](bindingAny, opts)
+}
+
+// WithRouterJSONDoc is the WithRouterDoc of the routers binding T with ctx.BindJSON
+func WithRouterJSONDoc[T any](opts ...WrapperOption) ControllerOption {
+	return withRouterDoc[T](bindingJSON, opts)
+}
+
+// WithRouterFormDoc is the WithRouterDoc of the routers binding T with ctx.BindForm
+func WithRouterFormDoc[T any](opts ...WrapperOption) ControllerOption {
+	return withRouterDoc[T](bindingForm, opts)
+}
+
+// AddWrapper registers for method and pattern the router created by wrapper from biz and opts,
+// wrapper is Wrapper, WrapperFromJson or WrapperFromForm. The router is documented in the OpenAPI document
+// by T and by the options the wrapper is built with, see WithRouterOptions to configure the router:
+//
+//	web.AddWrapper(web.BeeApp.Handlers, http.MethodPost, "/users", web.WrapperFromJson[*SignupRequest], signUp,
+//		web.WithResponseType(User{}), web.WithSummary("sign up"), web.WithRouterOptions(web.WithRouterTimeout(time.Second)))
+func AddWrapper[T any](p *ControllerRegister, method, pattern string,
+	wrapper func(biz bizFunc[T], opts ...WrapperOption) func(ctx *context.Context),
+	biz bizFunc[T], opts ...WrapperOption,
+) {
+	var cfg *wrapperConfig
+	// the last option receives the config built by the wrapper, including its binding
+	h := wrapper(biz, append(opts[:len(opts):len(opts)], func(c *wrapperConfig) {
+		cfg = c
+	})...)
+	doc := &routeDoc{
+		input: reflect.TypeOf((*T)(nil)).Elem(),
+		cfg:   cfg,
+	}
+	routerOpts := append([]ControllerOption{func(c *ControllerInfo) {
+		c.doc = doc
+	}}, cfg.routerOptions...)
+	p.AddMethod(method, pattern, h, routerOpts...)
+}
+
+func withRouterDoc[T any](binding string, opts []WrapperOption) ControllerOption {
+	doc := &routeDoc{
+		input: reflect.TypeOf((*T)(nil)).Elem(),
+		cfg:   newWrapperConfig(withBinding(binding, opts)),
+	}
+	return func(c *ControllerInfo) {
+		c.doc = doc
+	}
+}
+
+// OpenAPI builds the OpenAPI 3.1 document of the registered routers.
+// Path parameters are taken from the patterns, including their constraints.
+// The routers registered with AddWrapper or WithRouterDoc are described by their input type,
+// see WithResponseType, WithSummary, WithDescription and WithTags to complete them.
+func (p *ControllerRegister) OpenAPI(info openapi.Info) *openapi.Document {
+	doc := openapi.NewDocument(info)
+	routes := p.GetAllControllerInfo()
+	// the same router is stored in the tree of every method it accepts
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].pattern < routes[j].pattern
+	})
+	seen := make(map[*ControllerInfo]bool, len(routes))
+	for _, route := range routes {
+		if seen[route] {
+			continue
+		}
+		seen[route] = true
+		documentRoute(doc, route)
+	}
+	return doc
+}
+
+// OpenAPIHandler serves the OpenAPI document of the registered routers.
+// The document is encoded as YAML if the path ends with .yaml or .yml or if the Accept header asks for YAML,
+// as JSON otherwise. Register it with WithRouterHidden to leave it out of the document.
+func (p *ControllerRegister) OpenAPIHandler(info openapi.Info) HandleFunc {
+	return func(ctx *context.Context) {
+		doc := p.OpenAPI(info)
+		var (
+			data        []byte
+			err         error
+			contentType string
+		)
+		path := ctx.Request.URL.Path
+		if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") ||
+			(!strings.HasSuffix(path, ".json") && strings.Contains(ctx.Input.Header("Accept"), "yaml")) {
+			data, err = doc.YAML()
+			contentType = context.ApplicationYAML
+		} else {
+			data, err = doc.JSON()
+			contentType = context.ApplicationJSON
+		}
+		if err != nil {
+			logs.Error("err {%v} happen in encoding the OpenAPI document", err)
+			http.Error(ctx.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		ctx.Output.Header("Content-Type", contentType+"; charset=utf-8")
+		_ = ctx.Output.Body(data)
+	}
+}
+
+// serveOpenAPI registers the document of routers on app, according to BConfig.WebConfig.OpenAPI
+func serveOpenAPI(app *HttpServer, routers *ControllerRegister, cfg OpenAPIConfig) {
+	info := openapi.Info{
+		Title:       cfg.OpenAPITitle,
+		Description: cfg.OpenAPIDescription,
+		Version:     cfg.OpenAPIVersion,
+	}
+	if info.Title == "" {
+		info.Title = BConfig.AppName
+	}
+	h := routers.OpenAPIHandler(info)
+	path := "/" + strings.Trim(cfg.OpenAPIPath, "/")
+	app.Get(path, h, WithRouterHidden())
+	app.Get(path+".json", h, WithRouterHidden())
+	app.Get(path+".yaml", h, WithRouterHidden())
+}
+
+func documentRoute(doc *openapi.Document, route *ControllerInfo) {
+	rd := route.doc
+	if rd != nil && rd.hidden {
+		return
+	}
+	path, params := openAPIPath(route.pattern)
+	for _, method := range routeMethods(route) {
+		op := &openapi.Operation{
+			OperationID: operationID(method, path),
+			Parameters:  append([]*openapi.Parameter(nil), params...),
+			Responses: map[string]*openapi.Response{
+				"200": {Description: http.StatusText(http.StatusOK)},
+			},
+		}
+		if route.routerType == routerTypeBeego {
+			op.Tags = []string{strings.TrimSuffix(route.controllerType.Name(), "Controller")}
+		}
+		if rd != nil && rd.cfg != nil {
+			rd.describe(doc, method, op)
+		}
+		doc.AddOperation(method, path, op)
+	}
+}
+
+// routeMethods returns the http methods of route which can be documented
+func routeMethods(route *ControllerInfo) []string {
+	if len(route.methods) == 0 {
+		if route.routerType != routerTypeBeego {
+			return openAPIAnyMethods
+		}
+		// the controller dispatches on the request method, keep the methods it implements
+		methods := make([]string, 0, len(openAPIAnyMethods))
+		for _, m := range openAPIAnyMethods {
+			if implementsMethod(route.controllerType, m[:1]+strings.ToLower(m[1:])) {
+				methods = append(methods, m)
+			}
+		}
+		return methods
+	}
+	if _, ok := route.methods["*"]; ok || len(route.methods) == len(HTTPMETHOD) {
+		return openAPIAnyMethods
+	}
+	methods := make([]string, 0, len(route.methods))
+	for m := range route.methods {
+		if openapi.SupportsMethod(m) {
+			methods = append(methods, m)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// implementsMethod reports whether the controller declares the method itself,
+// the ones promoted from the embedded Controller are generated by the compiler
+func implementsMethod(ct reflect.Type, name string) bool {
+	m, ok := reflect.PtrTo(ct).MethodByName(name)
+	if !ok {
+		return false
+	}
+	fn := runtime.FuncForPC(m.Func.Pointer())
+	if fn == nil {
+		return false
+	}
+	file, _ := fn.FileLine(fn.Entry())
+	return file != "<autogenerated>"
+}
+
+// operationID builds a unique id from the method and the path, eg. "getUsersId" for GET /users/{id}
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	upper := true
+	for _, r := range path {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			if upper && r >= 'a' && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			b.WriteRune(r)
+			upper = false
+			continue
+		}
+		upper = true
+	}
+	return b.String()
+}
+
+// openAPIPath converts a router pattern into an OpenAPI path and its parameters.
+// "/users/:id<int>/*.*" -> "/users/{id}/{path}.{ext}"
+// Optional parameters such as ?:id are documented as required, because OpenAPI has no optional path parameter.
+func openAPIPath(pattern string) (string, []*openapi.Parameter) {
+	var params []*openapi.Parameter
+	addParam := func(name string, schema *openapi.Schema) {
+		params = append(params, &openapi.Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		switch seg {
+		case "*":
+			segments[i] = "{splat}"
+			addParam("splat", &openapi.Schema{Type: "string"})
+			continue
+		case "*.*":
+			segments[i] = "{path}.{ext}"
+			addParam("path", &openapi.Schema{Type: "string"})
+			addParam("ext", &openapi.Schema{Type: "string"})
+			continue
+		}
+		var b strings.Builder
+		for j := 0; j < len(seg); {
+			if seg[j] == '?' && j+1 < len(seg) && seg[j+1] == ':' {
+				j++
+				continue
+			}
+			if seg[j] != ':' {
+				b.WriteByte(seg[j])
+				j++
+				continue
+			}
+			k := j + 1
+			for k < len(seg) && isParamChar(seg[k]) {
+				k++
+			}
+			name := seg[j+1 : k]
+			schema := &openapi.Schema{Type: "string"}
+			switch rest := seg[k:]; {
+			case strings.HasPrefix(rest, "<"):
+				if end := strings.IndexByte(rest, '>'); end > 0 {
+					schema = constraintSchema(getRouteConstraint(rest[1:end]))
+					k += end + 1
+				}
+			case strings.HasPrefix(rest, ":int"):
+				schema = &openapi.Schema{Type: "integer"}
+				k += len(":int")
+			case strings.HasPrefix(rest, ":string"):
+				k += len(":string")
+			case strings.HasPrefix(rest, "("):
+				if end := closingParen(rest); end > 0 {
+					schema.Pattern = "^" + rest[:end+1] + "$"
+					k += end + 1
+				}
+			}
+			b.WriteString("{" + name + "}")
+			addParam(name, schema)
+			j = k
+		}
+		segments[i] = b.String()
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func isParamChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// closingParen returns the index of the parenthesis closing the one at s[0]
+func closingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func constraintSchema(c *RouteConstraint) *openapi.Schema {
+	switch strings.ToLower(c.Name) {
+	case "int":
+		return &openapi.Schema{Type: "integer"}
+	case "uuid":
+		return &openapi.Schema{Type: "string", Format: "uuid"}
+	case "date":
+		return &openapi.Schema{Type: "string", Format: "date"}
+	}
+	return &openapi.Schema{Type: "string", Pattern: "^(?:" + c.Regexp + ")$"}
+}
+
+// describe completes op with the input, the response and the errors of a generic wrapper
+func (rd *routeDoc) describe(doc *openapi.Document, method string, op *openapi.Operation) {
+	cfg := rd.cfg
+	op.Summary = cfg.summary
+	op.Description = cfg.description
+	if len(cfg.tags) > 0 {
+		op.Tags = cfg.tags
+	}
+
+	input := rd.input
+	for input.Kind() == reflect.Ptr {
+		input = input.Elem()
+	}
+	inQuery := cfg.binding == bindingForm &&
+		(method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete)
+	if inQuery && input.Kind() == reflect.Struct {
+		op.Parameters = append(op.Parameters, queryParameters(doc, input)...)
+	} else if !inQuery {
+		schema := doc.SchemaOf(input)
+		var contentTypes []string
+		switch cfg.binding {
+		case bindingJSON:
+			contentTypes = []string{context.ApplicationJSON}
+		case bindingForm:
+			contentTypes = []string{context.ApplicationForm}
+		default:
+			contentTypes = []string{context.ApplicationJSON, context.ApplicationXML,
+				context.ApplicationForm, context.ApplicationYAML}
+		}
+		op.RequestBody = &openapi.RequestBody{Required: true, Content: make(map[string]*openapi.MediaType, len(contentTypes))}
+		for _, ct := range contentTypes {
+			op.RequestBody.Content[ct] = &openapi.MediaType{Schema: schema}
+		}
+	}
+
+	var result *openapi.Schema
+	if cfg.responseType != nil {
+		result = doc.SchemaOf(cfg.responseType)
+	}
+	op.Responses["200"] = openapi.JSONResponse(http.StatusText(http.StatusOK), result)
+	op.Responses[strconv.Itoa(cfg.bindErrorStatus)] = &openapi.Response{Description: "the input can't be bound"}
+	if cfg.validate && input.Kind() == reflect.Struct {
+		op.Responses[strconv.Itoa(cfg.validationErrorStatus)] = openapi.JSONResponse("the input is not valid",
+			doc.SchemaOf(reflect.TypeOf(ValidationError{})))
+	}
+	if _, ok := op.Responses[strconv.Itoa(cfg.bizErrorStatus)]; !ok {
+		op.Responses[strconv.Itoa(cfg.bizErrorStatus)] = &openapi.Response{Description: http.StatusText(cfg.bizErrorStatus)}
+	}
+}
+
+// queryParameters describes the fields of a form bound from the query string
+func queryParameters(doc *openapi.Document, t reflect.Type) []*openapi.Parameter {
+	var params []*openapi.Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("form"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct {
+			params = append(params, queryParameters(doc, ft)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		param := &openapi.Parameter{Name: name, In: "query", Schema: doc.SchemaOf(f.Type)}
+		param.Required = openapi.ApplyValidTag(param.Schema, f.Tag.Get("valid"))
+		params = append(params, param)
+	}
+	return params
+}
@@ -0,0 +1,247 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const componentsPrefix = "#/components/schemas/"
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	// characters which can't be used in the name of a component, eg. the brackets of generic types
+	invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+	// patterns of the validation functions which have no json schema keyword
+	validPatterns = map[string]string{
+		"Alpha":        `^[a-zA-Z]*$`,
+		"Numeric":      `^[0-9]*$`,
+		"AlphaNumeric": `^[a-zA-Z0-9]*$`,
+		"AlphaDash":    `^[a-zA-Z0-9_-]*$`,
+		"Base64":       `^[A-Za-z0-9+/]*={0,2}$`,
+	}
+	validFormats = map[string]string{
+		"Email": "email",
+		"IP":    "ipv4",
+	}
+)
+
+// SchemaOf returns the schema of t.
+// Named struct types are stored in the components of the document and referenced by $ref.
+// The properties are named after the `json` tags and the `valid` tags of core/validation
+// are translated to the matching keywords, eg. Required, MinSize(3) or Range(1, 10).
+func (d *Document) SchemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.SchemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.SchemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return &Schema{Ref: componentsPrefix + d.component(t)}
+	}
+	// interfaces, functions and channels accept any value
+	return &Schema{}
+}
+
+// component stores the schema of the named struct t and returns its name
+func (d *Document) component(t reflect.Type) string {
+	if name, ok := d.names[t]; ok {
+		return name
+	}
+	name := invalidNameChars.ReplaceAllString(t.Name(), "_")
+	if _, ok := d.Components.Schemas[name]; ok {
+		// same name in another package
+		pkg := t.PkgPath()
+		name = invalidNameChars.ReplaceAllString(pkg[strings.LastIndex(pkg, "/")+1:], "_") + "." + name
+		for i := 2; ; i++ {
+			if _, ok = d.Components.Schemas[name]; !ok {
+				break
+			}
+			name = strings.TrimRight(name, "0123456789") + strconv.Itoa(i)
+		}
+	}
+	// register the name before the fields are visited, so that recursive types end with a $ref
+	d.names[t] = name
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.structSchema(t)
+	return name
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	d.addFields(s, t)
+	return s
+}
+
+func (d *Document) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// fields of embedded structs are promoted, like encoding/json does
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			d.addFields(s, ft)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		var fs *Schema
+		if len(opts) > 1 && opts[1] == "string" {
+			fs = &Schema{Type: "string"}
+		} else {
+			fs = d.SchemaOf(f.Type)
+		}
+		if ApplyValidTag(fs, f.Tag.Get("valid")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// ApplyValidTag adds the constraints of a `valid` tag to s.
+// It returns true if the tag contains Required.
+func ApplyValidTag(s *Schema, tag string) (required bool) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return false
+	}
+	// the expression of Match may contain ';', take it out first like core/validation does
+	if start := strings.Index(tag, "Match(/"); start != -1 {
+		if end := strings.LastIndex(tag, "/)"); end > start {
+			s.Pattern = tag[start+len("Match(/") : end]
+			tag = tag[:start] + tag[end+len("/)"):]
+		}
+	}
+	array := s.Type == "array"
+	for _, fn := range strings.Split(tag, ";") {
+		name, args := parseValidFunc(fn)
+		switch name {
+		case "Required":
+			required = true
+		case "Min":
+			s.Minimum = floatArg(args, 0)
+		case "Max":
+			s.Maximum = floatArg(args, 0)
+		case "Range":
+			s.Minimum, s.Maximum = floatArg(args, 0), floatArg(args, 1)
+		case "MinSize", "MaxSize", "Length":
+			n := intArg(args, 0)
+			minimum, maximum := &s.MinLength, &s.MaxLength
+			if array {
+				minimum, maximum = &s.MinItems, &s.MaxItems
+			}
+			if name != "MaxSize" {
+				*minimum = n
+			}
+			if name != "MinSize" {
+				*maximum = n
+			}
+		default:
+			if p, ok := validPatterns[name]; ok && s.Pattern == "" {
+				s.Pattern = p
+			} else if f, ok := validFormats[name]; ok {
+				s.Format = f
+			}
+		}
+	}
+	return required
+}
+
+// parseValidFunc splits "Range(1, 10)" into "Range" and ["1", "10"]
+func parseValidFunc(fn string) (name string, args []string) {
+	fn = strings.TrimSpace(fn)
+	start := strings.Index(fn, "(")
+	if start == -1 {
+		return fn, nil
+	}
+	end := strings.LastIndex(fn, ")")
+	if end < start {
+		return strings.TrimSpace(fn[:start]), nil
+	}
+	for _, a := range strings.Split(fn[start+1:end], ",") {
+		args = append(args, strings.TrimSpace(a))
+	}
+	return strings.TrimSpace(fn[:start]), args
+}
+
+func floatArg(args []string, i int) *float64 {
+	if i >= len(args) {
+		return nil
+	}
+	f, err := strconv.ParseFloat(args[i], 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func intArg(args []string, i int) *int {
+	if i >= len(args) {
+		return nil
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil {
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,108 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type base struct {
+	ID      int64     `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type user struct {
+	base
+	Name    string            `json:"name" valid:"Required;MinSize(2);MaxSize(20)"`
+	Email   string            `json:"email" valid:"Email"`
+	Age     int               `json:"age" valid:"Range(1, 140)"`
+	Code    string            `json:"code" valid:"Match(/^[a-z;]+$/)"`
+	Tags    []string          `json:"tags" valid:"MaxSize(3)"`
+	Avatar  []byte            `json:"avatar,omitempty"`
+	Friends []*user           `json:"friends"`
+	Extra   map[string]string `json:"extra"`
+	Count   int64             `json:"count,string"`
+	Secret  string            `json:"-"`
+	private string
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := NewDocument(Info{Title: "test", Version: "1"})
+	s := doc.SchemaOf(reflect.TypeOf(&user{}))
+	assert.Equal(t, "#/components/schemas/user", s.Ref)
+
+	u := doc.Components.Schemas["user"]
+	require.NotNil(t, u)
+	assert.Equal(t, "object", u.Type)
+	assert.Equal(t, []string{"name"}, u.Required)
+	assert.Len(t, u.Properties, 11)
+	assert.NotContains(t, u.Properties, "Secret")
+	assert.NotContains(t, u.Properties, "private")
+
+	assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, u.Properties["id"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, u.Properties["created"])
+	assert.Equal(t, 2, *u.Properties["name"].MinLength)
+	assert.Equal(t, 20, *u.Properties["name"].MaxLength)
+	assert.Equal(t, "email", u.Properties["email"].Format)
+	assert.Equal(t, 1.0, *u.Properties["age"].Minimum)
+	assert.Equal(t, 140.0, *u.Properties["age"].Maximum)
+	assert.Equal(t, "^[a-z;]+$", u.Properties["code"].Pattern)
+	assert.Equal(t, 3, *u.Properties["tags"].MaxItems)
+	assert.Nil(t, u.Properties["tags"].MaxLength)
+	assert.Equal(t, &Schema{Type: "string", Format: "byte"}, u.Properties["avatar"])
+	assert.Equal(t, "#/components/schemas/user", u.Properties["friends"].Items.Ref)
+	assert.Equal(t, "string", u.Properties["extra"].AdditionalProperties.Type)
+	assert.Equal(t, "string", u.Properties["count"].Type)
+
+	inline := doc.SchemaOf(reflect.TypeOf(struct {
+		Value float64
+	}{}))
+	assert.Equal(t, "double", inline.Properties["Value"].Format)
+	assert.Len(t, doc.Components.Schemas, 1)
+}
+
+func TestDocument(t *testing.T) {
+	doc := NewDocument(Info{Title: "test", Version: "1"})
+	op := &Operation{Responses: map[string]*Response{
+		"200": JSONResponse("OK", doc.SchemaOf(reflect.TypeOf(user{}))),
+	}}
+	assert.True(t, doc.AddOperation(http.MethodGet, "/users/{id}", op))
+	assert.False(t, doc.AddOperation(http.MethodGet, "/users/{id}", op))
+	assert.False(t, doc.AddOperation("PROPFIND", "/users/{id}", op))
+	assert.True(t, doc.AddOperation(http.MethodDelete, "/users/{id}", op))
+	assert.True(t, SupportsMethod("patch"))
+
+	data, err := doc.JSON()
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "3.1.0", decoded["openapi"])
+	assert.Contains(t, decoded["paths"].(map[string]interface{})["/users/{id}"], "delete")
+
+	data, err = doc.YAML()
+	require.NoError(t, err)
+	decoded = nil
+	require.NoError(t, yaml.Unmarshal(data, &decoded))
+	assert.Equal(t, "3.1.0", decoded["openapi"])
+	assert.Contains(t, decoded["components"].(map[string]interface{})["schemas"], "user")
+}
@@ -0,0 +1,183 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/asish-tom/beego/v2/server/web/context"
+	"github.com/asish-tom/beego/v2/server/web/openapi"
+)
+
+type openAPIController struct {
+	Controller
+}
+
+func (c *openAPIController) Get() {}
+
+func (c *openAPIController) Delete() {}
+
+func (c *openAPIController) List() {}
+
+type openAPIUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type openAPISearch struct {
+	Keyword string `form:"q" valid:"Required"`
+	Page    int    `form:"page" valid:"Min(1)"`
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		params  []*openapi.Parameter
+	}{
+		{"/users", "/users", nil},
+		{"/users/:id<int>/posts/:slug", "/users/{id}/posts/{slug}", []*openapi.Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "slug", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		}},
+		{"/v1/:uid<uuid>/?:day<date>", "/v1/{uid}/{day}", []*openapi.Parameter{
+			{Name: "uid", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+			{Name: "day", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "date"}},
+		}},
+		{"/item/:id([0-9]+)_:name:string", "/item/{id}_{name}", []*openapi.Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Pattern: "^([0-9]+)$"}},
+			{Name: "name", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		}},
+		{"/files/*.*", "/files/{path}.{ext}", []*openapi.Parameter{
+			{Name: "path", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			{Name: "ext", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		}},
+		{"/tag/:name<slug>", "/tag/{name}", []*openapi.Parameter{
+			{Name: "name", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Pattern: "^(?:[a-z0-9][a-z0-9-]*)$"}},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			path, params := openAPIPath(c.pattern)
+			assert.Equal(t, c.path, path)
+			assert.Equal(t, c.params, params)
+		})
+	}
+	assert.Equal(t, "getUsersIdPosts", operationID(http.MethodGet, "/users/{id}/posts"))
+}
+
+func TestControllerRegisterOpenAPI(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/users/:id<int>", &openAPIController{})
+	handler.Add("/users", &openAPIController{}, WithRouterMethods(&openAPIController{}, "get:List"))
+	opts := []WrapperOption{WithResponseType(openAPIUser{}), WithSummary("sign up"), WithTags("users")}
+	AddWrapper(handler, http.MethodPost, "/users", WrapperFromJson[*signupRequest],
+		func(ctx *context.Context, u *signupRequest) (any, error) {
+			return openAPIUser{Name: u.Name}, nil
+		}, append(opts, WithRouterOptions(WithRouterTimeout(time.Second)))...)
+	search := func(ctx *context.Context) {
+		var s openAPISearch
+		_ = ctx.BindForm(&s)
+	}
+	handler.Get("/search", search, WithRouterFormDoc[openAPISearch]())
+	// the same handler is documented by each router
+	handler.Get("/internal/search", search, WithRouterHidden())
+	handler.Any("/ping", func(ctx *context.Context) {})
+	handler.Get("/doc", handler.OpenAPIHandler(openapi.Info{Title: "test"}), WithRouterHidden())
+
+	doc := handler.OpenAPI(openapi.Info{Title: "test", Version: "1.0.0"})
+	assert.Len(t, doc.Paths, 4)
+	assert.NotContains(t, doc.Paths, "/doc")
+	assert.NotContains(t, doc.Paths, "/internal/search")
+
+	item := doc.Paths["/users/{id}"]
+	require.NotNil(t, item)
+	require.NotNil(t, item.Get)
+	require.NotNil(t, item.Delete)
+	assert.Nil(t, item.Post)
+	assert.Equal(t, []string{"openAPI"}, item.Get.Tags)
+	assert.Equal(t, "integer", item.Get.Parameters[0].Schema.Type)
+
+	item = doc.Paths["/users"]
+	require.NotNil(t, item.Get)
+	require.NotNil(t, item.Post)
+	assert.Equal(t, "sign up", item.Post.Summary)
+	assert.Equal(t, []string{"users"}, item.Post.Tags)
+	body := item.Post.RequestBody.Content[context.ApplicationJSON]
+	require.NotNil(t, body)
+	assert.Equal(t, "#/components/schemas/signupRequest", body.Schema.Ref)
+	assert.Equal(t, "#/components/schemas/openAPIUser", item.Post.Responses["200"].Content[context.ApplicationJSON].Schema.Ref)
+	assert.Contains(t, item.Post.Responses, "400")
+	assert.Equal(t, "#/components/schemas/ValidationError", item.Post.Responses["422"].Content[context.ApplicationJSON].Schema.Ref)
+	assert.Contains(t, item.Post.Responses, "500")
+	assert.Contains(t, doc.Components.Schemas["signupRequest"].Required, "name")
+	// the options of the router are applied
+	var timeout time.Duration
+	for _, route := range handler.GetAllControllerInfo() {
+		if route.pattern == "/users" && route.routerType == routerTypeRESTFul {
+			timeout = route.timeout
+		}
+	}
+	assert.Equal(t, time.Second, timeout)
+
+	op := doc.Paths["/search"].Get
+	require.NotNil(t, op)
+	assert.Nil(t, op.RequestBody)
+	require.Len(t, op.Parameters, 2)
+	assert.Equal(t, "q", op.Parameters[0].Name)
+	assert.Equal(t, "query", op.Parameters[0].In)
+	assert.True(t, op.Parameters[0].Required)
+	assert.Equal(t, 1.0, *op.Parameters[1].Schema.Minimum)
+
+	ping := doc.Paths["/ping"]
+	assert.NotNil(t, ping.Get)
+	assert.NotNil(t, ping.Patch)
+	assert.Nil(t, ping.Head)
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Get("/users/:id", func(ctx *context.Context) {})
+	h := handler.OpenAPIHandler(openapi.Info{Title: "test", Version: "1.0.0"})
+	handler.Get("/openapi", h)
+	handler.Get("/openapi.yaml", h)
+
+	r, _ := http.NewRequest(http.MethodGet, "/openapi", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), context.ApplicationJSON)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.Equal(t, "3.1.0", decoded["openapi"])
+	assert.Contains(t, decoded["paths"], "/users/{id}")
+
+	r, _ = http.NewRequest(http.MethodGet, "/openapi.yaml", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), context.ApplicationYAML)
+	decoded = nil
+	require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.Equal(t, "test", decoded["info"].(map[string]interface{})["title"])
+}
@@ -0,0 +1,110 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/asish-tom/beego/v2/core/berror"
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+// ProblemJSON is the media type of the problem details defined by RFC 7807
+const ProblemJSON = "application/problem+json"
+
+// Problem is the body of an application/problem+json response, see RFC 7807.
+// Code, Module and Name are extension members filled from berror coded errors.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     uint32 `json:"code,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Name     string `json:"name,omitempty"`
+	// Errors lists the invalid fields of a *ValidationError
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem creates a problem whose type is about:blank, the title is the status text
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// ProblemFromError creates the problem of err.
+// If err is a berror coded error, its code, module and name are added
+// and the detail is the message without the "ERROR-code, " prefix.
+func ProblemFromError(status int, err error) *Problem {
+	p := NewProblem(status, err.Error())
+	if code, ok := berror.FromError(err); ok {
+		p.Code = code.Code()
+		p.Module = code.Module()
+		p.Name = code.Name()
+		if i := strings.Index(p.Detail, ", "); i != -1 {
+			p.Detail = p.Detail[i+2:]
+		}
+	}
+	return p
+}
+
+// WriteProblem writes p as application/problem+json, the instance is the request URI if it's empty
+func WriteProblem(ctx *context.Context, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = ctx.Request.URL.RequestURI()
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		logs.Error("err {%v} happen in encoding problem ", err)
+		return
+	}
+	ctx.Output.Status = p.Status
+	ctx.Output.Header("Content-Type", ProblemJSON)
+	ctx.ResponseWriter.WriteHeader(p.Status)
+	_, _ = ctx.ResponseWriter.Write(data)
+}
+
+// writePanicProblem renders a recovered panic.
+// The status set by ctx.Abort is kept, the message of an unexpected panic is only shown in dev mode.
+func writePanicProblem(err interface{}, ctx *context.Context, cfg *Config) {
+	status := ctx.Output.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	var p *Problem
+	if e, ok := err.(error); ok {
+		p = ProblemFromError(status, e)
+	} else {
+		p = NewProblem(status, fmt.Sprint(err))
+	}
+	if ctx.Output.Status == 0 && cfg.RunMode != DEV {
+		p.Detail = ""
+	}
+	WriteProblem(ctx, p)
+}
+
+// acceptsProblem reports whether the error of the request should be rendered as problem+json
+func acceptsProblem(cfg *Config, ctx *context.Context) bool {
+	return cfg.EnableProblemJSON && ctx.Input.AcceptsJSON()
+}
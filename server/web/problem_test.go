@@ -0,0 +1,145 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/core/berror"
+	"github.com/asish-tom/beego/v2/server/web/context"
+)
+
+var problemTestCode = berror.DefineCode(5200001, "web_test", "ProblemTest", "used by TestProblemJSON")
+
+func TestProblemFromError(t *testing.T) {
+	p := ProblemFromError(http.StatusBadRequest, berror.Error(problemTestCode, "invalid id"))
+	assert.Equal(t, &Problem{
+		Type:   "about:blank",
+		Title:  "Bad Request",
+		Status: http.StatusBadRequest,
+		Detail: "invalid id",
+		Code:   5200001,
+		Module: "web_test",
+		Name:   "ProblemTest",
+	}, p)
+
+	p = ProblemFromError(http.StatusInternalServerError, errors.New("plain, error"))
+	assert.Equal(t, "plain, error", p.Detail)
+	assert.Zero(t, p.Code)
+}
+
+func TestProblemJSON(t *testing.T) {
+	registerDefaultErrorHandler()
+	BConfig.EnableProblemJSON = true
+	defer func() {
+		BConfig.EnableProblemJSON = false
+	}()
+
+	cfg := newBConfig()
+	cfg.CopyRequestBody = true
+	cfg.EnableProblemJSON = true
+	handler := NewControllerRegisterWithCfg(cfg)
+	handler.Add("/error", &errorTestController{})
+	handler.Get("/abort", func(ctx *context.Context) {
+		ctx.Abort(http.StatusUnprocessableEntity, "bad input")
+	})
+	handler.Get("/panic", func(ctx *context.Context) {
+		panic(berror.Error(problemTestCode, "internal details"))
+	})
+	handler.Post("/signup", WrapperFromJson(signup))
+	handler.Get("/biz", WrapperFromForm(func(ctx *context.Context, _ struct{}) (any, error) {
+		return nil, errors.New("dial tcp 10.0.0.1:3306: connection refused")
+	}))
+	handler.Get("/conflict", WrapperFromForm(func(ctx *context.Context, _ struct{}) (any, error) {
+		return nil, errors.New("already exists")
+	}, WithBizErrorStatus(http.StatusConflict)))
+
+	cases := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		accept string
+		status int
+		detail string
+	}{
+		{name: "not found", method: http.MethodGet, url: "/missing", accept: "application/json", status: 404},
+		{name: "abort", method: http.MethodGet, url: "/error?code=0", accept: "application/problem+json", status: 404},
+		{name: "custom abort", method: http.MethodGet, url: "/error?code=409", accept: "application/json",
+			status: 409, detail: "409"},
+		{name: "ctx abort", method: http.MethodGet, url: "/abort", accept: "application/json",
+			status: 422, detail: "bad input"},
+		{name: "panic", method: http.MethodGet, url: "/panic", accept: "application/json", status: 500},
+		{name: "validation", method: http.MethodPost, url: "/signup", body: `{"name":""}`,
+			accept: "application/json", status: 422, detail: "validation failed"},
+		{name: "biz error", method: http.MethodGet, url: "/biz", accept: "application/json", status: 500},
+		{name: "biz client error", method: http.MethodGet, url: "/conflict", accept: "application/json",
+			status: 409, detail: "already exists"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, _ := http.NewRequest(c.method, c.url, strings.NewReader(c.body))
+			r.Header.Set("Accept", c.accept)
+			r.Header.Set("Content-Type", context.ApplicationJSON)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, c.status, w.Code)
+			assert.Equal(t, ProblemJSON, w.Header().Get("Content-Type"))
+			p := &Problem{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), p))
+			assert.Equal(t, "about:blank", p.Type)
+			assert.Equal(t, c.status, p.Status)
+			assert.Equal(t, http.StatusText(c.status), p.Title)
+			assert.Equal(t, c.detail, p.Detail)
+			assert.Equal(t, c.url, p.Instance)
+		})
+	}
+
+	// the berror code is kept even though the message of the panic is hidden
+	r, _ := http.NewRequest(http.MethodGet, "/panic", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	p := &Problem{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), p))
+	assert.Equal(t, "ProblemTest", p.Name)
+	assert.Equal(t, "web_test", p.Module)
+
+	r, _ = http.NewRequest(http.MethodPost, "/signup", strings.NewReader(`{"name":""}`))
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Content-Type", context.ApplicationJSON)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	p = &Problem{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), p))
+	assert.NotEmpty(t, p.Errors)
+
+	// browsers still get the error pages
+	r, _ = http.NewRequest(http.MethodGet, "/missing", nil)
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotEqual(t, ProblemJSON, w.Header().Get("Content-Type"))
+}
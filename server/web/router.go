@@ -127,6 +127,13 @@ type ControllerInfo struct {
 	initialize     func() ControllerInterface
 	methodParams   []*param.MethodParam
 	sessionOn      bool
+	timeout        time.Duration
+	// override the MaxMemory and MaxUploadSize of the config when they are positive
+	maxMemory     int64
+	maxUploadSize int64
+	streamBody    bool
+	// describes the router in the OpenAPI document, see WithRouterDoc and WithRouterHidden
+	doc *routeDoc
 }
 
 type ControllerOption func(*ControllerInfo)
@@ -151,6 +158,46 @@ func WithRouterSessionOn(sessionOn bool) ControllerOption {
 	}
 }
 
+// WithRouterTimeout sets the deadline of the requests served by the router.
+// The deadline is attached to ctx.Request.Context(), so pass it to the ORM *WithCtx methods
+// and to httplib to stop them when the time is up.
+// The timeout is cooperative: unlike http.TimeoutHandler, the response isn't buffered
+// and the handler isn't interrupted, only the handlers watching the context are cut off.
+// A handler ignoring it runs to the end, and what it writes after the deadline is still sent.
+// If the deadline is exceeded before the controller runs, 503 is sent,
+// if the controller returns after the deadline without writing a response, 504 is sent.
+// It overrides the timeout of the namespace, see NSTimeout
+func WithRouterTimeout(timeout time.Duration) ControllerOption {
+	return func(c *ControllerInfo) {
+		c.timeout = timeout
+	}
+}
+
+// WithRouterMaxMemory sets the max size of the request bodies which are not uploads,
+// and the memory used to parse the uploads. It overrides Config.MaxMemory and the namespace, see NSMaxMemory
+func WithRouterMaxMemory(size int64) ControllerOption {
+	return func(c *ControllerInfo) {
+		c.maxMemory = size
+	}
+}
+
+// WithRouterMaxUploadSize sets the max size of the uploads.
+// It overrides Config.MaxUploadSize and the namespace, see NSMaxUploadSize
+func WithRouterMaxUploadSize(size int64) ControllerOption {
+	return func(c *ControllerInfo) {
+		c.maxUploadSize = size
+	}
+}
+
+// WithRouterStreamBody leaves the request body unread, it's neither copied nor parsed,
+// so the handler reads it as a stream, eg. with Input.ForEachPart for the uploads.
+// The size of the body is still limited by the max memory or the max upload size.
+func WithRouterStreamBody() ControllerOption {
+	return func(c *ControllerInfo) {
+		c.streamBody = true
+	}
+}
+
 type filterChainConfig struct {
 	pattern string
 	chain   FilterChain
@@ -644,8 +691,8 @@ type HandleFunc func(ctx *beecontext.Context)
 //	Get("/", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) Get(pattern string, f HandleFunc) {
-	p.AddMethod("get", pattern, f)
+func (p *ControllerRegister) Get(pattern string, f HandleFunc, opts ...ControllerOption) {
+	p.AddMethod("get", pattern, f, opts...)
 }
 
 // Post add post method
@@ -654,8 +701,8 @@ func (p *ControllerRegister) Get(pattern string, f HandleFunc) {
 //	Post("/api", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) Post(pattern string, f HandleFunc) {
-	p.AddMethod("post", pattern, f)
+func (p *ControllerRegister) Post(pattern string, f HandleFunc, opts ...ControllerOption) {
+	p.AddMethod("post", pattern, f, opts...)
 }
 
 // Put add put method
@@ -664,8 +711,8 @@ func (p *ControllerRegister) Post(pattern string, f HandleFunc) {
 //	Put("/api/:id", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) Put(pattern string, f HandleFunc) {
-	p.AddMethod("put", pattern, f)
+func (p *ControllerRegister) Put(pattern string, f HandleFunc, opts ...ControllerOption) {
+	p.AddMethod("put", pattern, f, opts...)
 }
 
 // Delete add delete method
@@ -674,8 +721,8 @@ func (p *ControllerRegister) Put(pattern string, f HandleFunc) {
 //	Delete("/api/:id", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) Delete(pattern string, f HandleFunc) {
-	p.AddMethod("delete", pattern, f)
+func (p *ControllerRegister) Delete(pattern string, f HandleFunc, opts ...ControllerOption) {
+	p.AddMethod("delete", pattern, f, opts...)
 }
 
 // Head add head method
@@ -684,8 +731,8 @@ func (p *ControllerRegister) Delete(pattern string, f HandleFunc) {
 //	Head("/api/:id", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) Head(pattern string, f HandleFunc) {
-	p.AddMethod("head", pattern, f)
+func (p *ControllerRegister) Head(pattern string, f HandleFunc, opts ...ControllerOption) {
+	p.AddMethod("head", pattern, f, opts...)
 }
 
 // Patch add patch method
@@ -694,8 +741,8 @@ func (p *ControllerRegister) Head(pattern string, f HandleFunc) {
 //	Patch("/api/:id", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) Patch(pattern string, f HandleFunc) {
-	p.AddMethod("patch", pattern, f)
+func (p *ControllerRegister) Patch(pattern string, f HandleFunc, opts ...ControllerOption) {
+	p.AddMethod("patch", pattern, f, opts...)
 }
 
 // Options add options method
@@ -704,8 +751,8 @@ func (p *ControllerRegister) Patch(pattern string, f HandleFunc) {
 //	Options("/api/:id", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) Options(pattern string, f HandleFunc) {
-	p.AddMethod("options", pattern, f)
+func (p *ControllerRegister) Options(pattern string, f HandleFunc, opts ...ControllerOption) {
+	p.AddMethod("options", pattern, f, opts...)
 }
 
 // Any add all method
@@ -714,8 +761,8 @@ func (p *ControllerRegister) Options(pattern string, f HandleFunc) {
 //	Any("/api/:id", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) Any(pattern string, f HandleFunc) {
-	p.AddMethod("*", pattern, f)
+func (p *ControllerRegister) Any(pattern string, f HandleFunc, opts ...ControllerOption) {
+	p.AddMethod("*", pattern, f, opts...)
 }
 
 // AddMethod add http method router
@@ -724,12 +771,17 @@ func (p *ControllerRegister) Any(pattern string, f HandleFunc) {
 //	AddMethod("get","/api/:id", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (p *ControllerRegister) AddMethod(method, pattern string, f HandleFunc) {
+//
+// opts configure the router, eg. WithRouterTimeout or WithRouterDoc
+func (p *ControllerRegister) AddMethod(method, pattern string, f HandleFunc, opts ...ControllerOption) {
 	method = p.getUpperMethodString(method)
 
 	route := p.createRestfulRouter(f, pattern)
 	methods := p.getHttpMethodMapMethod(method, "")
 	route.methods = methods
+	for i := range opts {
+		opts[i](route)
+	}
 
 	p.addRouterForMethod(route)
 }
@@ -838,6 +890,7 @@ func (p *ControllerRegister) insertFilterRouter(pos int, mr *FilterRouter) (err
 
 // URLFor does another controller handler in this request function.
 // it can access any controller method.
+// Values which don't satisfy the constraint of a typed param, eg. :id<uuid>, are rejected.
 func (p *ControllerRegister) URLFor(endpoint string, values ...interface{}) string {
 	paths := strings.Split(endpoint, ".")
 	if len(paths) <= 1 {
@@ -912,7 +965,7 @@ func (p *ControllerRegister) getURL(t *Tree, url, controllerName, methodName str
 							return true, strings.Replace(url, "/"+urlPlaceholder, "", 1) + toURL(params)
 						}
 						if len(l.wildcards) == 1 {
-							if v, ok := params[l.wildcards[0]]; ok {
+							if v, ok := params[l.wildcards[0]]; ok && l.satisfies(l.wildcards[0], v) {
 								delete(params, l.wildcards[0])
 								return true, strings.Replace(url, urlPlaceholder, v, 1) + toURL(params)
 							}
@@ -934,6 +987,9 @@ func (p *ControllerRegister) getURL(t *Tree, url, controllerName, methodName str
 								continue
 							}
 							if u, ok := params[v]; ok {
+								if !l.satisfies(v, u) {
+									return false, ""
+								}
 								delete(params, v)
 								url = strings.Replace(url, urlPlaceholder, u, 1)
 							} else {
@@ -955,7 +1011,7 @@ func (p *ControllerRegister) getURL(t *Tree, url, controllerName, methodName str
 							continue
 						} else if v == ')' {
 							startReg = false
-							if v, ok := params[l.wildcards[i]]; ok {
+							if v, ok := params[l.wildcards[i]]; ok && l.satisfies(l.wildcards[i], v) {
 								delete(params, l.wildcards[i])
 								regURL = regURL + v
 								i++
@@ -1003,6 +1059,33 @@ func (p *ControllerRegister) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 	p.chainRoot.filter(ctx, p.getUrlPath(ctx), preFilterParams)
 }
 
+// recordRoutes returns whether the route statistics of the request are recorded, see URLMap.RouteStatistics
+func (p *ControllerRegister) recordRoutes(ctx *beecontext.Context) bool {
+	return p.cfg.Listen.EnableAdmin || ctx.Input.GetData(routeStatisticsKey) != nil
+}
+
+// recordRoute adds the request to the route statistics
+func recordRoute(ctx *beecontext.Context, startTime time.Time) {
+	pattern, ok := ctx.Input.GetData("RouterPattern").(string)
+	if !ok {
+		pattern = unknownRoutePattern
+	}
+	// the methods are sent by the clients, the invalid ones share a label
+	method := ctx.Request.Method
+	if !HTTPMETHOD[method] {
+		method = unknownRouteMethod
+	}
+	status := ctx.ResponseWriter.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	route := StatisticsMap.route(method, pattern)
+	if ctx.Input.GetData(routeStatisticsKey) != nil {
+		route.exported.Store(true)
+	}
+	route.observe(time.Now(), time.Since(startTime), status)
+}
+
 func (p *ControllerRegister) serveHttp(ctx *beecontext.Context) {
 	var err error
 	startTime := time.Now()
@@ -1020,6 +1103,10 @@ func (p *ControllerRegister) serveHttp(ctx *beecontext.Context) {
 		originFindRouter bool
 	)
 
+	// registered before RecoverFunc so that the status of the recovered panics is recorded
+	if p.recordRoutes(ctx) {
+		defer recordRoute(ctx, startTime)
+	}
 	if p.cfg.RecoverFunc != nil {
 		defer p.cfg.RecoverFunc(ctx, p.cfg)
 	}
@@ -1050,31 +1137,45 @@ func (p *ControllerRegister) serveHttp(ctx *beecontext.Context) {
 		goto Admin
 	}
 
+	originRouterInfo, originFindRouter = p.FindRouter(ctx)
 	if r.Method != http.MethodGet && r.Method != http.MethodHead {
 		body := ctx.Input.Context.Request.Body
 		if body == nil {
 			body = io.NopCloser(bytes.NewReader([]byte{}))
 		}
 
+		maxMemory, maxUploadSize, streamBody := p.cfg.MaxMemory, p.cfg.MaxUploadSize, false
+		if originFindRouter && originRouterInfo != nil {
+			if originRouterInfo.maxMemory > 0 {
+				maxMemory = originRouterInfo.maxMemory
+			}
+			if originRouterInfo.maxUploadSize > 0 {
+				maxUploadSize = originRouterInfo.maxUploadSize
+			}
+			streamBody = originRouterInfo.streamBody
+		}
+
 		if ctx.Input.IsUpload() {
 			ctx.Input.Context.Request.Body = http.MaxBytesReader(ctx.Input.Context.ResponseWriter,
 				body,
-				p.cfg.MaxUploadSize)
-		} else if p.cfg.CopyRequestBody {
+				maxUploadSize)
+		} else if p.cfg.CopyRequestBody && !streamBody {
 			// connection will close if the incoming data are larger (RFC 7231, 6.5.11)
-			if r.ContentLength > p.cfg.MaxMemory {
+			if r.ContentLength > maxMemory {
 				logs.Error(errors.New("payload too large"))
 				exception("413", ctx)
 				goto Admin
 			}
-			ctx.Input.CopyBody(p.cfg.MaxMemory)
+			ctx.Input.CopyBody(maxMemory)
 		} else {
 			ctx.Input.Context.Request.Body = http.MaxBytesReader(ctx.Input.Context.ResponseWriter,
 				body,
-				p.cfg.MaxMemory)
+				maxMemory)
 		}
 
-		err = ctx.Input.ParseFormOrMultiForm(p.cfg.MaxMemory)
+		if !streamBody {
+			err = ctx.Input.ParseFormOrMultiForm(maxMemory)
+		}
 		if err != nil {
 			logs.Error(err)
 			if strings.Contains(err.Error(), `http: request body too large`) {
@@ -1088,7 +1189,6 @@ func (p *ControllerRegister) serveHttp(ctx *beecontext.Context) {
 
 	// session init
 	currentSessionOn = p.cfg.WebConfig.Session.SessionOn
-	originRouterInfo, originFindRouter = p.FindRouter(ctx)
 	if originFindRouter {
 		currentSessionOn = originRouterInfo.sessionOn
 	}
@@ -1131,6 +1231,19 @@ func (p *ControllerRegister) serveHttp(ctx *beecontext.Context) {
 	if routerInfo != nil {
 		// store router pattern into context
 		ctx.Input.SetData("RouterPattern", routerInfo.pattern)
+
+		if p.recordRoutes(ctx) {
+			route := StatisticsMap.route(r.Method, routerInfo.pattern)
+			route.inFlight.Add(1)
+			defer route.inFlight.Add(-1)
+		}
+
+		if routerInfo.timeout > 0 {
+			timeoutCtx, cancel := context.WithTimeout(r.Context(), routerInfo.timeout)
+			defer cancel()
+			ctx.Request = r.WithContext(timeoutCtx)
+			r = ctx.Request
+		}
 	}
 
 	// execute middleware filters
@@ -1143,6 +1256,12 @@ func (p *ControllerRegister) serveHttp(ctx *beecontext.Context) {
 		goto Admin
 	}
 
+	// the time is up before running the controller
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		exception("503", ctx)
+		goto Admin
+	}
+
 	if routerInfo != nil {
 		if routerInfo.routerType == routerTypeRESTFul {
 			if _, ok := routerInfo.methods[r.Method]; ok {
@@ -1240,8 +1359,9 @@ func (p *ControllerRegister) serveHttp(ctx *beecontext.Context) {
 				}
 			}
 
-			// render template
-			if !ctx.ResponseWriter.Started && ctx.Output.Status == 0 {
+			// render template, unless the time is up
+			if !ctx.ResponseWriter.Started && ctx.Output.Status == 0 &&
+				!errors.Is(r.Context().Err(), context.DeadlineExceeded) {
 				if p.cfg.WebConfig.AutoRender {
 					if err := execController.Render(); err != nil {
 						logs.Error(err)
@@ -1254,6 +1374,10 @@ func (p *ControllerRegister) serveHttp(ctx *beecontext.Context) {
 		execController.Finish()
 	}
 
+	if !ctx.ResponseWriter.Started && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		exception("504", ctx)
+	}
+
 	// execute middleware filters
 	if len(p.filters[AfterExec]) > 0 && p.execFilter(ctx, urlPath, AfterExec) {
 		goto Admin
@@ -16,13 +16,22 @@ package web
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 
 	"github.com/asish-tom/beego/v2/core/logs"
 	"github.com/asish-tom/beego/v2/server/web/context"
@@ -134,6 +143,25 @@ func TestUrlFor(t *testing.T) {
 	}
 }
 
+func TestUrlForConstraint(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/users/:id<uuid>", &TestController{}, WithRouterMethods(&TestController{}, "get:GetURL"))
+	handler.Add("/report/:day<date>/:page<int>", &TestController{}, WithRouterMethods(&TestController{}, "get:List"))
+	id := "5f0c7c8e-4a3b-4c1d-9e2f-1a2b3c4d5e6f"
+	if a := handler.URLFor("TestController.GetURL", ":id", id); a != "/users/"+id {
+		t.Errorf("TestController.GetURL must equal to /users/%s, but get %s", id, a)
+	}
+	if a := handler.URLFor("TestController.GetURL", ":id", "astaxie"); a != "" {
+		t.Errorf("TestController.GetURL must reject non uuid id, but get " + a)
+	}
+	if a := handler.URLFor("TestController.List", ":day", "2020-02-29", ":page", "2"); a != "/report/2020-02-29/2" {
+		t.Errorf("TestController.List must equal to /report/2020-02-29/2, but get " + a)
+	}
+	if a := handler.URLFor("TestController.List", ":day", "2021-02-29", ":page", "2"); a != "" {
+		t.Errorf("TestController.List must reject invalid date, but get " + a)
+	}
+}
+
 func TestUrlFor3(t *testing.T) {
 	handler := NewControllerRegister()
 	handler.AddAuto(&TestController{})
@@ -1128,3 +1156,141 @@ func TestGetAllControllerInfo(t *testing.T) {
 		t.Errorf("ControllerInfo.GetMethod expected %#v, but %#v got", expectedMethods, actualMethods)
 	}
 }
+
+type timeoutController struct {
+	Controller
+}
+
+func (c *timeoutController) Get() {
+	select {
+	case <-c.Ctx.Request.Context().Done():
+	case <-time.After(time.Second):
+		c.Ctx.WriteString("done")
+	}
+}
+
+func (c *timeoutController) Fast() {
+	_, ok := c.Ctx.Request.Context().Deadline()
+	c.Ctx.WriteString(strconv.FormatBool(ok))
+}
+
+func TestRouterTimeout(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/slow", &timeoutController{}, WithRouterTimeout(10*time.Millisecond))
+	handler.Add("/fast", &timeoutController{}, WithRouterMethods(&timeoutController{}, "get:Fast"),
+		WithRouterTimeout(time.Second))
+	handler.Add("/none", &timeoutController{}, WithRouterMethods(&timeoutController{}, "get:Fast"))
+	handler.Add("/queued", &timeoutController{}, WithRouterMethods(&timeoutController{}, "get:Fast"),
+		WithRouterTimeout(time.Millisecond))
+	handler.InsertFilter("/queued", BeforeExec, func(ctx *context.Context) {
+		time.Sleep(5 * time.Millisecond)
+	})
+
+	cases := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{url: "/slow", status: http.StatusGatewayTimeout},
+		{url: "/fast", status: http.StatusOK, body: "true"},
+		{url: "/none", status: http.StatusOK, body: "false"},
+		{url: "/queued", status: http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, c.url, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, c.status, w.Code)
+			if c.body != "" {
+				assert.Equal(t, c.body, w.Body.String())
+			}
+		})
+	}
+
+}
+
+type bodyController struct {
+	Controller
+}
+
+func (c *bodyController) Post() {
+	c.Ctx.WriteString(c.Ctx.Input.Query("v"))
+}
+
+// Upload responds the names and the sha256 of the parts
+func (c *bodyController) Upload() {
+	var res []string
+	err := c.Ctx.Input.ForEachPart(func(part *multipart.Part) error {
+		h := sha256.New()
+		if _, err := io.Copy(h, part); err != nil {
+			return err
+		}
+		res = append(res, part.FormName()+":"+hex.EncodeToString(h.Sum(nil))[:8])
+		return nil
+	})
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		c.Ctx.Output.SetStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		return
+	}
+	c.Ctx.WriteString(strings.Join(res, ","))
+}
+
+func newMultipartRequest(t *testing.T, url string, size int) *http.Request {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("name", "report")
+	fw, err := mw.CreateFormFile("file", "report.csv")
+	assert.Nil(t, err)
+	_, _ = fw.Write(bytes.Repeat([]byte("a"), size))
+	assert.Nil(t, mw.Close())
+	r, _ := http.NewRequest(http.MethodPost, url, body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestRouterBodyLimits(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.Add("/small", &bodyController{}, WithRouterMaxMemory(16))
+	handler.Add("/large", &bodyController{})
+	handler.Add("/ingest", &bodyController{}, WithRouterMethods(&bodyController{}, "post:Upload"),
+		WithRouterMaxUploadSize(1<<10), WithRouterStreamBody())
+
+	cases := []struct {
+		name   string
+		req    *http.Request
+		status int
+		body   string
+	}{
+		{name: "small", req: newFormRequest("/small", "v=ok"), status: http.StatusOK, body: "ok"},
+		{name: "small too large", req: newFormRequest("/small", "v="+strings.Repeat("a", 32)),
+			status: http.StatusRequestEntityTooLarge},
+		{name: "large", req: newFormRequest("/large", "v="+strings.Repeat("a", 32)),
+			status: http.StatusOK, body: strings.Repeat("a", 32)},
+		{name: "stream", req: newMultipartRequest(t, "/ingest", 512),
+			status: http.StatusOK, body: "name:845e9183,file:471be655"},
+		{name: "stream too large", req: newMultipartRequest(t, "/ingest", 2<<10),
+			status: http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, c.req)
+			assert.Equal(t, c.status, w.Code)
+			if c.body != "" {
+				assert.Equal(t, c.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func newFormRequest(url, body string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
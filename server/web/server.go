@@ -15,25 +15,19 @@
 package web
 
 import (
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"net"
 	"net/http"
-	"net/http/fcgi"
-	"os"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
-	"golang.org/x/crypto/acme/autocert"
-
 	"github.com/asish-tom/beego/v2/core/logs"
 	"github.com/asish-tom/beego/v2/core/utils"
 	beecontext "github.com/asish-tom/beego/v2/server/web/context"
-	"github.com/asish-tom/beego/v2/server/web/grace"
 )
 
 // BeeApp is an application instance
@@ -52,6 +46,19 @@ type HttpServer struct {
 	Server             *http.Server
 	Cfg                *Config
 	LifeCycleCallbacks []LifeCycleCallback
+	// Listeners are served by Run besides those enabled by Cfg.Listen, see AddListener
+	Listeners []Listener
+
+	// shutdown pipeline, see Shutdown
+	mu            sync.Mutex
+	running       []Listener
+	shutdownHooks []namedShutdownHook
+	stopping      atomic.Bool
+	drainOnce     sync.Once
+	shutdownOnce  sync.Once
+	shutdownErr   error
+	// the h2c connections are hijacked, http.Server.Shutdown doesn't wait for them
+	h2cConns atomic.Int64
 }
 
 // NewHttpSever returns a new beego application.
@@ -97,211 +104,35 @@ func (app *HttpServer) Run(addr string, mws ...MiddleWare) {
 		addr = fmt.Sprintf("%s:%d", app.Cfg.Listen.HTTPAddr, app.Cfg.Listen.HTTPPort)
 	}
 
-	var (
-		err        error
-		l          net.Listener
-		endRunning = make(chan bool, 1)
-	)
-
-	// run cgi server
+	// fcgi serves the handlers without the middlewares
 	if app.Cfg.Listen.EnableFcgi {
-		for _, lifeCycleCallback := range app.LifeCycleCallbacks {
-			lifeCycleCallback.AfterStart(app)
-		}
-		if app.Cfg.Listen.EnableStdIo {
-			if err = fcgi.Serve(nil, app.Handlers); err == nil { // standard I/O
-				logs.Info("Use FCGI via standard I/O")
-			} else {
-				logs.Critical("Cannot use FCGI via standard I/O", err)
-			}
-			for _, lifeCycleCallback := range app.LifeCycleCallbacks {
-				lifeCycleCallback.BeforeShutdown(app)
-			}
-			return
-		}
-		if app.Cfg.Listen.HTTPPort == 0 {
-			// remove the Socket file before start
-			if utils.FileExists(addr) {
-				os.Remove(addr)
-			}
-			l, err = net.Listen("unix", addr)
-		} else {
-			l, err = net.Listen("tcp", addr)
-		}
-		if err != nil {
-			logs.Critical("Listen for Fcgi: ", err)
-		}
-		if err = fcgi.Serve(l, app.Handlers); err != nil {
-			logs.Critical("fcgi.Serve: ", err)
-		}
-		for _, lifeCycleCallback := range app.LifeCycleCallbacks {
-			lifeCycleCallback.BeforeShutdown(app)
-		}
+		app.serve(app.Handlers, app.listeners(addr))
 		return
 	}
 
-	app.Server.Handler = app.Handlers
+	var handler http.Handler = app.Handlers
 	for i := len(mws) - 1; i >= 0; i-- {
 		if mws[i] == nil {
 			continue
 		}
-		app.Server.Handler = mws[i](app.Server.Handler)
+		handler = mws[i](handler)
 	}
+	app.Server.Handler = handler
+	app.Server.Addr = addr
 	app.Server.ReadTimeout = time.Duration(app.Cfg.Listen.ServerTimeOut) * time.Second
 	app.Server.WriteTimeout = time.Duration(app.Cfg.Listen.ServerTimeOut) * time.Second
 	app.Server.ErrorLog = logs.GetLogger("HTTP")
-
-	// run graceful mode
-	if app.Cfg.Listen.Graceful {
-		var opts []grace.ServerOption
-		for _, lifeCycleCallback := range app.LifeCycleCallbacks {
-			lifeCycleCallbackDup := lifeCycleCallback
-			opts = append(opts, grace.WithShutdownCallback(func() {
-				lifeCycleCallbackDup.BeforeShutdown(app)
-			}))
-		}
-
-		httpsAddr := app.Cfg.Listen.HTTPSAddr
-		app.Server.Addr = httpsAddr
-		if app.Cfg.Listen.EnableHTTPS || app.Cfg.Listen.EnableMutualHTTPS {
-			go func() {
-				time.Sleep(1000 * time.Microsecond)
-				if app.Cfg.Listen.HTTPSPort != 0 {
-					httpsAddr = fmt.Sprintf("%s:%d", app.Cfg.Listen.HTTPSAddr, app.Cfg.Listen.HTTPSPort)
-					app.Server.Addr = httpsAddr
-				}
-				server := grace.NewServer(httpsAddr, app.Server.Handler, opts...)
-				server.Server.ReadTimeout = app.Server.ReadTimeout
-				server.Server.WriteTimeout = app.Server.WriteTimeout
-				var ln net.Listener
-				if app.Cfg.Listen.EnableMutualHTTPS {
-					if ln, err = server.ListenMutualTLS(app.Cfg.Listen.HTTPSCertFile,
-						app.Cfg.Listen.HTTPSKeyFile,
-						app.Cfg.Listen.TrustCaFile); err != nil {
-						logs.Critical("ListenMutualTLS: ", err, fmt.Sprintf("%d", os.Getpid()))
-						return
-					}
-				} else {
-					if app.Cfg.Listen.AutoTLS {
-						m := autocert.Manager{
-							Prompt:     autocert.AcceptTOS,
-							HostPolicy: autocert.HostWhitelist(app.Cfg.Listen.Domains...),
-							Cache:      autocert.DirCache(app.Cfg.Listen.TLSCacheDir),
-						}
-						app.Server.TLSConfig = &tls.Config{GetCertificate: m.GetCertificate}
-						app.Cfg.Listen.HTTPSCertFile, app.Cfg.Listen.HTTPSKeyFile = "", ""
-					}
-					if ln, err = server.ListenTLS(app.Cfg.Listen.HTTPSCertFile, app.Cfg.Listen.HTTPSKeyFile); err != nil {
-						logs.Critical("ListenTLS: ", err, fmt.Sprintf("%d", os.Getpid()))
-						return
-					}
-				}
-				for _, callback := range app.LifeCycleCallbacks {
-					callback.AfterStart(app)
-				}
-				if err = server.ServeTLS(ln); err != nil {
-					logs.Critical("ServeTLS: ", err, fmt.Sprintf("%d", os.Getpid()))
-					time.Sleep(100 * time.Microsecond)
-				}
-				endRunning <- true
-			}()
-		}
-		if app.Cfg.Listen.EnableHTTP {
-			go func() {
-				server := grace.NewServer(addr, app.Server.Handler, opts...)
-				server.Server.ReadTimeout = app.Server.ReadTimeout
-				server.Server.WriteTimeout = app.Server.WriteTimeout
-				if app.Cfg.Listen.ListenTCP4 {
-					server.Network = "tcp4"
-				}
-				ln, err := net.Listen(server.Network, server.Addr)
-				logs.Info("graceful http server Running on http://%s", server.Addr)
-				if err != nil {
-					logs.Critical("Listen for HTTP[graceful mode]: ", err)
-					endRunning <- true
-					return
-				}
-				for _, callback := range app.LifeCycleCallbacks {
-					callback.AfterStart(app)
-				}
-				if err := server.ServeWithListener(ln); err != nil {
-					logs.Critical("ServeWithListener: ", err, fmt.Sprintf("%d", os.Getpid()))
-					time.Sleep(100 * time.Microsecond)
-				}
-				endRunning <- true
-			}()
+	if app.Cfg.Listen.EnableH2C && !app.Cfg.Listen.Graceful {
+		// after the timeouts, HTTP/2 uses the same ones
+		h, err := app.h2cHandler(app.Server, handler)
+		if err != nil {
+			logs.Critical("h2c: ", err)
+		} else {
+			app.Server.Handler = h
 		}
-		<-endRunning
-		return
 	}
 
-	// run normal mode
-	if app.Cfg.Listen.EnableHTTPS || app.Cfg.Listen.EnableMutualHTTPS {
-		go func() {
-			time.Sleep(1000 * time.Microsecond)
-			if app.Cfg.Listen.HTTPSPort != 0 {
-				app.Server.Addr = fmt.Sprintf("%s:%d", app.Cfg.Listen.HTTPSAddr, app.Cfg.Listen.HTTPSPort)
-			} else if app.Cfg.Listen.EnableHTTP {
-				logs.Info("Start https server error, conflict with http. Please reset https port")
-				return
-			}
-			logs.Info("https server Running on https://%s", app.Server.Addr)
-			if app.Cfg.Listen.AutoTLS {
-				m := autocert.Manager{
-					Prompt:     autocert.AcceptTOS,
-					HostPolicy: autocert.HostWhitelist(app.Cfg.Listen.Domains...),
-					Cache:      autocert.DirCache(app.Cfg.Listen.TLSCacheDir),
-				}
-				app.Server.TLSConfig = &tls.Config{GetCertificate: m.GetCertificate}
-				app.Cfg.Listen.HTTPSCertFile, app.Cfg.Listen.HTTPSKeyFile = "", ""
-			} else if app.Cfg.Listen.EnableMutualHTTPS {
-				pool := x509.NewCertPool()
-				data, err := os.ReadFile(app.Cfg.Listen.TrustCaFile)
-				if err != nil {
-					logs.Info("MutualHTTPS should provide TrustCaFile")
-					return
-				}
-				pool.AppendCertsFromPEM(data)
-				app.Server.TLSConfig = &tls.Config{
-					ClientCAs:  pool,
-					ClientAuth: tls.ClientAuthType(app.Cfg.Listen.ClientAuth),
-				}
-			}
-			if err := app.Server.ListenAndServeTLS(app.Cfg.Listen.HTTPSCertFile, app.Cfg.Listen.HTTPSKeyFile); err != nil {
-				logs.Critical("ListenAndServeTLS: ", err)
-				time.Sleep(100 * time.Microsecond)
-				endRunning <- true
-			}
-		}()
-	}
-	if app.Cfg.Listen.EnableHTTP {
-		go func() {
-			app.Server.Addr = addr
-			logs.Info("http server Running on http://%s", app.Server.Addr)
-			if app.Cfg.Listen.ListenTCP4 {
-				ln, err := net.Listen("tcp4", app.Server.Addr)
-				if err != nil {
-					logs.Critical("Listen for HTTP[normal mode]: ", err)
-					time.Sleep(100 * time.Microsecond)
-					endRunning <- true
-					return
-				}
-				if err = app.Server.Serve(ln); err != nil {
-					logs.Critical("Serve: ", err)
-					time.Sleep(100 * time.Microsecond)
-					endRunning <- true
-					return
-				}
-			} else {
-				if err := app.Server.ListenAndServe(); err != nil {
-					logs.Critical("ListenAndServe: ", err)
-					time.Sleep(100 * time.Microsecond)
-					endRunning <- true
-				}
-			}
-		}()
-	}
-	<-endRunning
+	app.serve(handler, append(app.listeners(addr), app.Listeners...))
 }
 
 // Router see HttpServer.Router
@@ -674,8 +505,8 @@ func (app *HttpServer) CtrlAny(rootpath string, f interface{}) *HttpServer {
 }
 
 // Get see HttpServer.Get
-func Get(rootpath string, f HandleFunc) *HttpServer {
-	return BeeApp.Get(rootpath, f)
+func Get(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	return BeeApp.Get(rootpath, f, opts...)
 }
 
 // Get used to register router for Get method
@@ -684,14 +515,14 @@ func Get(rootpath string, f HandleFunc) *HttpServer {
 //	beego.Get("/", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (app *HttpServer) Get(rootpath string, f HandleFunc) *HttpServer {
-	app.Handlers.Get(rootpath, f)
+func (app *HttpServer) Get(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	app.Handlers.Get(rootpath, f, opts...)
 	return app
 }
 
 // Post see HttpServer.Post
-func Post(rootpath string, f HandleFunc) *HttpServer {
-	return BeeApp.Post(rootpath, f)
+func Post(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	return BeeApp.Post(rootpath, f, opts...)
 }
 
 // Post used to register router for Post method
@@ -700,14 +531,14 @@ func Post(rootpath string, f HandleFunc) *HttpServer {
 //	beego.Post("/api", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (app *HttpServer) Post(rootpath string, f HandleFunc) *HttpServer {
-	app.Handlers.Post(rootpath, f)
+func (app *HttpServer) Post(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	app.Handlers.Post(rootpath, f, opts...)
 	return app
 }
 
 // Delete see HttpServer.Delete
-func Delete(rootpath string, f HandleFunc) *HttpServer {
-	return BeeApp.Delete(rootpath, f)
+func Delete(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	return BeeApp.Delete(rootpath, f, opts...)
 }
 
 // Delete used to register router for Delete method
@@ -716,14 +547,14 @@ func Delete(rootpath string, f HandleFunc) *HttpServer {
 //	beego.Delete("/api", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (app *HttpServer) Delete(rootpath string, f HandleFunc) *HttpServer {
-	app.Handlers.Delete(rootpath, f)
+func (app *HttpServer) Delete(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	app.Handlers.Delete(rootpath, f, opts...)
 	return app
 }
 
 // Put see HttpServer.Put
-func Put(rootpath string, f HandleFunc) *HttpServer {
-	return BeeApp.Put(rootpath, f)
+func Put(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	return BeeApp.Put(rootpath, f, opts...)
 }
 
 // Put used to register router for Put method
@@ -732,14 +563,14 @@ func Put(rootpath string, f HandleFunc) *HttpServer {
 //	beego.Put("/api", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (app *HttpServer) Put(rootpath string, f HandleFunc) *HttpServer {
-	app.Handlers.Put(rootpath, f)
+func (app *HttpServer) Put(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	app.Handlers.Put(rootpath, f, opts...)
 	return app
 }
 
 // Head see HttpServer.Head
-func Head(rootpath string, f HandleFunc) *HttpServer {
-	return BeeApp.Head(rootpath, f)
+func Head(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	return BeeApp.Head(rootpath, f, opts...)
 }
 
 // Head used to register router for Head method
@@ -748,14 +579,14 @@ func Head(rootpath string, f HandleFunc) *HttpServer {
 //	beego.Head("/api", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (app *HttpServer) Head(rootpath string, f HandleFunc) *HttpServer {
-	app.Handlers.Head(rootpath, f)
+func (app *HttpServer) Head(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	app.Handlers.Head(rootpath, f, opts...)
 	return app
 }
 
 // Options see HttpServer.Options
-func Options(rootpath string, f HandleFunc) *HttpServer {
-	BeeApp.Handlers.Options(rootpath, f)
+func Options(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	BeeApp.Handlers.Options(rootpath, f, opts...)
 	return BeeApp
 }
 
@@ -765,14 +596,14 @@ func Options(rootpath string, f HandleFunc) *HttpServer {
 //	beego.Options("/api", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (app *HttpServer) Options(rootpath string, f HandleFunc) *HttpServer {
-	app.Handlers.Options(rootpath, f)
+func (app *HttpServer) Options(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	app.Handlers.Options(rootpath, f, opts...)
 	return app
 }
 
 // Patch see HttpServer.Patch
-func Patch(rootpath string, f HandleFunc) *HttpServer {
-	return BeeApp.Patch(rootpath, f)
+func Patch(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	return BeeApp.Patch(rootpath, f, opts...)
 }
 
 // Patch used to register router for Patch method
@@ -781,14 +612,14 @@ func Patch(rootpath string, f HandleFunc) *HttpServer {
 //	beego.Patch("/api", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (app *HttpServer) Patch(rootpath string, f HandleFunc) *HttpServer {
-	app.Handlers.Patch(rootpath, f)
+func (app *HttpServer) Patch(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	app.Handlers.Patch(rootpath, f, opts...)
 	return app
 }
 
 // Any see HttpServer.Any
-func Any(rootpath string, f HandleFunc) *HttpServer {
-	return BeeApp.Any(rootpath, f)
+func Any(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	return BeeApp.Any(rootpath, f, opts...)
 }
 
 // Any used to register router for all methods
@@ -797,8 +628,8 @@ func Any(rootpath string, f HandleFunc) *HttpServer {
 //	beego.Any("/api", func(ctx *context.Context){
 //	      ctx.Output.Body("hello world")
 //	})
-func (app *HttpServer) Any(rootpath string, f HandleFunc) *HttpServer {
-	app.Handlers.Any(rootpath, f)
+func (app *HttpServer) Any(rootpath string, f HandleFunc, opts ...ControllerOption) *HttpServer {
+	app.Handlers.Any(rootpath, f, opts...)
 	return app
 }
 
@@ -888,6 +719,7 @@ func (app *HttpServer) LogAccess(ctx *beecontext.Context, startTime *time.Time,
 		HTTPUserAgent:  r.Header.Get("User-Agent"),
 		RemoteUser:     r.Header.Get("Remote-User"),
 		BodyBytesSent:  r.ContentLength,
+		RequestID:      logs.RequestID(r.Context()),
 	}
 	logs.AccessLog(record, app.Cfg.Log.AccessLogsFormat)
 }
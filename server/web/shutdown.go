@@ -0,0 +1,169 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/asish-tom/beego/v2/core/admin"
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/task"
+)
+
+// ShutdownHook releases a resource when the application shuts down, see AddShutdownHook
+type ShutdownHook func(ctx context.Context) error
+
+type namedShutdownHook struct {
+	name string
+	hook ShutdownHook
+}
+
+// AddShutdownHook registers a hook run by Shutdown after the tasks are stopped,
+// the hooks are run in registered order, eg. closing the ORM databases:
+//
+//	web.AddShutdownHook("orm", orm.Shutdown)
+func (app *HttpServer) AddShutdownHook(name string, hook ShutdownHook) *HttpServer {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	app.shutdownHooks = append(app.shutdownHooks, namedShutdownHook{name: name, hook: hook})
+	return app
+}
+
+// AddShutdownHook see HttpServer.AddShutdownHook
+func AddShutdownHook(name string, hook ShutdownHook) *HttpServer {
+	return BeeApp.AddShutdownHook(name, hook)
+}
+
+// AddShutdownCloser registers c to be closed by Shutdown, in registered order with the shutdown hooks. eg. the caches:
+//
+//	bm, _ := cache.NewCache("redis", `{"conn":"127.0.0.1:6379"}`)
+//	web.AddShutdownCloser("redis", bm.(io.Closer))
+//
+// The redis and ssdb caches implement io.Closer. The memcache cache doesn't,
+// its connections are dropped when the process exits, and the memory and file caches have nothing to close.
+func (app *HttpServer) AddShutdownCloser(name string, c io.Closer) *HttpServer {
+	return app.AddShutdownHook(name, func(context.Context) error {
+		return c.Close()
+	})
+}
+
+// AddShutdownCloser see HttpServer.AddShutdownCloser
+func AddShutdownCloser(name string, c io.Closer) *HttpServer {
+	return BeeApp.AddShutdownCloser(name, c)
+}
+
+// Shutdown stops the application without dropping the active requests:
+//  1. the admin healthcheck reports not ready
+//  2. wait Listen.ShutdownDrainTime so that the load balancers stop sending requests
+//  3. shut down the listeners, waiting Listen.ShutdownTimeout at most for the active requests
+//  4. stop the tasks if they are started, and wait for the running ones
+//  5. run the shutdown hooks in registered order
+//  6. flush the logs
+//
+// The ORM databases are closed only if their hook is registered: web.AddShutdownHook("orm", orm.Shutdown).
+// Run calls it on SIGINT and SIGTERM when Listen.ShutdownOnSignal is true, and returns when it's done.
+// The pipeline runs once, the later calls wait for it and return the same error.
+func (app *HttpServer) Shutdown(ctx context.Context) error {
+	app.shutdownOnce.Do(func() {
+		app.shutdownErr = app.shutdown(ctx)
+	})
+	return app.shutdownErr
+}
+
+// Shutdown see HttpServer.Shutdown
+func Shutdown(ctx context.Context) error {
+	return BeeApp.Shutdown(ctx)
+}
+
+func (app *HttpServer) shutdown(ctx context.Context) error {
+	app.drain(ctx)
+
+	if timeout := app.Cfg.Listen.ShutdownTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	app.mu.Lock()
+	listeners, hooks := app.running, app.shutdownHooks
+	app.mu.Unlock()
+
+	var errs []error
+	for _, l := range listeners {
+		if err := l.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shut down the listener: %w", err))
+		}
+	}
+	if err := app.waitH2CConns(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("wait for the h2c connections: %w", err))
+	}
+
+	// the tasks may use the resources released by the hooks
+	if err := task.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("stop the tasks: %w", err))
+	}
+
+	for _, h := range hooks {
+		if err := h.hook(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", h.name, err))
+		}
+	}
+
+	logs.GetBeeLogger().Flush()
+	return errors.Join(errs...)
+}
+
+// drain makes the admin healthcheck report not ready and waits Listen.ShutdownDrainTime,
+// the listeners keep serving the requests meanwhile
+func (app *HttpServer) drain(ctx context.Context) {
+	app.drainOnce.Do(func() {
+		app.stopping.Store(true)
+		admin.SetReady(false)
+
+		d := time.Duration(app.Cfg.Listen.ShutdownDrainTime) * time.Second
+		if d <= 0 {
+			return
+		}
+		logs.Info("the server is not ready any more, draining the requests for %s", d)
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// shutdownOnSignal runs Shutdown when the process receives SIGINT or SIGTERM before stopped is closed
+func (app *HttpServer) shutdownOnSignal(stopped <-chan struct{}) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	select {
+	case sig := <-sigs:
+		logs.Info("received %s, shutting down the server", sig)
+		// serve waits for the pipeline and reports its error
+		_ = app.Shutdown(context.Background())
+	case <-stopped:
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 beego
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/core/admin"
+	"github.com/asish-tom/beego/v2/task"
+)
+
+func TestHttpServerShutdown(t *testing.T) {
+	defer admin.SetReady(true)
+	app := NewHttpServerWithCfg(newBConfig())
+	l := &testListener{served: make(chan http.Handler, 1), stop: make(chan struct{})}
+	app.AddListener(l)
+
+	var steps []string
+	app.AddShutdownHook("first", func(ctx context.Context) error {
+		// the listeners are shut down before the hooks
+		select {
+		case <-l.stop:
+			steps = append(steps, "first")
+		default:
+			steps = append(steps, "first before the listeners")
+		}
+		return nil
+	})
+	app.AddShutdownHook("second", func(ctx context.Context) error {
+		steps = append(steps, "second")
+		return errors.New("close failed")
+	})
+	app.AddShutdownCloser("closer", closerFunc(func() error {
+		steps = append(steps, "closer")
+		return nil
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		app.serve(app.Handlers, app.Listeners)
+		close(done)
+	}()
+	<-l.served
+	assert.True(t, admin.IsReady())
+
+	err := app.Shutdown(context.Background())
+	assert.EqualError(t, err, "shutdown hook second: close failed")
+	assert.False(t, admin.IsReady())
+	assert.Equal(t, []string{"first", "second", "closer"}, steps)
+	<-done
+
+	// the pipeline runs once
+	assert.Equal(t, err, app.Shutdown(context.Background()))
+	assert.Len(t, steps, 3)
+}
+
+func TestHttpServerShutdownTasks(t *testing.T) {
+	defer admin.SetReady(true)
+	defer task.ClearTask()
+	app := NewHttpServerWithCfg(newBConfig())
+
+	running := make(chan struct{}, 1)
+	var finished atomic.Bool
+	task.AddTask("shutdown", task.NewTask("shutdown", "* * * * * *", func(ctx context.Context) error {
+		select {
+		case running <- struct{}{}:
+		default:
+		}
+		time.Sleep(100 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	}))
+	task.StartTask()
+	<-running
+
+	// the running tasks are done before the hooks run
+	var hookRan bool
+	app.AddShutdownHook("orm", func(ctx context.Context) error {
+		hookRan = finished.Load()
+		return nil
+	})
+	assert.NoError(t, app.Shutdown(context.Background()))
+	assert.True(t, hookRan)
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func TestHttpServerShutdownDrain(t *testing.T) {
+	defer admin.SetReady(true)
+	cfg := newBConfig()
+	cfg.Listen.ShutdownDrainTime = 10
+	app := NewHttpServerWithCfg(cfg)
+
+	// the drain period is cut short when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	shutdown := make(chan struct{})
+	go func() {
+		_ = app.Shutdown(ctx)
+		close(shutdown)
+	}()
+	require.Eventually(t, func() bool {
+		return !admin.IsReady()
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	select {
+	case <-shutdown:
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown keeps draining after the context is done")
+	}
+}
@@ -16,11 +16,16 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -39,7 +44,7 @@ func serverStaticRouter(ctx *context.Context) {
 		return
 	}
 
-	fbd, filePath, fileInfo, err := lookupFile(ctx)
+	fbd, sfs, filePath, fileInfo, err := lookupFile(ctx)
 	if err == errNotStaticRequest {
 		return
 	}
@@ -64,14 +69,31 @@ func serverStaticRouter(ctx *context.Context) {
 				redirectURL = redirectURL + "?" + ctx.Request.URL.RawQuery
 			}
 			ctx.Redirect(302, redirectURL)
-		} else {
+		} else if sfs.name == "" {
 			// serveFile will list dir
 			http.ServeFile(ctx.ResponseWriter, ctx.Request, filePath)
+		} else {
+			serveFSDir(ctx, sfs, filePath)
 		}
 		return
-	} else if fileInfo.Size() > int64(BConfig.WebConfig.StaticCacheFileSize) {
+	}
+	if BConfig.WebConfig.StaticCacheImmutable && isFingerprinted(ctx.Request.URL.Path) {
+		rw := ctx.ResponseWriter.ResponseWriter
+		ctx.ResponseWriter.ResponseWriter = &immutableWriter{ResponseWriter: rw}
+		defer func() {
+			ctx.ResponseWriter.ResponseWriter = rw
+		}()
+	}
+	if BConfig.WebConfig.StaticPrecompressed && servePrecompressed(ctx, sfs, filePath, fileInfo) {
+		return
+	}
+	if fileInfo.Size() > int64(BConfig.WebConfig.StaticCacheFileSize) {
 		// over size file serve with http module
-		http.ServeFile(ctx.ResponseWriter, ctx.Request, filePath)
+		if sfs.name == "" {
+			http.ServeFile(ctx.ResponseWriter, ctx.Request, filePath)
+		} else {
+			serveFSFile(ctx, sfs, filePath)
+		}
 		return
 	}
 
@@ -80,7 +102,7 @@ func serverStaticRouter(ctx *context.Context) {
 	if enableCompress {
 		acceptEncoding = context.ParseEncoding(ctx.Request)
 	}
-	b, n, sch, reader, err := openFile(filePath, fileInfo, acceptEncoding)
+	b, n, sch, reader, err := openFile(sfs, filePath, fileInfo, acceptEncoding)
 	if err != nil {
 		if BConfig.RunMode == DEV {
 			logs.Warn("Can't compress the file:", filePath, err)
@@ -98,6 +120,82 @@ func serverStaticRouter(ctx *context.Context) {
 	http.ServeContent(ctx.ResponseWriter, ctx.Request, filePath, sch.modTime, reader)
 }
 
+// staticFileSystem is the file system of a static path
+type staticFileSystem struct {
+	fs.FS
+	// identifies the file system in the cache, it's empty for the disk
+	name string
+}
+
+// diskFS opens the paths as they are, relative to the working directory or absolute
+type diskFS struct{}
+
+func (diskFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (diskFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// diskStaticFS is the file system of the static paths of StaticDir
+var diskStaticFS = staticFileSystem{FS: diskFS{}}
+
+// staticFS are the file systems set by SetStaticPath, by url
+var staticFS = map[string]fs.FS{}
+
+// staticFileSystemOf returns the file system of the static path prefix
+func staticFileSystemOf(prefix string) staticFileSystem {
+	if fsys, ok := staticFS[prefix]; ok {
+		return staticFileSystem{FS: fsys, name: "fs" + prefix}
+	}
+	return diskStaticFS
+}
+
+// join joins the path of a static file, the paths of an fs.FS are unrooted and slash-separated
+func (sfs staticFileSystem) join(elem ...string) string {
+	if sfs.name == "" {
+		return path.Join(elem...)
+	}
+	p := strings.TrimPrefix(path.Join(elem...), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// serveFSFile serves a file of an fs.FS which isn't cached
+func serveFSFile(ctx *context.Context, sfs staticFileSystem, filePath string) {
+	file, err := sfs.Open(filePath)
+	if err != nil {
+		http.NotFound(ctx.ResponseWriter, ctx.Request)
+		return
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		http.NotFound(ctx.ResponseWriter, ctx.Request)
+		return
+	}
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.NotFound(ctx.ResponseWriter, ctx.Request)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	http.ServeContent(ctx.ResponseWriter, ctx.Request, filePath, fi.ModTime(), content)
+}
+
+// serveFSDir lists a directory of an fs.FS
+func serveFSDir(ctx *context.Context, sfs staticFileSystem, dirPath string) {
+	r := ctx.Request.Clone(ctx.Request.Context())
+	r.URL.Path = strings.TrimSuffix(path.Join("/", dirPath), "/") + "/"
+	http.FileServer(http.FS(sfs.FS)).ServeHTTP(ctx.ResponseWriter, r)
+}
+
 type serveContentHolder struct {
 	data       []byte
 	modTime    time.Time
@@ -115,7 +213,7 @@ var (
 	lruLock            sync.RWMutex
 )
 
-func openFile(filePath string, fi os.FileInfo, acceptEncoding string) (bool, string, *serveContentHolder, *serveContentReader, error) {
+func openFile(sfs staticFileSystem, filePath string, fi os.FileInfo, acceptEncoding string) (bool, string, *serveContentHolder, *serveContentReader, error) {
 	if staticFileLruCache == nil {
 		// avoid lru cache error
 		if BConfig.WebConfig.StaticCacheFileNum >= 1 {
@@ -124,7 +222,7 @@ func openFile(filePath string, fi os.FileInfo, acceptEncoding string) (bool, str
 			staticFileLruCache, _ = lru.New(1)
 		}
 	}
-	mapKey := acceptEncoding + ":" + filePath
+	mapKey := acceptEncoding + ":" + sfs.name + filePath
 	lruLock.RLock()
 	var mapFile *serveContentHolder
 	if cacheItem, ok := staticFileLruCache.Get(mapKey); ok {
@@ -141,7 +239,7 @@ func openFile(filePath string, fi os.FileInfo, acceptEncoding string) (bool, str
 		mapFile = cacheItem.(*serveContentHolder)
 	}
 	if !isOk(mapFile, fi) {
-		file, err := os.Open(filePath)
+		file, err := sfs.Open(filePath)
 		if err != nil {
 			return false, "", nil, nil, err
 		}
@@ -172,6 +270,9 @@ func isOk(s *serveContentHolder, fi os.FileInfo) bool {
 
 // isStaticCompress detect static files
 func isStaticCompress(filePath string) bool {
+	if !context.Compressible(mime.TypeByExtension(filepath.Ext(filePath))) {
+		return false
+	}
 	for _, statExtension := range BConfig.WebConfig.StaticExtensionsToGzip {
 		if strings.HasSuffix(strings.ToLower(filePath), strings.ToLower(statExtension)) {
 			return true
@@ -180,23 +281,69 @@ func isStaticCompress(filePath string) bool {
 	return false
 }
 
+// precompressedExtensions are the extensions of the precompressed files by their encoding,
+// in the order of preference
+var precompressedExtensions = []struct {
+	encoding  string
+	extension string
+}{
+	{encoding: "br", extension: ".br"},
+	{encoding: "zstd", extension: ".zst"},
+	{encoding: "gzip", extension: ".gz"},
+}
+
+// servePrecompressed serves the file next to filePath compressed with the encoding preferred by the client,
+// eg. app.js.br or app.js.gz for app.js. It returns false if there's no such file.
+func servePrecompressed(ctx *context.Context, sfs staticFileSystem, filePath string, fileInfo os.FileInfo) bool {
+	files := make(map[string]string, len(precompressedExtensions))
+	encodings := make([]string, 0, len(precompressedExtensions))
+	for _, pe := range precompressedExtensions {
+		fp := filePath + pe.extension
+		// the precompressed file is ignored if it's older than the original one
+		if fi, err := fs.Stat(sfs, fp); err == nil && fi.Mode().IsRegular() && !fi.ModTime().Before(fileInfo.ModTime()) {
+			files[pe.encoding] = fp
+			encodings = append(encodings, pe.encoding)
+		}
+	}
+	if len(encodings) == 0 {
+		return false
+	}
+	// the response depends on Accept-Encoding even when the original file is served
+	ctx.ResponseWriter.Header().Add("Vary", "Accept-Encoding")
+	encoding := context.NegotiateEncoding(ctx.Request, encodings...)
+	if encoding == "" {
+		return false
+	}
+
+	// the content type is the one of the original file, not of the compressed data
+	contentType := mime.TypeByExtension(filepath.Ext(filePath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	ctx.Output.Header("Content-Type", contentType)
+	ctx.Output.Header("Content-Encoding", encoding)
+	serveFSFile(ctx, sfs, files[encoding])
+	return true
+}
+
 // searchFile search the file by url path
 // if none the static file prefix matches ,return notStaticRequestErr
-func searchFile(ctx *context.Context) (string, os.FileInfo, error) {
+func searchFile(ctx *context.Context) (staticFileSystem, string, os.FileInfo, error) {
 	requestPath := filepath.ToSlash(filepath.Clean(ctx.Request.URL.Path))
 	// special processing : favicon.ico/robots.txt  can be in any static dir
 	if requestPath == "/favicon.ico" || requestPath == "/robots.txt" {
 		file := path.Join(".", requestPath)
 		if fi, _ := os.Stat(file); fi != nil {
-			return file, fi, nil
+			return diskStaticFS, file, fi, nil
 		}
-		for _, staticDir := range BConfig.WebConfig.StaticDir {
-			filePath := path.Join(staticDir, requestPath)
-			if fi, _ := os.Stat(filePath); fi != nil {
-				return filePath, fi, nil
+		for prefix, staticDir := range BConfig.WebConfig.StaticDir {
+			sfs := staticFileSystemOf(prefix)
+			filePath := sfs.join(staticDir, requestPath)
+			if fi, _ := fs.Stat(sfs, filePath); fi != nil {
+				return sfs, filePath, fi, nil
 			}
 		}
-		return "", nil, errNotStaticRequest
+		return diskStaticFS, "", nil, errNotStaticRequest
 	}
 
 	for prefix, staticDir := range BConfig.WebConfig.StaticDir {
@@ -206,30 +353,122 @@ func searchFile(ctx *context.Context) (string, os.FileInfo, error) {
 		if prefix != "/" && len(requestPath) > len(prefix) && requestPath[len(prefix)] != '/' {
 			continue
 		}
-		filePath := path.Join(staticDir, requestPath[len(prefix):])
-		if fi, err := os.Stat(filePath); fi != nil {
-			return filePath, fi, err
+		sfs := staticFileSystemOf(prefix)
+		filePath := sfs.join(staticDir, requestPath[len(prefix):])
+		if fi, err := fs.Stat(sfs, filePath); fi != nil {
+			return sfs, filePath, fi, err
 		}
 	}
-	return "", nil, errNotStaticRequest
+	return diskStaticFS, "", nil, errNotStaticRequest
 }
 
 // lookupFile find the file to serve
 // if the file is dir ,search the index.html as default file( MUST NOT A DIR also)
 // if the index.html not exist or is a dir, give a forbidden response depending on  DirectoryIndex
-func lookupFile(ctx *context.Context) (bool, string, os.FileInfo, error) {
-	fp, fi, err := searchFile(ctx)
+func lookupFile(ctx *context.Context) (bool, staticFileSystem, string, os.FileInfo, error) {
+	sfs, fp, fi, err := searchFile(ctx)
 	if fp == "" || fi == nil {
-		return false, "", nil, err
+		return false, sfs, "", nil, err
 	}
 	if !fi.IsDir() {
-		return false, fp, fi, err
+		return false, sfs, fp, fi, err
 	}
 	if requestURL := ctx.Input.URL(); requestURL[len(requestURL)-1] == '/' {
 		ifp := filepath.Join(fp, "index.html")
-		if ifi, _ := os.Stat(ifp); ifi != nil && ifi.Mode().IsRegular() {
-			return false, ifp, ifi, err
+		if sfs.name != "" {
+			ifp = sfs.join(fp, "index.html")
+		}
+		if ifi, _ := fs.Stat(sfs, ifp); ifi != nil && ifi.Mode().IsRegular() {
+			return false, sfs, ifp, ifi, err
+		}
+	}
+	return !BConfig.WebConfig.DirectoryIndex, sfs, fp, fi, err
+}
+
+// immutableWriter sends the fingerprinted files with Cache-Control: immutable,
+// only when they are sent successfully, not with an error or a redirection
+type immutableWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *immutableWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if code == http.StatusOK || code == http.StatusPartialContent {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
 		}
 	}
-	return !BConfig.WebConfig.DirectoryIndex, fp, fi, err
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *immutableWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// fingerprintPattern matches the names containing a hex hash, eg. app.3f2a9c1b.js or app-3f2a9c1b.min.js
+var fingerprintPattern = regexp.MustCompile(`[.-]([0-9a-f]{8,})(\.\w+)+$`)
+
+// isFingerprinted reports whether the url of a static file changes with its content,
+// either its name contains a hash or it's a file of the asset manifest.
+// The hash must mix digits and letters, so that the dates or the numbers, eg. report-20240101.pdf, don't match
+func isFingerprinted(urlPath string) bool {
+	if m := fingerprintPattern.FindStringSubmatch(path.Base(urlPath)); m != nil &&
+		strings.ContainsAny(m[1], "0123456789") && strings.ContainsAny(m[1], "abcdef") {
+		return true
+	}
+	assetManifestLock.RLock()
+	defer assetManifestLock.RUnlock()
+	return fingerprintedAssets[path.Clean(urlPath)]
+}
+
+// AssetManifest maps the logical names of the static files to their fingerprinted names,
+// eg. "css/app.css" to "css/app.3f2a9c1b.css".
+// It's the manifest generated by the asset pipelines, eg. rev-manifest.json of gulp-rev
+// or manifest.json of webpack-assets-manifest
+type AssetManifest map[string]string
+
+var (
+	assetManifestLock sync.RWMutex
+	assetManifestURL  string
+	assetManifest     AssetManifest
+	// the urls of the files of the manifest
+	fingerprintedAssets map[string]bool
+)
+
+// SetAssetManifest sets the manifest of the static path url used by the asset template function.
+// The files of the manifest are sent with Cache-Control: immutable if StaticCacheImmutable is true
+func SetAssetManifest(url string, manifest AssetManifest) {
+	url = "/" + strings.Trim(url, "/")
+	fingerprinted := make(map[string]bool, len(manifest))
+	for _, name := range manifest {
+		fingerprinted[path.Join(url, name)] = true
+	}
+	assetManifestLock.Lock()
+	defer assetManifestLock.Unlock()
+	assetManifestURL, assetManifest, fingerprintedAssets = url, manifest, fingerprinted
+}
+
+// LoadAssetManifest reads the JSON manifest name of fsys, and sets it as the manifest of the static path url.
+// fsys is usually the embed.FS of the static files, or os.DirFS(".")
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	web.SetStaticPath("/static", "public", public)
+//	web.LoadAssetManifest("/static", public, "public/manifest.json")
+func LoadAssetManifest(url string, fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	manifest := AssetManifest{}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return err
+	}
+	SetAssetManifest(url, manifest)
+	return nil
 }
@@ -6,9 +6,18 @@ import (
 	"compress/zlib"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/server/web/context"
 )
 
 var (
@@ -18,7 +27,7 @@ var (
 
 func testOpenFile(encoding string, content []byte, t *testing.T) {
 	fi, _ := os.Stat(licenseFile)
-	b, n, sch, reader, err := openFile(licenseFile, fi, encoding)
+	b, n, sch, reader, err := openFile(diskStaticFS, licenseFile, fi, encoding)
 	if err != nil {
 		t.Log(err)
 		t.Fail()
@@ -62,13 +71,13 @@ func TestStaticCacheWork(t *testing.T) {
 
 	fi, _ := os.Stat(licenseFile)
 	for _, encoding := range encodings {
-		_, _, first, _, err := openFile(licenseFile, fi, encoding)
+		_, _, first, _, err := openFile(diskStaticFS, licenseFile, fi, encoding)
 		if err != nil {
 			t.Error(err)
 			continue
 		}
 
-		_, _, second, _, err := openFile(licenseFile, fi, encoding)
+		_, _, second, _, err := openFile(diskStaticFS, licenseFile, fi, encoding)
 		if err != nil {
 			t.Error(err)
 			continue
@@ -100,3 +109,142 @@ func assetOpenFileAndContent(sch *serveContentHolder, reader *serveContentReader
 		t.Fail()
 	}
 }
+
+func TestServePrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("plain"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js.br"), []byte("brotli"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js.gz"), []byte("gzip"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.css"), []byte("css"), 0o600))
+
+	oldDir, oldPrecompressed := BConfig.WebConfig.StaticDir, BConfig.WebConfig.StaticPrecompressed
+	BConfig.WebConfig.StaticDir = map[string]string{"/assets": dir}
+	BConfig.WebConfig.StaticPrecompressed = true
+	defer func() {
+		BConfig.WebConfig.StaticDir, BConfig.WebConfig.StaticPrecompressed = oldDir, oldPrecompressed
+	}()
+
+	cases := []struct {
+		url            string
+		acceptEncoding string
+		body           string
+		encoding       string
+		vary           bool
+	}{
+		{url: "/assets/app.js", acceptEncoding: "gzip, deflate, br, zstd", body: "brotli", encoding: "br", vary: true},
+		{url: "/assets/app.js", acceptEncoding: "gzip, br;q=0.5", body: "gzip", encoding: "gzip", vary: true},
+		{url: "/assets/app.js", acceptEncoding: "*", body: "brotli", encoding: "br", vary: true},
+		{url: "/assets/app.js", acceptEncoding: "deflate", body: "plain", vary: true},
+		{url: "/assets/app.js", body: "plain", vary: true},
+		{url: "/assets/main.css", acceptEncoding: "br", body: "css"},
+	}
+	for _, c := range cases {
+		t.Run(c.url+" "+c.acceptEncoding, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, c.url, nil)
+			r.Header.Set("Accept-Encoding", c.acceptEncoding)
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, r)
+			serverStaticRouter(ctx)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, c.body, w.Body.String())
+			assert.Equal(t, c.encoding, w.Header().Get("Content-Encoding"))
+			if c.encoding != "" {
+				// the type of the original file
+				assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+			}
+			assert.Equal(t, c.vary, w.Header().Get("Vary") == "Accept-Encoding")
+		})
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"public/app.js":              {Data: []byte("app")},
+		"public/app.3f2a9c1b.js":     {Data: []byte("hashed")},
+		"public/css/site.css":        {Data: []byte("site")},
+		"public/css/site.css.gz":     {Data: []byte("gzipped")},
+		"public/docs/index.html":     {Data: []byte("index")},
+		"public/large.txt":           {Data: bytes.Repeat([]byte("a"), 2048)},
+		"public/manifest.json":       {Data: []byte(`{"main.js": "app.3f2a9c1b.js"}`)},
+		"public/vendor/lib-Xa9k.js":  {Data: []byte("vendor")},
+		"public/vendor/manifest.txt": {Data: []byte("not fingerprinted")},
+	}
+	oldSize, oldPrecompressed := BConfig.WebConfig.StaticCacheFileSize, BConfig.WebConfig.StaticPrecompressed
+	BConfig.WebConfig.StaticCacheFileSize = 1024
+	BConfig.WebConfig.StaticPrecompressed = true
+	BConfig.WebConfig.StaticCacheImmutable = true
+	SetStaticPath("/embed", "public", fsys)
+	defer func() {
+		DelStaticPath("/embed")
+		BConfig.WebConfig.StaticCacheFileSize, BConfig.WebConfig.StaticPrecompressed = oldSize, oldPrecompressed
+		BConfig.WebConfig.StaticCacheImmutable = false
+		SetAssetManifest("", nil)
+	}()
+	require.NoError(t, LoadAssetManifest("/embed", fsys, "public/manifest.json"))
+	assert.Equal(t, "/embed/app.3f2a9c1b.js", AssetURL("main.js"))
+	assert.Equal(t, "/embed/css/site.css", AssetURL("/css/site.css"))
+
+	cases := []struct {
+		url       string
+		gzip      bool
+		rng       string
+		status    int
+		body      string
+		immutable bool
+	}{
+		{url: "/embed/app.js", status: http.StatusOK, body: "app"},
+		{url: "/embed/app.3f2a9c1b.js", status: http.StatusOK, body: "hashed", immutable: true},
+		{url: "/embed/app.3f2a9c1b.js", rng: "bytes=0-1", status: http.StatusPartialContent, body: "ha", immutable: true},
+		{url: "/embed/app.3f2a9c1b.js", rng: "bytes=100-", status: http.StatusRequestedRangeNotSatisfiable},
+		{url: "/embed/css/site.css", status: http.StatusOK, body: "site"},
+		{url: "/embed/css/site.css", gzip: true, status: http.StatusOK, body: "gzipped"},
+		{url: "/embed/docs/", status: http.StatusOK, body: "index"},
+		{url: "/embed/vendor/", status: http.StatusForbidden},
+		{url: "/embed/large.txt", status: http.StatusOK, body: string(bytes.Repeat([]byte("a"), 2048))},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, c.url, nil)
+			if c.gzip {
+				r.Header.Set("Accept-Encoding", "gzip")
+			}
+			if c.rng != "" {
+				r.Header.Set("Range", c.rng)
+			}
+			w := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(w, r)
+			serverStaticRouter(ctx)
+
+			assert.Equal(t, c.status, w.Code)
+			if c.body != "" {
+				assert.Equal(t, c.body, w.Body.String())
+			}
+			assert.Equal(t, c.immutable, strings.Contains(w.Header().Get("Cache-Control"), "immutable"))
+		})
+	}
+
+	BConfig.WebConfig.DirectoryIndex = true
+	defer func() {
+		BConfig.WebConfig.DirectoryIndex = false
+	}()
+	r, _ := http.NewRequest(http.MethodGet, "/embed/vendor/", nil)
+	w := httptest.NewRecorder()
+	ctx := context.NewContext()
+	ctx.Reset(w, r)
+	serverStaticRouter(ctx)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "lib-Xa9k.js")
+
+	// the files of the manifest are immutable even if their name doesn't look like a hash
+	SetAssetManifest("/embed", AssetManifest{"lib.js": "vendor/lib-Xa9k.js"})
+	assert.True(t, isFingerprinted("/embed/vendor/lib-Xa9k.js"))
+	assert.False(t, isFingerprinted("/embed/vendor/manifest.txt"))
+	assert.True(t, isFingerprinted("/other/jquery-0123456789abcdef.min.js"))
+	// the numbers and the words aren't hashes
+	assert.False(t, isFingerprinted("/other/report-20240101.pdf"))
+	assert.False(t, isFingerprinted("/other/invoice-12345678.pdf"))
+	assert.False(t, isFingerprinted("/other/backup.deadbeef.tar.gz"))
+}
@@ -17,10 +17,14 @@ package web
 import (
 	"fmt"
 	"html/template"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/asish-tom/beego/v2/core/utils"
+	"github.com/asish-tom/beego/v2/server/web/context"
 )
 
 // Statistics struct
@@ -31,6 +35,8 @@ type Statistics struct {
 	MinTime           time.Duration
 	MaxTime           time.Duration
 	TotalTime         time.Duration
+
+	latency *latencyHistogram
 }
 
 // URLMap contains several statistics struct to log different data
@@ -38,6 +44,9 @@ type URLMap struct {
 	lock        sync.RWMutex
 	LengthLimit int // limit the urlmap's length if it's equal to 0 there's no limit
 	urlmap      map[string]map[string]*Statistics
+
+	// routes holds the statistics of the routes by method and router pattern
+	routes map[string]*routeStatistics
 }
 
 // AddStatistics add statistics task.
@@ -55,6 +64,7 @@ func (m *URLMap) AddStatistics(requestMethod, requestURL, requestController stri
 				s.MinTime = requesttime
 			}
 			s.TotalTime += requesttime
+			s.latency.observe(requesttime, 0)
 		} else {
 			nb := &Statistics{
 				RequestURL:        requestURL,
@@ -63,7 +73,9 @@ func (m *URLMap) AddStatistics(requestMethod, requestURL, requestController stri
 				MinTime:           requesttime,
 				MaxTime:           requesttime,
 				TotalTime:         requesttime,
+				latency:           newLatencyHistogram(),
 			}
+			nb.latency.observe(requesttime, 0)
 			m.urlmap[requestURL][requestMethod] = nb
 		}
 	} else {
@@ -78,7 +90,9 @@ func (m *URLMap) AddStatistics(requestMethod, requestURL, requestController stri
 			MinTime:           requesttime,
 			MaxTime:           requesttime,
 			TotalTime:         requesttime,
+			latency:           newLatencyHistogram(),
 		}
+		nb.latency.observe(requesttime, 0)
 		methodmap[requestMethod] = nb
 		m.urlmap[requestURL] = methodmap
 	}
@@ -89,7 +103,7 @@ func (m *URLMap) GetMap() map[string]interface{} {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	fields := []string{"requestUrl", "method", "times", "used", "max used", "min used", "avg used"}
+	fields := []string{"requestUrl", "method", "times", "used", "max used", "min used", "avg used", "p50", "p90", "p99"}
 
 	var resultLists [][]string
 	content := make(map[string]interface{})
@@ -110,6 +124,10 @@ func (m *URLMap) GetMap() map[string]interface{} {
 				fmt.Sprintf("%d", time.Duration(int64(vv.TotalTime)/vv.RequestNum)),
 				fmt.Sprintf("% -16s", utils.ToShortTimeFormat(time.Duration(int64(vv.TotalTime)/vv.RequestNum))),
 			}
+			for _, q := range []float64{0.5, 0.9, 0.99} {
+				d := vv.latency.quantile(q)
+				result = append(result, fmt.Sprintf("%d", d), fmt.Sprintf("% -16s", utils.ToShortTimeFormat(d)))
+			}
 			resultLists = append(resultLists, result)
 		}
 	}
@@ -134,6 +152,9 @@ func (m *URLMap) GetMapData() []map[string]interface{} {
 				"max_time":    utils.ToShortTimeFormat(vv.MaxTime),
 				"min_time":    utils.ToShortTimeFormat(vv.MinTime),
 				"avg_time":    utils.ToShortTimeFormat(time.Duration(int64(vv.TotalTime) / vv.RequestNum)),
+				"p50_time":    utils.ToShortTimeFormat(vv.latency.quantile(0.5)),
+				"p90_time":    utils.ToShortTimeFormat(vv.latency.quantile(0.9)),
+				"p99_time":    utils.ToShortTimeFormat(vv.latency.quantile(0.99)),
 			}
 			resultLists = append(resultLists, result)
 		}
@@ -147,5 +168,270 @@ var StatisticsMap *URLMap
 func init() {
 	StatisticsMap = &URLMap{
 		urlmap: make(map[string]map[string]*Statistics),
+		routes: make(map[string]*routeStatistics),
+	}
+}
+
+// unknownRoutePattern is the pattern of the requests which don't match any router, eg. the static files
+const unknownRoutePattern = "UnknownRouterPattern"
+
+// unknownRouteMethod is the method of the requests whose method isn't an http method, they are rejected with 405
+const unknownRouteMethod = "OTHER"
+
+// RouteStatisticsLimit is the maximum number of routes in the route statistics,
+// the requests to the other routes are recorded as the requests of the unknown route. 0 means no limit
+var RouteStatisticsLimit = 1000
+
+// routeStatisticsKey is the key of the input data making the router record the route statistics
+const routeStatisticsKey = "RouteStatistics"
+
+// LatencyBuckets are the upper bounds of the buckets of the latency histograms,
+// the quantiles are estimated from them. Change it before the server starts
+var LatencyBuckets = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second,
+}
+
+// StatisticsWindow is the period covered by the rolling window of the route statistics
+var StatisticsWindow = time.Minute
+
+// statisticsSlots is the number of slots of the rolling window, it moves one slot at a time
+const statisticsSlots = 6
+
+// latencyHistogram counts the requests by latency and by status class
+type latencyHistogram struct {
+	// buckets[i] counts the requests slower than LatencyBuckets[i-1] and not slower than LatencyBuckets[i],
+	// the last one counts the requests slower than all the bounds
+	buckets  []int64
+	count    int64
+	sum      time.Duration
+	min      time.Duration
+	max      time.Duration
+	statuses [5]int64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{buckets: make([]int64, len(LatencyBuckets)+1)}
+}
+
+// observe records a request, status is ignored when it's not a valid status code
+func (h *latencyHistogram) observe(d time.Duration, status int) {
+	h.buckets[sort.Search(len(LatencyBuckets), func(i int) bool { return d <= LatencyBuckets[i] })]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+	if class := status / 100; class >= 1 && class <= 5 {
+		h.statuses[class-1]++
+	}
+}
+
+func (h *latencyHistogram) merge(o *latencyHistogram) {
+	if o.count == 0 {
+		return
+	}
+	for i, n := range o.buckets {
+		h.buckets[i] += n
+	}
+	if h.count == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+	h.count += o.count
+	h.sum += o.sum
+	for i, n := range o.statuses {
+		h.statuses[i] += n
+	}
+}
+
+// quantile estimates the q-quantile by interpolating in the bucket it falls in, like Prometheus does
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := q * float64(h.count)
+	var cumulative int64
+	for i, n := range h.buckets {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		lower, upper := h.min, h.max
+		if i > 0 && LatencyBuckets[i-1] > lower {
+			lower = LatencyBuckets[i-1]
+		}
+		if i < len(LatencyBuckets) && LatencyBuckets[i] < upper {
+			upper = LatencyBuckets[i]
+		}
+		return lower + time.Duration(float64(upper-lower)*(rank-float64(cumulative))/float64(n))
+	}
+	return h.max
+}
+
+func (h *latencyHistogram) stats() LatencyStats {
+	res := LatencyStats{
+		Count:   h.count,
+		Sum:     h.sum,
+		Min:     h.min,
+		Max:     h.max,
+		P50:     h.quantile(0.5),
+		P90:     h.quantile(0.9),
+		P99:     h.quantile(0.99),
+		Status:  make(map[string]int64, len(h.statuses)),
+		Buckets: append([]int64(nil), h.buckets...),
+	}
+	for i, n := range h.statuses {
+		res.Status[strconv.Itoa(i+1)+"xx"] = n
 	}
+	return res
+}
+
+// routeStatistics holds the statistics of a route since the server started and in the rolling window
+type routeStatistics struct {
+	method   string
+	pattern  string
+	inFlight atomic.Int64
+	// exported is true when a request was recorded because of RecordRouteStatistics
+	exported atomic.Bool
+
+	mu    sync.Mutex
+	total *latencyHistogram
+	slots [statisticsSlots]*latencyHistogram
+	// periods are the indexes of the periods of the slots since the Unix epoch
+	periods [statisticsSlots]int64
+}
+
+func (s *routeStatistics) observe(now time.Time, d time.Duration, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total.observe(d, status)
+
+	period := now.UnixNano() / int64(StatisticsWindow/statisticsSlots)
+	i := period % statisticsSlots
+	if s.slots[i] == nil || s.periods[i] != period {
+		s.slots[i], s.periods[i] = newLatencyHistogram(), period
+	}
+	s.slots[i].observe(d, status)
+}
+
+func (s *routeStatistics) stats(now time.Time) RouteStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	period := now.UnixNano() / int64(StatisticsWindow/statisticsSlots)
+	window := newLatencyHistogram()
+	for i, slot := range s.slots {
+		if slot != nil && s.periods[i] > period-statisticsSlots && s.periods[i] <= period {
+			window.merge(slot)
+		}
+	}
+	return RouteStats{
+		Method:   s.method,
+		Pattern:  s.pattern,
+		InFlight: s.inFlight.Load(),
+		Exported: s.exported.Load(),
+		Total:    s.total.stats(),
+		Window:   window.stats(),
+	}
+}
+
+// RouteStats is a snapshot of the statistics of the requests to a route
+type RouteStats struct {
+	Method   string `json:"method"`
+	Pattern  string `json:"pattern"`
+	InFlight int64  `json:"in_flight"`
+	// Exported means some requests to the route were recorded because of RecordRouteStatistics, eg. by the prometheus filter
+	Exported bool `json:"exported"`
+	// Total covers the requests since the server started
+	Total LatencyStats `json:"total"`
+	// Window covers the requests of the last StatisticsWindow
+	Window LatencyStats `json:"window"`
+}
+
+// LatencyStats summarizes the latency histogram of some requests, the durations are in nanoseconds
+type LatencyStats struct {
+	Count int64         `json:"count"`
+	Sum   time.Duration `json:"sum"`
+	Min   time.Duration `json:"min"`
+	Max   time.Duration `json:"max"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P99   time.Duration `json:"p99"`
+	// Status counts the requests by status class, eg. "2xx"
+	Status map[string]int64 `json:"status"`
+	// Buckets counts the requests by latency, Buckets[i] counts the requests slower than LatencyBuckets[i-1]
+	// and not slower than LatencyBuckets[i], the last one counts the requests slower than all the bounds
+	Buckets []int64 `json:"buckets"`
+}
+
+// RecordRouteStatistics makes the router record the statistics of the route of the request
+// when the admin service is disabled, and marks the route as exported, see RouteStats.Exported.
+// It's called before the router by the filter chains exporting the statistics, eg. the prometheus filter
+func RecordRouteStatistics(ctx *context.Context) {
+	ctx.Input.SetData(routeStatisticsKey, true)
+}
+
+// route returns the statistics of the route, creating them if needed
+func (m *URLMap) route(method, pattern string) *routeStatistics {
+	key := method + " " + pattern
+	m.lock.RLock()
+	s, ok := m.routes[key]
+	m.lock.RUnlock()
+	if ok {
+		return s
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if s, ok = m.routes[key]; ok {
+		return s
+	}
+	if m.routes == nil {
+		m.routes = make(map[string]*routeStatistics)
+	}
+	if RouteStatisticsLimit > 0 && len(m.routes) >= RouteStatisticsLimit {
+		method, pattern = unknownRouteMethod, unknownRoutePattern
+		key = method + " " + pattern
+		if s, ok = m.routes[key]; ok {
+			return s
+		}
+	}
+	s = &routeStatistics{method: method, pattern: pattern, total: newLatencyHistogram()}
+	m.routes[key] = s
+	return s
+}
+
+// AddRouteStatistics records a request to the route of pattern, status is the status code of the response
+func (m *URLMap) AddRouteStatistics(method, pattern string, status int, d time.Duration) {
+	m.route(method, pattern).observe(time.Now(), d, status)
+}
+
+// RouteStatistics returns the statistics of the routes sorted by pattern and method
+func (m *URLMap) RouteStatistics() []RouteStats {
+	m.lock.RLock()
+	routes := make([]*routeStatistics, 0, len(m.routes))
+	for _, s := range m.routes {
+		routes = append(routes, s)
+	}
+	m.lock.RUnlock()
+
+	now := time.Now()
+	res := make([]RouteStats, 0, len(routes))
+	for _, s := range routes {
+		res = append(res, s.stats(now))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Pattern != res[j].Pattern {
+			return res[i].Pattern < res[j].Pattern
+		}
+		return res[i].Method < res[j].Method
+	})
+	return res
 }
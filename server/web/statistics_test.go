@@ -16,8 +16,15 @@ package web
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/server/web/context"
 )
 
 func TestStatics(t *testing.T) {
@@ -38,3 +45,180 @@ func TestStatics(t *testing.T) {
 
 	t.Log(string(b))
 }
+
+func TestLatencyHistogram(t *testing.T) {
+	h := newLatencyHistogram()
+	assert.Equal(t, time.Duration(0), h.quantile(0.5))
+
+	// 90 fast requests and 10 slow ones
+	for i := 0; i < 90; i++ {
+		h.observe(3*time.Millisecond, http.StatusOK)
+	}
+	for i := 0; i < 10; i++ {
+		h.observe(800*time.Millisecond, http.StatusInternalServerError)
+	}
+	assert.Equal(t, int64(100), h.count)
+	// the estimations are in the bucket of the quantile and within min and max
+	for q, bounds := range map[float64][2]time.Duration{
+		0.5:  {3 * time.Millisecond, 5 * time.Millisecond},
+		0.9:  {3 * time.Millisecond, 5 * time.Millisecond},
+		0.99: {500 * time.Millisecond, 800 * time.Millisecond},
+	} {
+		assert.GreaterOrEqual(t, h.quantile(q), bounds[0])
+		assert.LessOrEqual(t, h.quantile(q), bounds[1])
+	}
+	assert.Less(t, h.quantile(0.5), h.quantile(0.9))
+
+	h.observe(time.Minute, 0)
+	assert.Equal(t, time.Minute, h.quantile(1))
+
+	stats := h.stats()
+	assert.Equal(t, int64(90), stats.Status["2xx"])
+	assert.Equal(t, int64(10), stats.Status["5xx"])
+	assert.Equal(t, int64(0), stats.Status["4xx"])
+	assert.Equal(t, int64(1), stats.Buckets[len(LatencyBuckets)])
+	assert.Equal(t, 3*time.Millisecond, stats.Min)
+	assert.Equal(t, time.Minute, stats.Max)
+
+	// interpolation in a bucket
+	h = newLatencyHistogram()
+	h.observe(10*time.Millisecond, http.StatusOK)
+	h.observe(20*time.Millisecond, http.StatusOK)
+	assert.Equal(t, 15*time.Millisecond, h.quantile(0.75))
+}
+
+func TestRouteStatisticsWindow(t *testing.T) {
+	s := &routeStatistics{method: http.MethodGet, pattern: "/window", total: newLatencyHistogram()}
+	start := time.Unix(0, 0).Add(StatisticsWindow)
+	s.observe(start, time.Millisecond, http.StatusOK)
+	s.observe(start.Add(StatisticsWindow/2), 2*time.Millisecond, http.StatusNotFound)
+
+	stats := s.stats(start.Add(StatisticsWindow / 2))
+	assert.Equal(t, int64(2), stats.Window.Count)
+	assert.Equal(t, int64(2), stats.Total.Count)
+
+	// the first request is out of the window
+	stats = s.stats(start.Add(StatisticsWindow))
+	assert.Equal(t, int64(1), stats.Window.Count)
+	assert.Equal(t, int64(1), stats.Window.Status["4xx"])
+	assert.Equal(t, int64(2), stats.Total.Count)
+
+	// the slot of the first request is reused
+	s.observe(start.Add(StatisticsWindow), 3*time.Millisecond, http.StatusOK)
+	stats = s.stats(start.Add(StatisticsWindow))
+	assert.Equal(t, int64(2), stats.Window.Count)
+	assert.Equal(t, 3*time.Millisecond, stats.Window.Max)
+
+	stats = s.stats(start.Add(3 * StatisticsWindow))
+	assert.Equal(t, int64(0), stats.Window.Count)
+	assert.Equal(t, int64(3), stats.Total.Count)
+}
+
+func recordRouteStatisticsChain(next FilterFunc) FilterFunc {
+	return func(ctx *context.Context) {
+		RecordRouteStatistics(ctx)
+		next(ctx)
+	}
+}
+
+func TestRouterRouteStatistics(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := NewControllerRegister()
+	handler.InsertFilterChain("/stats/*", recordRouteStatisticsChain)
+	handler.Init()
+	handler.Get("/stats/slow/:id", func(ctx *context.Context) {
+		close(started)
+		<-release
+		ctx.Output.SetStatus(http.StatusAccepted)
+		_ = ctx.Output.Body([]byte("slow"))
+	})
+	handler.Get("/stats/missing", func(ctx *context.Context) {
+		ctx.Abort(http.StatusNotFound, "missing")
+	})
+
+	route := func(pattern string) RouteStats {
+		for _, s := range StatisticsMap.RouteStatistics() {
+			if s.Pattern == pattern && s.Method == http.MethodGet {
+				return s
+			}
+		}
+		return RouteStats{}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r, _ := http.NewRequest(http.MethodGet, "/stats/slow/1", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		close(done)
+	}()
+	<-started
+	assert.Equal(t, int64(1), route("/stats/slow/:id").InFlight)
+	close(release)
+	<-done
+
+	slow := route("/stats/slow/:id")
+	assert.Equal(t, int64(0), slow.InFlight)
+	assert.Equal(t, int64(1), slow.Total.Count)
+	assert.Equal(t, int64(1), slow.Window.Status["2xx"])
+
+	r, _ := http.NewRequest(http.MethodGet, "/stats/missing", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, int64(1), route("/stats/missing").Window.Status["4xx"])
+
+	// the JSON export of the admin service
+	w := httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/statistics", nil)
+	ctx := context.NewContext()
+	ctx.Reset(w, r)
+	c := &adminController{}
+	c.Init(ctx, "adminController", "Statistics", nil)
+	c.Statistics()
+	var export struct {
+		Window time.Duration `json:"window"`
+		Routes []RouteStats  `json:"routes"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &export))
+	assert.Equal(t, StatisticsWindow, export.Window)
+	assert.NotEmpty(t, export.Routes)
+}
+
+func TestRouterRouteStatisticsMethod(t *testing.T) {
+	handler := NewControllerRegister()
+	handler.InsertFilterChain("*", recordRouteStatisticsChain)
+	handler.Init()
+	for _, method := range []string{"FOO", "BAR"} {
+		r, _ := http.NewRequest(method, "/stats/method", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	}
+
+	var methods []string
+	for _, s := range StatisticsMap.RouteStatistics() {
+		if s.Pattern == unknownRoutePattern {
+			methods = append(methods, s.Method)
+		}
+	}
+	assert.Contains(t, methods, unknownRouteMethod)
+	assert.NotContains(t, methods, "FOO")
+	assert.NotContains(t, methods, "BAR")
+}
+
+func TestRouteStatisticsLimit(t *testing.T) {
+	m := &URLMap{urlmap: make(map[string]map[string]*Statistics)}
+	old := RouteStatisticsLimit
+	RouteStatisticsLimit = 2
+	defer func() {
+		RouteStatisticsLimit = old
+	}()
+
+	m.AddRouteStatistics(http.MethodGet, "/a", http.StatusOK, time.Millisecond)
+	m.AddRouteStatistics(http.MethodGet, "/b", http.StatusOK, time.Millisecond)
+	m.AddRouteStatistics(http.MethodGet, "/c", http.StatusOK, time.Millisecond)
+	m.AddRouteStatistics(http.MethodPost, "/d", http.StatusOK, time.Millisecond)
+	stats := m.RouteStatistics()
+	require.Len(t, stats, 3)
+	assert.Equal(t, unknownRoutePattern, stats[2].Pattern)
+	assert.Equal(t, unknownRouteMethod, stats[2].Method)
+	assert.Equal(t, int64(2), stats[2].Total.Count)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -91,6 +92,7 @@ func init() {
 	beegoTplFuncMap["renderform"] = RenderForm
 	beegoTplFuncMap["assets_js"] = AssetsJs
 	beegoTplFuncMap["assets_css"] = AssetsCSS
+	beegoTplFuncMap["asset"] = AssetURL
 	beegoTplFuncMap["config"] = GetConfig
 	beegoTplFuncMap["map_get"] = MapGet
 
@@ -371,7 +373,13 @@ func SetViewsPath(path string) *HttpServer {
 
 // SetStaticPath sets static directory path and proper url pattern in beego application.
 // if beego.SetStaticPath("static","public"), visit /static/* to load static file in folder "public".
-func SetStaticPath(url string, path string) *HttpServer {
+// If fsys is given, the folder is a directory of fsys instead of the disk, eg. an embed.FS:
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	web.SetStaticPath("/static", "public", public)
+func SetStaticPath(url string, path string, fsys ...fs.FS) *HttpServer {
 	if !strings.HasPrefix(url, "/") {
 		url = "/" + url
 	}
@@ -379,6 +387,11 @@ func SetStaticPath(url string, path string) *HttpServer {
 		url = strings.TrimRight(url, "/")
 	}
 	BConfig.WebConfig.StaticDir[url] = path
+	if len(fsys) > 0 && fsys[0] != nil {
+		staticFS[url] = fsys[0]
+	} else {
+		delete(staticFS, url)
+	}
 	return BeeApp
 }
 
@@ -391,6 +404,7 @@ func DelStaticPath(url string) *HttpServer {
 		url = strings.TrimRight(url, "/")
 	}
 	delete(BConfig.WebConfig.StaticDir, url)
+	delete(staticFS, url)
 	return BeeApp
 }
 
@@ -20,6 +20,7 @@ import (
 	"html"
 	"html/template"
 	"net/url"
+	"path"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -254,6 +255,18 @@ func AssetsJs(text string) template.HTML {
 	return template.HTML(text)
 }
 
+// AssetURL returns the url of the static file name, fingerprinted if it's in the asset manifest,
+// eg. {{asset "css/app.css"}} returns /static/css/app.3f2a9c1b.css. See SetAssetManifest
+func AssetURL(name string) string {
+	assetManifestLock.RLock()
+	defer assetManifestLock.RUnlock()
+	name = strings.TrimPrefix(name, "/")
+	if hashed, ok := assetManifest[name]; ok {
+		name = hashed
+	}
+	return path.Join(assetManifestURL, name)
+}
+
 // AssetsCSS returns stylesheet link tag with src string.
 func AssetsCSS(text string) template.HTML {
 	text = "<link href=\"" + text + "\" rel=\"stylesheet\" />"
@@ -49,6 +49,30 @@ func NewTree() *Tree {
 // prefix should has no params
 func (t *Tree) AddTree(prefix string, tree *Tree) {
 	t.addtree(splitPath(prefix), tree, nil, "")
+	tree.addConstraints(parseRouteConstraints(prefix))
+}
+
+// addConstraints attaches the constraints of a prefix to every leaf of the tree
+func (t *Tree) addConstraints(constraints map[string]*RouteConstraint) {
+	if len(constraints) == 0 {
+		return
+	}
+	for _, v := range t.fixrouters {
+		v.addConstraints(constraints)
+	}
+	if t.wildcard != nil {
+		t.wildcard.addConstraints(constraints)
+	}
+	for _, l := range t.leaves {
+		merged := make(map[string]*RouteConstraint, len(constraints)+len(l.constraints))
+		for k, c := range constraints {
+			merged[k] = c
+		}
+		for k, c := range l.constraints {
+			merged[k] = c
+		}
+		l.constraints = merged
+	}
 }
 
 func (t *Tree) addtree(segments []string, tree *Tree, wildcards []string, reg string) {
@@ -199,24 +223,36 @@ func filterTreeWithPrefix(t *Tree, wildcards []string, reg string) {
 
 // AddRouter call addseg function
 func (t *Tree) AddRouter(pattern string, runObject interface{}) {
-	t.addseg(splitPath(pattern), runObject, nil, "")
+	t.addseg(splitPath(pattern), runObject, nil, "", parseRouteConstraints(pattern))
+}
+
+// addLeaf puts the newest leaf first, but keeps constrained leaves ahead of
+// unconstrained ones so that /:id<uuid> is tried before /:name
+func (t *Tree) addLeaf(leaf *leafInfo) {
+	i := 0
+	if len(leaf.constraints) == 0 {
+		for i < len(t.leaves) && len(t.leaves[i].constraints) > 0 {
+			i++
+		}
+	}
+	t.leaves = append(t.leaves[:i], append([]*leafInfo{leaf}, t.leaves[i:]...)...)
 }
 
 // "/"
 // "admin" ->
-func (t *Tree) addseg(segments []string, route interface{}, wildcards []string, reg string) {
+func (t *Tree) addseg(segments []string, route interface{}, wildcards []string, reg string, constraints map[string]*RouteConstraint) {
 	if len(segments) == 0 {
+		leaf := &leafInfo{runObject: route, wildcards: wildcards, constraints: constraints}
 		if reg != "" {
-			t.leaves = append([]*leafInfo{{runObject: route, wildcards: wildcards, regexps: regexp.MustCompile("^" + reg + "$")}}, t.leaves...)
-		} else {
-			t.leaves = append([]*leafInfo{{runObject: route, wildcards: wildcards}}, t.leaves...)
+			leaf.regexps = regexp.MustCompile("^" + reg + "$")
 		}
+		t.addLeaf(leaf)
 	} else {
 		seg := segments[0]
 		iswild, params, regexpStr := splitSegment(seg)
 		// if it's ? meaning can igone this, so add one more rule for it
 		if len(params) > 0 && params[0] == ":" {
-			t.addseg(segments[1:], route, wildcards, reg)
+			t.addseg(segments[1:], route, wildcards, reg, constraints)
 			params = params[1:]
 		}
 		// Rule: /login/*/access match /login/2009/11/access
@@ -261,7 +297,7 @@ func (t *Tree) addseg(segments []string, route interface{}, wildcards []string,
 					params = params[1:]
 				}
 			}
-			t.wildcard.addseg(segments[1:], route, append(wildcards, params...), reg+regexpStr)
+			t.wildcard.addseg(segments[1:], route, append(wildcards, params...), reg+regexpStr, constraints)
 		} else {
 			var subTree *Tree
 			for _, sub := range t.fixrouters {
@@ -275,7 +311,7 @@ func (t *Tree) addseg(segments []string, route interface{}, wildcards []string,
 				subTree.prefix = seg
 				t.fixrouters = append(t.fixrouters, subTree)
 			}
-			subTree.addseg(segments[1:], route, wildcards, reg)
+			subTree.addseg(segments[1:], route, wildcards, reg, constraints)
 		}
 	}
 }
@@ -391,9 +427,18 @@ type leafInfo struct {
 	// if the leaf is regexp
 	regexps *regexp.Regexp
 
+	// constraints of the typed wildcards, eg. {":id": uuid} for ":id<uuid>"
+	constraints map[string]*RouteConstraint
+
 	runObject interface{}
 }
 
+// satisfies reports whether value is acceptable for the wildcard
+func (leaf *leafInfo) satisfies(wildcard, value string) bool {
+	c, ok := leaf.constraints[wildcard]
+	return !ok || c.Accept(value)
+}
+
 func (leaf *leafInfo) match(treePattern string, wildcardValues []string, ctx *context.Context) (ok bool) {
 	// fmt.Println("Leaf:", wildcardValues, leaf.wildcards, leaf.regexps)
 	if leaf.regexps == nil {
@@ -419,6 +464,11 @@ func (leaf *leafInfo) match(treePattern string, wildcardValues []string, ctx *co
 				return false
 			}
 			var index int
+			for index = 0; index < len(leaf.wildcards)-2; index++ {
+				if !leaf.satisfies(leaf.wildcards[index], wildcardValues[index]) {
+					return false
+				}
+			}
 			for index = 0; index < len(leaf.wildcards)-2; index++ {
 				ctx.Input.SetParam(leaf.wildcards[index], wildcardValues[index])
 			}
@@ -438,6 +488,11 @@ func (leaf *leafInfo) match(treePattern string, wildcardValues []string, ctx *co
 		if len(leaf.wildcards) != len(wildcardValues) {
 			return false
 		}
+		for j, v := range leaf.wildcards {
+			if !leaf.satisfies(v, wildcardValues[j]) {
+				return false
+			}
+		}
 		for j, v := range leaf.wildcards {
 			ctx.Input.SetParam(v, wildcardValues[j])
 		}
@@ -448,6 +503,11 @@ func (leaf *leafInfo) match(treePattern string, wildcardValues []string, ctx *co
 		return false
 	}
 	matches := leaf.regexps.FindStringSubmatch(path.Join(wildcardValues...))
+	for i, match := range matches[1:] {
+		if i < len(leaf.wildcards) && !leaf.satisfies(leaf.wildcards[i], match) {
+			return false
+		}
+	}
 	for i, match := range matches[1:] {
 		if i < len(leaf.wildcards) {
 			ctx.Input.SetParam(leaf.wildcards[i], match)
@@ -473,6 +533,7 @@ func splitPath(key string) []string {
 // "?:id" -> true, [: :id], ""        : meaning can empty
 // ":id:int" -> true, [:id], ([0-9]+)
 // ":name:string" -> true, [:name], ([\w]+)
+// ":id<uuid>" -> true, [:id], ([0-9a-fA-F]{8}-...)   see AddRouteConstraint
 // ":id([0-9]+)" -> true, [:id], ([0-9]+)
 // ":id([0-9]+)_:name" -> true, [:id :name], ([0-9]+)_(.+)
 // "cms_:id_:page.html" -> true, [:id_ :page], cms_(.+)(.+).html
@@ -529,6 +590,20 @@ func splitSegment(key string) (bool, []string, string) {
 						}
 					}
 				}
+				// :id<uuid>
+				if v == '<' {
+					if end := strings.IndexByte(key[i:], '>'); end > 0 {
+						c := getRouteConstraint(key[i+1 : i+end])
+						out = append(out, []rune("("+c.Regexp+")")...)
+						params = append(params, ":"+string(param))
+						paramsNum++
+						start = false
+						startexp = false
+						skipnum = end
+						param = make([]rune, 0)
+						continue
+					}
+				}
 				// params only support a-zA-Z0-9
 				if reg.MatchString(string(v)) {
 					param = append(param, v)
@@ -15,6 +15,7 @@
 package web
 
 import (
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -108,6 +109,11 @@ func init() {
 		matchTestInfo("/?:year/:month/mid/:day/?:hour", "/11/mid/10/24", map[string]string{":month": "11", ":day": "10"}),
 		matchTestInfo("/?:year/:month/mid/:day/?:hour", "/2020/11/mid/10", map[string]string{":year": "2020", ":month": "11", ":day": "10"}),
 		matchTestInfo("/?:year/:month/mid/:day/?:hour", "/11/mid/10", map[string]string{":month": "11", ":day": "10"}),
+		matchTestInfo("/users/:id<uuid>", "/users/5f0c7c8e-4a3b-4c1d-9e2f-1a2b3c4d5e6f", map[string]string{":id": "5f0c7c8e-4a3b-4c1d-9e2f-1a2b3c4d5e6f"}),
+		matchTestInfo("/users/:id<uuid>/posts/:slug<slug>", "/users/5f0c7c8e-4a3b-4c1d-9e2f-1a2b3c4d5e6f/posts/hello-world", map[string]string{":id": "5f0c7c8e-4a3b-4c1d-9e2f-1a2b3c4d5e6f", ":slug": "hello-world"}),
+		matchTestInfo("/report/:day<date>", "/report/2020-02-29", map[string]string{":day": "2020-02-29"}),
+		matchTestInfo("/topic/?:page<int>", "/topic", nil),
+		matchTestInfo("/topic/?:page<int>", "/topic/12", map[string]string{":page": "12"}),
 
 		// not match example
 		// https://github.com/asish-tom/beego/v2/issues/3865
@@ -134,6 +140,11 @@ func init() {
 		notMatchTestInfo("/book2/:type:string/fixPath1/:name", "/book2/type1/fixPath1/name1/../../././////evilType/evilName"),
 		notMatchTestInfo("/book2/:type:string/fixPath1/:name", "/book2/type1/fixPath1/name1/../../././////evilType/evilName"),
 		notMatchTestInfo("/book2/:type:string/fixPath1/:name", "/book2/type1/fixPath1/name1/../../././////evilType/evilName"),
+		// typed params
+		notMatchTestInfo("/users/:id<uuid>", "/users/astaxie"),
+		notMatchTestInfo("/users/:id<uuid>/posts/:slug<slug>", "/users/5f0c7c8e-4a3b-4c1d-9e2f-1a2b3c4d5e6f/posts/Hello_World"),
+		notMatchTestInfo("/report/:day<date>", "/report/2021-02-29"),
+		notMatchTestInfo("/topic/?:page<int>", "/topic/abc"),
 	}
 }
 
@@ -318,6 +329,58 @@ func TestAddTree5(t *testing.T) {
 	}
 }
 
+func TestConstraintPriority(t *testing.T) {
+	AddRouteConstraintFunc("even", func(value string) bool {
+		n, err := strconv.Atoi(value)
+		return err == nil && n%2 == 0
+	})
+	tr := NewTree()
+	tr.AddRouter("/users/:id<uuid>", "uuid")
+	tr.AddRouter("/users/:id<even>", "even")
+	tr.AddRouter("/users/:name", "name")
+
+	tests := map[string]string{
+		"/users/5f0c7c8e-4a3b-4c1d-9e2f-1a2b3c4d5e6f": "uuid",
+		"/users/42":      "even",
+		"/users/43":      "name",
+		"/users/astaxie": "name",
+	}
+	for url, expect := range tests {
+		ctx := context.NewContext()
+		obj := tr.Match(url, ctx)
+		if obj == nil || obj.(string) != expect {
+			t.Fatalf("%s should match %s, got %v", url, expect, obj)
+		}
+	}
+
+	ctx := context.NewContext()
+	tr.Match("/users/43", ctx)
+	if ctx.Input.Param(":id") != "" || ctx.Input.Param(":name") != "43" {
+		t.Fatal("failed constraints should not set params")
+	}
+}
+
+func TestConstraintAddTree(t *testing.T) {
+	tr := NewTree()
+	tr.AddRouter("/orders/:oid<int>", "order")
+	t1 := NewTree()
+	t1.AddTree("/v1/:tenant<slug>", tr)
+	ctx := context.NewContext()
+	obj := t1.Match("/v1/acme-corp/orders/12", ctx)
+	if obj == nil || obj.(string) != "order" {
+		t.Fatal("/v1/:tenant<slug>/orders/:oid<int> can't get obj")
+	}
+	if ctx.Input.Param(":tenant") != "acme-corp" || ctx.Input.Param(":oid") != "12" {
+		t.Fatal("get :tenant :oid param error")
+	}
+	if obj = t1.Match("/v1/acme-corp/orders/abc", context.NewContext()); obj != nil {
+		t.Fatal("/v1/acme-corp/orders/abc should not match")
+	}
+	if obj = t1.Match("/v1/Acme_Corp/orders/12", context.NewContext()); obj != nil {
+		t.Fatal("/v1/Acme_Corp/orders/12 should not match")
+	}
+}
+
 func TestSplitPath(t *testing.T) {
 	a := splitPath("")
 	if len(a) != 0 {
@@ -365,6 +428,9 @@ func TestSplitSegment(t *testing.T) {
 		"cms_:id(.+)_:page(.+).html": {true, []string{":id", ":page"}, `cms_(.+)_(.+).html`},
 		`:app(a|b|c)`:                {true, []string{":app"}, `(a|b|c)`},
 		`:app\((a|b|c)\)`:            {true, []string{":app"}, `(.+)\((a|b|c)\)`},
+		":id<int>":                   {true, []string{":id"}, `([0-9]+)`},
+		"?:id<int>":                  {true, []string{":", ":id"}, `([0-9]+)`},
+		"item_:id<int>.html":         {true, []string{":id"}, `item_([0-9]+).html`},
 	}
 
 	for pattern, v := range items {
@@ -0,0 +1,69 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"net/http"
+
+	"github.com/asish-tom/beego/v2/core/logs"
+	"github.com/asish-tom/beego/v2/server/web/context"
+	"github.com/asish-tom/beego/v2/server/web/ws"
+)
+
+// WebSocketHandler serves an upgraded websocket connection.
+// The connection is closed when the handler returns.
+type WebSocketHandler func(ctx *context.Context, conn *ws.Conn)
+
+// WebSocket registers a websocket endpoint for GET requests.
+// Filters, policies and the session run before the upgrade like for any other route,
+// so they can reject the request or fill ctx.Input before the handler is called.
+// usage:
+//
+//	WebSocket("/chat/:room", func(ctx *context.Context, conn *ws.Conn){
+//	      room := ctx.Input.Param(":room")
+//	      ...
+//	})
+func (p *ControllerRegister) WebSocket(pattern string, handler WebSocketHandler, opts ...ws.Option) {
+	upgrader := ws.NewUpgrader(opts...)
+	p.Get(pattern, func(ctx *context.Context) {
+		conn, err := upgrader.Upgrade(ctx.ResponseWriter, ctx.Request, ctx.ResponseWriter.Header())
+		if err != nil {
+			logs.Debug("websocket upgrade of %s failed: %v", ctx.Request.URL.Path, err)
+			return
+		}
+		// the response is hijacked, record the status for the access log and statistics
+		ctx.ResponseWriter.Started = true
+		ctx.ResponseWriter.Status = http.StatusSwitchingProtocols
+		defer conn.Close()
+		handler(ctx, conn)
+	})
+}
+
+// WebSocket see HttpServer.WebSocket
+func WebSocket(rootpath string, handler WebSocketHandler, opts ...ws.Option) *HttpServer {
+	return BeeApp.WebSocket(rootpath, handler, opts...)
+}
+
+// WebSocket used to register a websocket endpoint
+// usage:
+//
+//	beego.WebSocket("/echo", func(ctx *context.Context, conn *ws.Conn){
+//	      mt, msg, _ := conn.ReadMessage()
+//	      conn.WriteMessage(mt, msg)
+//	})
+func (app *HttpServer) WebSocket(rootpath string, handler WebSocketHandler, opts ...ws.Option) *HttpServer {
+	app.Handlers.WebSocket(rootpath, handler, opts...)
+	return app
+}
@@ -0,0 +1,97 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	context2 "context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/asish-tom/beego/v2/server/web/context"
+	"github.com/asish-tom/beego/v2/server/web/session"
+	"github.com/asish-tom/beego/v2/server/web/ws"
+)
+
+func TestControllerRegisterWebSocket(t *testing.T) {
+	cfg := newBConfig()
+	cfg.WebConfig.Session.SessionOn = true
+	oldSessions := GlobalSessions
+	defer func() {
+		GlobalSessions = oldSessions
+	}()
+	var err error
+	GlobalSessions, err = session.NewManager("memory", &session.ManagerConfig{
+		CookieName:      "beegosessionID",
+		EnableSetCookie: true,
+		Gclifetime:      3600,
+	})
+	require.NoError(t, err)
+
+	handler := NewControllerRegisterWithCfg(cfg)
+	handler.InsertFilter("/chat/*", BeforeRouter, func(ctx *context.Context) {
+		if ctx.Input.Query("token") != "secret" {
+			ctx.Output.SetStatus(http.StatusUnauthorized)
+			_ = ctx.Output.Body([]byte("unauthorized"))
+			return
+		}
+		_ = ctx.Input.CruSession.Set(context2.Background(), "user", "astaxie")
+	})
+	handler.InsertFilter("/chat/*", BeforeExec, func(ctx *context.Context) {
+		ctx.Input.SetData("greeting", "hello")
+	})
+	handler.WebSocket("/chat/:room", func(ctx *context.Context, conn *ws.Conn) {
+		user := ctx.Input.CruSession.Get(context2.Background(), "user")
+		greeting := ctx.Input.GetData("greeting")
+		_ = conn.WriteText(greeting.(string) + " " + user.(string) + " in " + ctx.Input.Param(":room"))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = conn.WriteText("echo " + string(msg))
+	})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	conn, resp, err := ws.Dial(url+"/chat/golang?token=secret", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Contains(t, resp.Header.Get("Set-Cookie"), "beegosessionID=")
+
+	_, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "hello astaxie in golang", string(msg))
+	require.NoError(t, conn.WriteText("ping"))
+	_, msg, err = conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "echo ping", string(msg))
+
+	// filters can reject the request before the upgrade
+	_, resp, err = ws.Dial(url+"/chat/golang", nil)
+	assert.Equal(t, ws.ErrBadHandshake, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// plain http requests are refused by the handshake
+	r, _ := http.NewRequest(http.MethodGet, "/chat/golang?token=secret", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,422 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ws is a minimal implementation of the WebSocket protocol (RFC 6455).
+// It supports text, binary and control frames, fragmentation and the closing
+// handshake. Extensions such as permessage-deflate are not supported.
+//
+// Usage:
+//
+//	web.BeeApp.Handlers.WebSocket("/echo", func(ctx *context.Context, conn *ws.Conn) {
+//		for {
+//			mt, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			if err = conn.WriteMessage(mt, msg); err != nil {
+//				return
+//			}
+//		}
+//	})
+package ws
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, the values are the opcodes defined in RFC 6455
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes defined in RFC 6455, section 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	finBit  = 1 << 7
+	rsvBits = 7 << 4
+	maskBit = 1 << 7
+
+	maxControlPayload = 125
+	// DefaultReadLimit is the maximum size of a message read from the peer
+	DefaultReadLimit = 32 << 20
+	closeTimeout     = time.Second
+)
+
+var (
+	// ErrCloseSent is returned when writing after the close frame was sent
+	ErrCloseSent = errors.New("ws: close sent")
+	// ErrReadLimit is returned when a message exceeds the read limit
+	ErrReadLimit = errors.New("ws: read limit exceeded")
+	// ErrBadHandshake is returned when the opening handshake is invalid
+	ErrBadHandshake = errors.New("ws: bad handshake")
+
+	errProtocol    = errors.New("ws: protocol error")
+	errInvalidUTF8 = errors.New("ws: invalid utf8 in text message")
+)
+
+// CloseError is returned by ReadMessage when the peer closes the connection
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("ws: close %d %s", e.Code, e.Text)
+}
+
+// Conn is a WebSocket connection.
+// One goroutine may read and another one may write concurrently.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	subprotocol string
+	readLimit   int64
+
+	wmu       sync.Mutex
+	closeSent bool
+
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{
+		conn:      conn,
+		br:        br,
+		isServer:  isServer,
+		readLimit: DefaultReadLimit,
+	}
+	c.pingHandler = func(data []byte) error {
+		return c.WriteControl(PongMessage, data)
+	}
+	c.pongHandler = func([]byte) error { return nil }
+	return c
+}
+
+// Subprotocol returns the negotiated subprotocol
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// NetConn returns the underlying connection
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// RemoteAddr returns the remote network address
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadLimit sets the maximum size of a message read from the peer
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetReadDeadline sets the read deadline on the underlying connection
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline on the underlying connection
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPingHandler sets the handler of ping frames, the default one replies with a pong
+func (c *Conn) SetPingHandler(h func(data []byte) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler sets the handler of pong frames
+func (c *Conn) SetPongHandler(h func(data []byte) error) {
+	c.pongHandler = h
+}
+
+// ReadMessage reads the next data message, fragmented messages are reassembled.
+// Control frames are handled while reading. When the peer closes the
+// connection a *CloseError is returned.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+		switch opcode {
+		case PingMessage:
+			if err = c.pingHandler(payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err = c.pongHandler(payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(errProtocol)
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(errProtocol)
+			}
+			messageType = opcode
+		default:
+			return 0, nil, c.fail(errProtocol)
+		}
+		if int64(len(p)+len(payload)) > c.readLimit {
+			return 0, nil, c.fail(ErrReadLimit)
+		}
+		p = append(p, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(p) {
+				return 0, nil, c.fail(errInvalidUTF8)
+			}
+			if p == nil {
+				p = []byte{}
+			}
+			return messageType, p, nil
+		}
+	}
+}
+
+// fail sends the close frame matching err to the peer
+func (c *Conn) fail(err error) error {
+	code := 0
+	switch err {
+	case errProtocol:
+		code = CloseProtocolError
+	case ErrReadLimit:
+		code = CloseMessageTooBig
+	case errInvalidUTF8:
+		code = CloseInvalidFramePayloadData
+	}
+	if code != 0 {
+		_ = c.WriteClose(code, "")
+	}
+	return err
+}
+
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(errProtocol)
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !utf8.ValidString(closeErr.Text) {
+			return c.fail(errInvalidUTF8)
+		}
+	}
+	// echo the status code back, as required by the closing handshake
+	code := closeErr.Code
+	if code == CloseNoStatusReceived {
+		code = CloseNormalClosure
+	}
+	_ = c.WriteClose(code, "")
+	return closeErr
+}
+
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&finBit != 0
+	opcode = int(header[0] & 0x0f)
+	masked := header[1]&maskBit != 0
+	length := int64(header[1] & 0x7f)
+
+	if header[0]&rsvBits != 0 || masked != c.isServer {
+		return false, 0, nil, errProtocol
+	}
+	if opcode >= CloseMessage && (!fin || length > maxControlPayload) {
+		return false, 0, nil, errProtocol
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+		if length < 0 {
+			return false, 0, nil, errProtocol
+		}
+	}
+	if length > c.readLimit {
+		return false, 0, nil, ErrReadLimit
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, key[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		maskBytes(key, payload)
+	}
+	return
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// WriteMessage writes a single unfragmented message
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return c.WriteControl(messageType, data)
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// WriteText writes a text message
+func (c *Conn) WriteText(s string) error {
+	return c.WriteMessage(TextMessage, []byte(s))
+}
+
+// WriteControl writes a ping, pong or close frame
+func (c *Conn) WriteControl(messageType int, data []byte) error {
+	if messageType < CloseMessage || messageType > PongMessage {
+		return fmt.Errorf("ws: invalid control message type %d", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("ws: control frame payload too large")
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// Ping sends a ping frame
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteControl(PingMessage, data)
+}
+
+// WriteClose sends a close frame, no data can be written afterwards
+func (c *Conn) WriteClose(code int, text string) error {
+	var payload []byte
+	if code != CloseNoStatusReceived {
+		payload = make([]byte, 2, 2+len(text))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, text...)
+	}
+	return c.WriteControl(CloseMessage, payload)
+}
+
+// Close sends a normal closure frame if none was sent yet and closes the underlying connection
+func (c *Conn) Close() error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+	_ = c.WriteClose(CloseNormalClosure, "")
+	return c.conn.Close()
+}
+
+func (c *Conn) writeFrame(opcode int, data []byte) error {
+	header := make([]byte, 0, 14)
+	header = append(header, finBit|byte(opcode))
+
+	var maskFlag byte
+	if !c.isServer {
+		maskFlag = maskBit
+	}
+	length := len(data)
+	switch {
+	case length <= maxControlPayload:
+		header = append(header, maskFlag|byte(length))
+	case length <= 0xffff:
+		header = append(header, maskFlag|126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, maskFlag|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if !c.isServer {
+		// clients must mask every frame, RFC 6455 section 5.3
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		header = append(header, key[:]...)
+		masked := make([]byte, length)
+		copy(masked, data)
+		maskBytes(key, masked)
+		data = masked
+	}
+
+	if _, err := c.conn.Write(append(header, data...)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ws
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// magic value used to compute Sec-WebSocket-Accept, RFC 6455 section 1.3
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Upgrader upgrades HTTP connections to WebSocket connections
+type Upgrader struct {
+	// Subprotocols are the protocols supported by the server in order of preference
+	Subprotocols []string
+	// CheckOrigin returns true if the Origin of the request is acceptable.
+	// If it's nil, the host of the Origin header must equal the Host of the request.
+	CheckOrigin func(r *http.Request) bool
+	// ReadLimit is the maximum size of a message, DefaultReadLimit is used if it's zero
+	ReadLimit int64
+}
+
+// Option configures the Upgrader
+type Option func(u *Upgrader)
+
+// WithSubprotocols sets the supported subprotocols
+func WithSubprotocols(protocols ...string) Option {
+	return func(u *Upgrader) {
+		u.Subprotocols = protocols
+	}
+}
+
+// WithCheckOrigin sets the origin check of the handshake
+func WithCheckOrigin(check func(r *http.Request) bool) Option {
+	return func(u *Upgrader) {
+		u.CheckOrigin = check
+	}
+}
+
+// WithReadLimit sets the maximum size of a message
+func WithReadLimit(limit int64) Option {
+	return func(u *Upgrader) {
+		u.ReadLimit = limit
+	}
+}
+
+// NewUpgrader creates an Upgrader
+func NewUpgrader(opts ...Option) *Upgrader {
+	u := &Upgrader{}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// IsWebSocketUpgrade reports whether r asks for a websocket upgrade
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade performs the opening handshake and hijacks the connection.
+// responseHeader is sent with the 101 response, eg. to set cookies.
+// If the handshake fails, an error response is written to w.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return nil, ErrBadHandshake
+	}
+	if !IsWebSocketUpgrade(r) {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-Websocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "websocket origin not allowed", http.StatusForbidden)
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+	netConn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// drop the deadlines set by the http server
+	_ = netConn.SetDeadline(time.Time{})
+
+	c := newConn(netConn, brw.Reader, true)
+	c.subprotocol = u.selectSubprotocol(r)
+	if u.ReadLimit > 0 {
+		c.readLimit = u.ReadLimit
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	buf.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if c.subprotocol != "" {
+		buf.WriteString("Sec-WebSocket-Protocol: " + c.subprotocol + "\r\n")
+	}
+	if len(responseHeader) > 0 {
+		h := responseHeader.Clone()
+		for _, k := range []string{"Upgrade", "Connection", "Sec-Websocket-Accept",
+			"Sec-Websocket-Protocol", "Content-Length", "Content-Type"} {
+			h.Del(k)
+		}
+		_ = h.Write(&buf)
+	}
+	buf.WriteString("\r\n")
+	if _, err = netConn.Write(buf.Bytes()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	for _, server := range u.Subprotocols {
+		for _, client := range headerTokens(r.Header, "Sec-Websocket-Protocol") {
+			if client == server {
+				return client
+			}
+		}
+	}
+	return ""
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, t := range headerTokens(header, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ws
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEchoServer(u *Upgrader) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err = conn.WriteMessage(mt, msg); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func wsURL(s *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func TestAcceptKey(t *testing.T) {
+	// example from RFC 6455 section 1.3
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestEcho(t *testing.T) {
+	server := newEchoServer(NewUpgrader())
+	defer server.Close()
+
+	conn, resp, err := Dial(wsURL(server), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	require.NoError(t, conn.WriteText("hello"))
+	mt, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, TextMessage, mt)
+	assert.Equal(t, "hello", string(msg))
+
+	// extended 16 and 64 bit payload lengths
+	for _, size := range []int{200, 70000} {
+		data := bytes.Repeat([]byte{0xfe}, size)
+		require.NoError(t, conn.WriteMessage(BinaryMessage, data))
+		mt, msg, err = conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, BinaryMessage, mt)
+		assert.Equal(t, data, msg)
+	}
+}
+
+func TestPingAndFragments(t *testing.T) {
+	server := newEchoServer(NewUpgrader())
+	defer server.Close()
+
+	conn, _, err := Dial(wsURL(server), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data []byte) error {
+		pong <- string(data)
+		return nil
+	})
+
+	conn.wmu.Lock()
+	// "hel" + ping + "lo", control frames may be interleaved with fragments
+	require.NoError(t, writeRawFrame(conn, TextMessage, false, []byte("hel")))
+	require.NoError(t, writeRawFrame(conn, PingMessage, true, []byte("p")))
+	require.NoError(t, writeRawFrame(conn, continuationFrame, true, []byte("lo")))
+	conn.wmu.Unlock()
+
+	mt, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, TextMessage, mt)
+	assert.Equal(t, "hello", string(msg))
+	assert.Equal(t, "p", <-pong)
+}
+
+func TestCloseHandshake(t *testing.T) {
+	closed := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := NewUpgrader().Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _, err = conn.ReadMessage()
+		closed <- err
+	}))
+	defer server.Close()
+
+	conn, _, err := Dial(wsURL(server), nil)
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteClose(CloseGoingAway, "bye"))
+	assert.Equal(t, ErrCloseSent, conn.WriteText("late"))
+
+	err = <-closed
+	closeErr, ok := err.(*CloseError)
+	require.True(t, ok)
+	assert.Equal(t, CloseGoingAway, closeErr.Code)
+	assert.Equal(t, "bye", closeErr.Text)
+
+	// the server echoes the close frame
+	_, _, err = conn.ReadMessage()
+	closeErr, ok = err.(*CloseError)
+	require.True(t, ok)
+	assert.Equal(t, CloseGoingAway, closeErr.Code)
+	conn.NetConn().Close()
+}
+
+func TestProtocolErrors(t *testing.T) {
+	server := newEchoServer(NewUpgrader(WithReadLimit(16)))
+	defer server.Close()
+
+	cases := map[string]struct {
+		opcode  int
+		fin     bool
+		payload []byte
+		code    int
+	}{
+		"too big":          {BinaryMessage, true, bytes.Repeat([]byte{1}, 17), CloseMessageTooBig},
+		"invalid utf8":     {TextMessage, true, []byte{0xff, 0xfe}, CloseInvalidFramePayloadData},
+		"bad continuation": {continuationFrame, true, []byte("x"), CloseProtocolError},
+		"fragmented ping":  {PingMessage, false, nil, CloseProtocolError},
+		"unknown opcode":   {3, true, nil, CloseProtocolError},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, _, err := Dial(wsURL(server), nil)
+			require.NoError(t, err)
+			defer conn.NetConn().Close()
+			require.NoError(t, writeRawFrame(conn, c.opcode, c.fin, c.payload))
+			_, _, err = conn.ReadMessage()
+			closeErr, ok := err.(*CloseError)
+			require.True(t, ok, "%v", err)
+			assert.Equal(t, c.code, closeErr.Code)
+		})
+	}
+}
+
+func TestBadHandshake(t *testing.T) {
+	server := newEchoServer(NewUpgrader())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, resp, err = Dial(wsURL(server), http.Header{"Origin": {"http://evil.example.com"}})
+	assert.Equal(t, ErrBadHandshake, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestSubprotocol(t *testing.T) {
+	server := newEchoServer(NewUpgrader(WithSubprotocols("v2.chat", "v1.chat")))
+	defer server.Close()
+
+	conn, _, err := Dial(wsURL(server), http.Header{"Sec-Websocket-Protocol": {"v1.chat, v2.chat"}})
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "v2.chat", conn.Subprotocol())
+}
+
+// writeRawFrame writes a single masked frame with the given fin bit
+func writeRawFrame(c *Conn, opcode int, fin bool, payload []byte) error {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= finBit
+	}
+	frame := []byte{b0, maskBit | byte(len(payload)), 0, 0, 0, 0}
+	_, err := c.conn.Write(append(frame, payload...))
+	return err
+}
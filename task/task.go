@@ -37,7 +37,11 @@ type taskManager struct {
 	stop          chan bool
 	changed       chan bool
 	started       bool
-	wait          sync.WaitGroup
+
+	// the number of running tasks, idle is closed when there is none
+	runningLock sync.Mutex
+	running     int
+	idle        chan struct{}
 }
 
 func newTaskManager() *taskManager {
@@ -47,9 +51,16 @@ func newTaskManager() *taskManager {
 		stop:          make(chan bool),
 		changed:       make(chan bool),
 		started:       false,
+		idle:          closedChan(),
 	}
 }
 
+func closedChan() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
+
 // The bounds for each field.
 var (
 	globalTaskManager *taskManager
@@ -485,6 +496,12 @@ func GracefulShutdown() <-chan struct{} {
 	return globalTaskManager.GracefulShutdown()
 }
 
+// Shutdown stops the tasks and waits for the running ones until ctx is done,
+// web.Shutdown calls it before running the shutdown hooks
+func Shutdown(ctx context.Context) error {
+	return globalTaskManager.Shutdown(ctx)
+}
+
 // StartTask start all tasks
 func (m *taskManager) StartTask() {
 	m.taskLock.Lock()
@@ -565,10 +582,10 @@ func (m *taskManager) runNextTasks(sortList *MapSorter, effective time.Time) {
 
 		// check if timeout is on, if yes passing the timeout context
 		ctx := context.Background()
-		m.wait.Add(1)
+		m.taskStarted()
 		if duration := e.GetTimeout(ctx); duration != 0 {
 			go func(e Tasker) {
-				defer m.wait.Done()
+				defer m.taskDone()
 				ctx, cancelFunc := context.WithTimeout(ctx, duration)
 				defer cancelFunc()
 				err := e.Run(ctx)
@@ -578,7 +595,7 @@ func (m *taskManager) runNextTasks(sortList *MapSorter, effective time.Time) {
 			}(e)
 		} else {
 			go func(e Tasker) {
-				defer m.wait.Done()
+				defer m.taskDone()
 				err := e.Run(ctx)
 				if err != nil {
 					log.Printf("tasker.run err: %s\n", err.Error())
@@ -598,17 +615,64 @@ func (m *taskManager) StopTask() {
 	}()
 }
 
+// taskStarted counts a task starting to run
+func (m *taskManager) taskStarted() {
+	m.runningLock.Lock()
+	defer m.runningLock.Unlock()
+	if m.running == 0 {
+		m.idle = make(chan struct{})
+	}
+	m.running++
+}
+
+// taskDone counts a task which is done
+func (m *taskManager) taskDone() {
+	m.runningLock.Lock()
+	defer m.runningLock.Unlock()
+	m.running--
+	if m.running == 0 {
+		close(m.idle)
+	}
+}
+
+// idleChan returns a channel closed when no task is running
+func (m *taskManager) idleChan() <-chan struct{} {
+	m.runningLock.Lock()
+	defer m.runningLock.Unlock()
+	return m.idle
+}
+
 // GracefulShutdown wait all task done
 func (m *taskManager) GracefulShutdown() <-chan struct{} {
 	done := make(chan struct{})
 	go func() {
-		m.stop <- true
-		m.wait.Wait()
+		_ = m.Shutdown(context.Background())
 		close(done)
 	}()
 	return done
 }
 
+// Shutdown stops the manager and waits for the running tasks, it returns the error of ctx when ctx is done first
+func (m *taskManager) Shutdown(ctx context.Context) error {
+	m.taskLock.RLock()
+	started := m.started
+	m.taskLock.RUnlock()
+	// nobody receives the stop signal when the manager isn't running
+	if started {
+		select {
+		case m.stop <- true:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case <-m.idleChan():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // AddTask add task with name
 func (m *taskManager) AddTask(taskname string, t Tasker) {
 	isChanged := false
@@ -198,6 +198,45 @@ func TestGracefulShutdown(t *testing.T) {
 	assert.True(t, waitDone.Load().(bool))
 }
 
+func TestShutdown(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, Shutdown(ctx))
+}
+
+func TestShutdownCanceled(t *testing.T) {
+	m := newTaskManager()
+	defer m.ClearTask()
+	release := make(chan struct{})
+	running := make(chan struct{}, 1)
+	m.AddTask("blocked", NewTask("blocked", "* * * * * *", func(ctx context.Context) error {
+		select {
+		case running <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	}))
+	m.StartTask()
+	<-running
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Shutdown(ctx), context.DeadlineExceeded)
+
+	close(release)
+	assert.NoError(t, m.Shutdown(context.Background()))
+}
+
+func TestGracefulShutdownNotStarted(t *testing.T) {
+	m := newTaskManager()
+	select {
+	case <-m.GracefulShutdown():
+	case <-time.After(time.Second):
+		t.Fatal("GracefulShutdown blocks when the manager isn't started")
+	}
+}
+
 func wait(wg *sync.WaitGroup) chan bool {
 	ch := make(chan bool)
 	go func() {